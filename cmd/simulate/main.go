@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/simulation"
+)
+
+var releaseFile = flag.String("release", "", "path to the JSON release to simulate")
+var scriptFile = flag.String("script", "", "path to the JSON script of monitor check results, when not set all checks succeed")
+
+func main() {
+	flag.Parse()
+
+	if *releaseFile == "" {
+		fmt.Println("please specify a release with the -release flag")
+		os.Exit(1)
+	}
+
+	r := &models.Release{}
+	err := readJSON(*releaseFile, r)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	s := &simulation.Script{}
+	if *scriptFile != "" {
+		err = readJSON(*scriptFile, s)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	res, err := simulation.Run(r, s, hclog.NewNullLogger())
+	if err != nil {
+		fmt.Println("Unable to simulate release:", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(res.Timeline)
+	fmt.Println()
+	fmt.Println("Final state:", res.FinalState)
+	fmt.Println("Duration:   ", res.Duration)
+
+	if res.Deployment != nil {
+		fmt.Println("Outcome:    ", res.Deployment.Outcome)
+
+		if res.Deployment.FailureReason != "" {
+			fmt.Println("Reason:     ", res.Deployment.FailureReason)
+		}
+	}
+}
+
+func readJSON(path string, v interface{}) error {
+	d, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read file %s: %s", path, err)
+	}
+
+	err = json.Unmarshal(d, v)
+	if err != nil {
+		return fmt.Errorf("unable to parse file %s: %s", path, err)
+	}
+
+	return nil
+}
@@ -13,17 +13,21 @@ import (
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 )
 
+// eventsKeepAlive is the interval that keep-alive comments are sent to event stream clients
+var eventsKeepAlive = 15 * time.Second
+
 // ReleaseHandler handles the CRUD operations for releases
 type ReleaseHandler struct {
 	logger          hclog.Logger
 	store           interfaces.Store
 	metrics         interfaces.Metrics
+	events          interfaces.Events
 	pluginProviders interfaces.Provider
 }
 
 // NewReleaseHandler creates a new ReleaseHandler
 func NewReleaseHandler(p interfaces.Provider) *ReleaseHandler {
-	return &ReleaseHandler{logger: p.GetLogger().Named("release_handler"), metrics: p.GetMetrics(), store: p.GetDataStore(), pluginProviders: p}
+	return &ReleaseHandler{logger: p.GetLogger().Named("release_handler"), metrics: p.GetMetrics(), store: p.GetDataStore(), events: p.GetEvents(), pluginProviders: p}
 }
 
 // Post handler for creating and updating releases
@@ -166,6 +170,142 @@ func (rh *ReleaseHandler) GetSingle(rw http.ResponseWriter, req *http.Request) {
 	mFinal(http.StatusOK)
 }
 
+// GetDeployments handler returns the deployment history for the release related to the "name"
+// HTTP querystring parameter, deployments are ordered from the oldest to the newest
+func (rh *ReleaseHandler) GetDeployments(rw http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "name")
+
+	rh.logger.Info("Release GET deployments handler called", "name", name)
+	mFinal := rh.metrics.HandleRequest("release_handler", map[string]string{"method": "get_deployments"})
+
+	rel, err := rh.store.GetRelease(name)
+
+	if err == interfaces.ReleaseNotFound {
+		rh.logger.Error("unable to find release, not found", "name", name)
+		mFinal(http.StatusNotFound)
+
+		http.Error(rw, fmt.Sprintf("release %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		rh.logger.Error("unable to get release", "error", err)
+		mFinal(http.StatusInternalServerError)
+
+		http.Error(rw, "unable to fetch deployments", http.StatusInternalServerError)
+		return
+	}
+
+	deployments, err := rh.store.ListDeployments(name)
+	if err != nil {
+		rh.logger.Error("unable to list deployments", "name", name, "error", err)
+		mFinal(http.StatusInternalServerError)
+
+		http.Error(rw, "unable to fetch deployments", http.StatusInternalServerError)
+		return
+	}
+
+	if deployments == nil {
+		deployments = []*models.Deployment{}
+	}
+
+	// include the deployment that is currently in progress
+	if rel.Deployment != nil {
+		deployments = append(deployments, rel.Deployment)
+	}
+
+	json.NewEncoder(rw).Encode(deployments)
+	mFinal(http.StatusOK)
+}
+
+// GetEvents handler streams the events for the release related to the "name" HTTP querystring
+// parameter as server-sent events until the client disconnects
+func (rh *ReleaseHandler) GetEvents(rw http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "name")
+
+	rh.logger.Info("Release GET events handler called", "name", name)
+	mFinal := rh.metrics.HandleRequest("release_handler", map[string]string{"method": "get_events"})
+
+	_, err := rh.store.GetRelease(name)
+
+	if err == interfaces.ReleaseNotFound {
+		rh.logger.Error("unable to find release, not found", "name", name)
+		mFinal(http.StatusNotFound)
+
+		http.Error(rw, fmt.Sprintf("release %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		rh.logger.Error("unable to get release", "error", err)
+		mFinal(http.StatusInternalServerError)
+
+		http.Error(rw, "unable to fetch events", http.StatusInternalServerError)
+		return
+	}
+
+	mFinal(rh.streamEvents(rw, req, name))
+}
+
+// GetAllEvents handler streams the events for all releases as server-sent events until the
+// client disconnects
+func (rh *ReleaseHandler) GetAllEvents(rw http.ResponseWriter, req *http.Request) {
+	rh.logger.Info("Release GET all events handler called")
+	mFinal := rh.metrics.HandleRequest("release_handler", map[string]string{"method": "get_all_events"})
+
+	mFinal(rh.streamEvents(rw, req, ""))
+}
+
+// streamEvents writes the events for the named release, or all releases when name is empty, to the
+// response until the client disconnects, returns the HTTP status code for the response
+func (rh *ReleaseHandler) streamEvents(rw http.ResponseWriter, req *http.Request, name string) int {
+	f, ok := rw.(http.Flusher)
+	if !ok {
+		rh.logger.Error("unable to stream events, response does not support flushing")
+
+		http.Error(rw, "streaming not supported", http.StatusInternalServerError)
+		return http.StatusInternalServerError
+	}
+
+	events, cancel := rh.events.Subscribe(name)
+	defer cancel()
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	// send a comment periodically so that proxies do not close idle connections
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return http.StatusOK
+
+		case <-keepAlive.C:
+			fmt.Fprint(rw, ": keep-alive\n\n")
+			f.Flush()
+
+		case e, ok := <-events:
+			if !ok {
+				return http.StatusOK
+			}
+
+			d, err := json.Marshal(e)
+			if err != nil {
+				rh.logger.Error("unable to serialize event", "error", err)
+				continue
+			}
+
+			fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", e.Type, d)
+			f.Flush()
+		}
+	}
+}
+
 // Delete handler deletes a deployment
 func (rh *ReleaseHandler) Delete(rw http.ResponseWriter, req *http.Request) {
 	name := chi.URLParam(req, "name")
@@ -249,3 +389,123 @@ func (rh *ReleaseHandler) Delete(rw http.ResponseWriter, req *http.Request) {
 
 	mFinal(http.StatusOK)
 }
+
+// Promote handler promotes a release that is awaiting manual promotion
+func (rh *ReleaseHandler) Promote(rw http.ResponseWriter, req *http.Request) {
+	rh.transition(rw, req, "promote", []string{interfaces.StateAwaitPromotion}, func(sm interfaces.StateMachine) error {
+		return sm.Promote()
+	})
+}
+
+// Pause handler freezes the traffic for an in-flight release, a release can only be paused while
+// it is monitored as pausing a scale would cancel a traffic change that has not been confirmed
+func (rh *ReleaseHandler) Pause(rw http.ResponseWriter, req *http.Request) {
+	rh.transition(rw, req, "pause", []string{interfaces.StateMonitor}, func(sm interfaces.StateMachine) error {
+		return sm.Pause()
+	})
+}
+
+// Resume handler continues a paused release
+func (rh *ReleaseHandler) Resume(rw http.ResponseWriter, req *http.Request) {
+	rh.transition(rw, req, "resume", []string{interfaces.StatePaused}, func(sm interfaces.StateMachine) error {
+		return sm.Resume()
+	})
+}
+
+// Abort handler rolls back an in-flight release
+func (rh *ReleaseHandler) Abort(rw http.ResponseWriter, req *http.Request) {
+	validStates := []string{
+		interfaces.StatePending,
+		interfaces.StateMonitor,
+		interfaces.StateScale,
+		interfaces.StatePaused,
+		interfaces.StateAwaitPromotion,
+	}
+
+	rh.transition(rw, req, "abort", validStates, func(sm interfaces.StateMachine) error {
+		return sm.Abort()
+	})
+}
+
+// DecideGate handler records the decision from an external approval service for a release
+// that is waiting for the gate
+func (rh *ReleaseHandler) DecideGate(rw http.ResponseWriter, req *http.Request) {
+	gate := chi.URLParam(req, "gate")
+
+	decision := interfaces.GateResponse{}
+
+	err := json.NewDecoder(req.Body).Decode(&decision)
+	if err != nil || (decision.Result != interfaces.GateResultApproved && decision.Result != interfaces.GateResultRejected) {
+		rh.logger.Error("Invalid gate decision", "gate", gate, "error", err)
+		rh.metrics.HandleRequest("release_handler", map[string]string{"method": "decide gate for"})(http.StatusBadRequest)
+
+		http.Error(rw, fmt.Sprintf("invalid decision for gate %s, the result must be %s or %s", gate, interfaces.GateResultApproved, interfaces.GateResultRejected), http.StatusBadRequest)
+		return
+	}
+
+	rh.transition(rw, req, "decide gate for", []string{interfaces.StateMonitor}, func(sm interfaces.StateMachine) error {
+		return sm.DecideGate(gate, decision)
+	})
+}
+
+// transition calls the given function for the statemachine of the named release, the function
+// is only called when the release is in one of the valid states
+func (rh *ReleaseHandler) transition(rw http.ResponseWriter, req *http.Request, action string, validStates []string, f func(sm interfaces.StateMachine) error) {
+	name := chi.URLParam(req, "name")
+
+	rh.logger.Info("Release "+action+" handler called", "name", name)
+	mFinal := rh.metrics.HandleRequest("release_handler", map[string]string{"method": action})
+
+	rel, err := rh.store.GetRelease(name)
+
+	if err == interfaces.ReleaseNotFound {
+		rh.logger.Error("unable to find release, not found", "name", name)
+		mFinal(http.StatusNotFound)
+
+		http.Error(rw, fmt.Sprintf("release %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		rh.logger.Error("unable to get release", "error", err)
+		mFinal(http.StatusInternalServerError)
+
+		http.Error(rw, fmt.Sprintf("unable to %s release", action), http.StatusInternalServerError)
+		return
+	}
+
+	sm, err := rh.pluginProviders.GetStateMachine(rel)
+	if err != nil {
+		rh.logger.Error("Unable to get state machine for", "release", rel.Name)
+		mFinal(http.StatusInternalServerError)
+
+		http.Error(rw, "unable to find state machine for release", http.StatusInternalServerError)
+		return
+	}
+
+	valid := false
+	for _, s := range validStates {
+		if sm.CurrentState() == s {
+			valid = true
+		}
+	}
+
+	if !valid {
+		rh.logger.Error("unable to "+action+" release, invalid state", "name", name, "state", sm.CurrentState())
+		mFinal(http.StatusConflict)
+
+		http.Error(rw, fmt.Sprintf("unable to %s release %s in the current state: %s", action, name, sm.CurrentState()), http.StatusConflict)
+		return
+	}
+
+	err = f(sm)
+	if err != nil {
+		rh.logger.Error("unable to "+action+" release", "name", name, "error", err)
+		mFinal(http.StatusInternalServerError)
+
+		http.Error(rw, fmt.Sprintf("unable to %s release", action), http.StatusInternalServerError)
+		return
+	}
+
+	mFinal(http.StatusOK)
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
@@ -31,7 +34,15 @@ func setupRelease(t *testing.T) (http.Handler, *httptest.ResponseRecorder, *mock
 	rtr.Post("/v1/releases", apiHandler.Post)
 	rtr.Get("/v1/releases", apiHandler.GetAll)
 	rtr.Get("/v1/releases/{name}", apiHandler.GetSingle)
+	rtr.Get("/v1/releases/{name}/deployments", apiHandler.GetDeployments)
+	rtr.Get("/v1/releases/{name}/events", apiHandler.GetEvents)
+	rtr.Get("/v1/events", apiHandler.GetAllEvents)
 	rtr.Delete("/v1/releases/{name}", apiHandler.Delete)
+	rtr.Post("/v1/releases/{name}/promote", apiHandler.Promote)
+	rtr.Post("/v1/releases/{name}/pause", apiHandler.Pause)
+	rtr.Post("/v1/releases/{name}/resume", apiHandler.Resume)
+	rtr.Post("/v1/releases/{name}/abort", apiHandler.Abort)
+	rtr.Post("/v1/releases/{name}/gates/{gate}", apiHandler.DecideGate)
 
 	return rtr, rw, pp, m
 }
@@ -162,6 +173,122 @@ func TestReleaseHandlerGetSingleReturns404WhenNotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, rw.Code)
 }
 
+func TestReleaseHandlerGetDeploymentsReturnsHistoryAndActiveDeployment(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	m1 := &models.Release{}
+	m1.Name = "test1"
+	m1.Deployment = models.NewDeployment("api-deployment", "3", time.Now())
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "test1").Return(m1, nil)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "ListDeployments")
+	m.StoreMock.On("ListDeployments", "test1").Return([]*models.Deployment{
+		{ID: "1", CandidateVersion: "1", Outcome: models.DeploymentOutcomePromoted},
+		{ID: "2", CandidateVersion: "2", Outcome: models.DeploymentOutcomeRolledBack, FailureReason: "boom"},
+	}, nil)
+
+	r := httptest.NewRequest("GET", "/v1/releases/test1/deployments", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+
+	deps := []models.Deployment{}
+	err := json.NewDecoder(rw.Body).Decode(&deps)
+	require.NoError(t, err)
+
+	require.Len(t, deps, 3)
+	require.Equal(t, "boom", deps[1].FailureReason)
+	require.Equal(t, "3", deps[2].CandidateVersion)
+	require.Equal(t, models.DeploymentOutcomeInProgress, deps[2].Outcome)
+}
+
+func TestReleaseHandlerGetDeploymentsReturns404WhenNotFound(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", mock.Anything).Return(nil, interfaces.ReleaseNotFound)
+
+	r := httptest.NewRequest("GET", "/v1/releases/test1/deployments", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestReleaseHandlerGetEventsReturns404WhenNotFound(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", mock.Anything).Return(nil, interfaces.ReleaseNotFound)
+
+	r := httptest.NewRequest("GET", "/v1/releases/test1/events", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func streamEvents(t *testing.T, d http.Handler, path string) *bufio.Reader {
+	ts := httptest.NewServer(d)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t.Cleanup(func() {
+		cancel()
+		ts.Close()
+	})
+
+	r, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+path, nil)
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	return bufio.NewReader(resp.Body)
+}
+
+func TestReleaseHandlerGetEventsStreamsEventsForRelease(t *testing.T) {
+	d, _, pp, _ := setupRelease(t)
+
+	body := streamEvents(t, d, "/v1/releases/test1/events")
+
+	pp.GetEvents().Publish(interfaces.ReleaseEvent{Release: "other", Type: interfaces.ReleaseEventState})
+	pp.GetEvents().Publish(interfaces.ReleaseEvent{Release: "test1", Type: interfaces.ReleaseEventTraffic, CandidateTraffic: 10})
+
+	line, err := body.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "event: traffic\n", line)
+
+	line, err = body.ReadString('\n')
+	require.NoError(t, err)
+	require.Contains(t, line, `"candidate_traffic":10`)
+}
+
+func TestReleaseHandlerGetAllEventsStreamsEventsForAllReleases(t *testing.T) {
+	d, _, pp, _ := setupRelease(t)
+
+	body := streamEvents(t, d, "/v1/events")
+
+	pp.GetEvents().Publish(interfaces.ReleaseEvent{Release: "other", Type: interfaces.ReleaseEventState})
+
+	line, err := body.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "event: state\n", line)
+}
+func TestReleaseHandlerGetDeploymentsWithStoreErrorReturnsError(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "test1").Return(&models.Release{Name: "test1"}, nil)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "ListDeployments")
+	m.StoreMock.On("ListDeployments", "test1").Return(nil, fmt.Errorf("boom"))
+
+	r := httptest.NewRequest("GET", "/v1/releases/test1/deployments", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+}
+
 func TestReleaseHandlerDeleteWithGetErrorReturnsError(t *testing.T) {
 	d, rw, _, m := setupRelease(t)
 
@@ -203,3 +330,214 @@ func TestReleaseHandlerDeleteWithNoErrorReturnsOk(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, rw.Code)
 }
+
+func TestReleaseHandlerPromoteWithNotFoundReturns404(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", mock.Anything).Return(nil, interfaces.ReleaseNotFound)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/promote", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	m.StateMachineMock.AssertNotCalled(t, "Promote")
+}
+
+func TestReleaseHandlerPromoteWhenNotAwaitingPromotionReturnsConflict(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/promote", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusConflict, rw.Code)
+	m.StateMachineMock.AssertNotCalled(t, "Promote")
+}
+
+func TestReleaseHandlerPromoteWithNoErrorReturnsOk(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StateAwaitPromotion)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/promote", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	m.StateMachineMock.AssertCalled(t, "Promote")
+}
+
+func TestReleaseHandlerPauseWhenNotMonitoringReturnsConflict(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/pause", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusConflict, rw.Code)
+	m.StateMachineMock.AssertNotCalled(t, "Pause")
+}
+
+func TestReleaseHandlerPauseWhenScalingReturnsConflict(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StateScale)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/pause", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusConflict, rw.Code)
+	m.StateMachineMock.AssertNotCalled(t, "Pause")
+}
+
+func TestReleaseHandlerPauseWithNoErrorReturnsOk(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StateMonitor)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/pause", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	m.StateMachineMock.AssertCalled(t, "Pause")
+}
+
+func TestReleaseHandlerPauseWithErrorReturnsError(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StateMonitor)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "Pause")
+	m.StateMachineMock.On("Pause").Return(fmt.Errorf("boom"))
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/pause", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+}
+
+func TestReleaseHandlerResumeWhenNotPausedReturnsConflict(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StateMonitor)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/resume", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusConflict, rw.Code)
+	m.StateMachineMock.AssertNotCalled(t, "Resume")
+}
+
+func TestReleaseHandlerResumeWithNoErrorReturnsOk(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StatePaused)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/resume", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	m.StateMachineMock.AssertCalled(t, "Resume")
+}
+
+func TestReleaseHandlerAbortWhenIdleReturnsConflict(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StateIdle)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/abort", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusConflict, rw.Code)
+	m.StateMachineMock.AssertNotCalled(t, "Abort")
+}
+
+func TestReleaseHandlerAbortWithNoErrorReturnsOk(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StatePaused)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/abort", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	m.StateMachineMock.AssertCalled(t, "Abort")
+}
+
+func TestReleaseHandlerAbortWhenPendingReturnsOk(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StatePending)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/abort", nil)
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	m.StateMachineMock.AssertCalled(t, "Abort")
+}
+
+func TestReleaseHandlerDecideGateWithInvalidResultReturnsBadRequest(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/gates/cab", bytes.NewBufferString(`{"result": "maybe"}`))
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	m.StateMachineMock.AssertNotCalled(t, "DecideGate", mock.Anything, mock.Anything)
+}
+
+func TestReleaseHandlerDecideGateWithNoErrorReturnsOk(t *testing.T) {
+	d, rw, _, m := setupRelease(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetRelease")
+	m.StoreMock.On("GetRelease", "consul").Return(&models.Release{Name: "consul"}, nil)
+
+	testutils.ClearMockCall(&m.StateMachineMock.Mock, "CurrentState")
+	m.StateMachineMock.On("CurrentState").Return(interfaces.StateMonitor)
+
+	r := httptest.NewRequest("POST", "/v1/releases/consul/gates/cab", bytes.NewBufferString(`{"result": "rejected", "reason": "change freeze"}`))
+	d.ServeHTTP(rw, r)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	m.StateMachineMock.AssertCalled(t, "DecideGate", "cab", interfaces.GateResponse{Result: interfaces.GateResultRejected, Reason: "change freeze"})
+}
@@ -61,7 +61,15 @@ func New(config *ServerConfig, p interfaces.Provider, l hclog.Logger) (*Server,
 	rtr.Post("/v1/releases", apiHandler.Post)
 	rtr.Get("/v1/releases", apiHandler.GetAll)
 	rtr.Get("/v1/releases/{name}", apiHandler.GetSingle)
+	rtr.Get("/v1/releases/{name}/deployments", apiHandler.GetDeployments)
+	rtr.With(noWriteTimeout).Get("/v1/releases/{name}/events", apiHandler.GetEvents)
+	rtr.With(noWriteTimeout).Get("/v1/events", apiHandler.GetAllEvents)
 	rtr.Delete("/v1/releases/{name}", apiHandler.Delete)
+	rtr.Post("/v1/releases/{name}/promote", apiHandler.Promote)
+	rtr.Post("/v1/releases/{name}/pause", apiHandler.Pause)
+	rtr.Post("/v1/releases/{name}/resume", apiHandler.Resume)
+	rtr.Post("/v1/releases/{name}/abort", apiHandler.Abort)
+	rtr.Post("/v1/releases/{name}/gates/{gate}", apiHandler.DecideGate)
 
 	apiServer.router = rtr
 
@@ -96,6 +104,7 @@ func (a *Server) Start() error {
 			Handler:      a.router,
 			ReadTimeout:  10 * time.Second,
 			WriteTimeout: 10 * time.Second,
+			ConnContext:  saveConn,
 		}
 
 		// start the TLS endpoint
@@ -122,6 +131,7 @@ func (a *Server) Start() error {
 			Handler:      a.router,
 			ReadTimeout:  10 * time.Second,
 			WriteTimeout: 10 * time.Second,
+			ConnContext:  saveConn,
 		}
 
 		go func() {
@@ -200,3 +210,23 @@ func (a *Server) Shutdown() error {
 
 	return nil
 }
+
+type connContextKey struct{}
+
+// saveConn adds the connection to the context of the requests that are received on it
+func saveConn(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// noWriteTimeout removes the write deadline that the server sets for the request, the events
+// endpoints stream responses until the client disconnects. The server sets the deadline again
+// when the next request is read from the connection.
+func noWriteTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if c, ok := r.Context().Value(connContextKey{}).(net.Conn); ok {
+			c.SetWriteDeadline(time.Time{})
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setupServer starts a server with a short write timeout, the handler writes a line, waits for
+// longer than the timeout, then writes a second line
+func setupServer(t *testing.T, tls bool, middleware func(http.Handler) http.Handler) *httptest.Server {
+	var h http.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(rw, "first")
+		rw.(http.Flusher).Flush()
+
+		time.Sleep(100 * time.Millisecond)
+
+		fmt.Fprintln(rw, "second")
+		rw.(http.Flusher).Flush()
+	})
+
+	if middleware != nil {
+		h = middleware(h)
+	}
+
+	ts := httptest.NewUnstartedServer(h)
+	ts.Config.WriteTimeout = 20 * time.Millisecond
+	ts.Config.ConnContext = saveConn
+
+	if tls {
+		ts.StartTLS()
+	} else {
+		ts.Start()
+	}
+
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func readLines(t *testing.T, ts *httptest.Server) []string {
+	resp, err := ts.Client().Get(ts.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	lines := []string{}
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+
+	return lines
+}
+
+func TestWriteTimeoutEndsStreamedResponse(t *testing.T) {
+	ts := setupServer(t, false, nil)
+
+	require.NotContains(t, readLines(t, ts), "second")
+}
+
+func TestNoWriteTimeoutStreamsResponse(t *testing.T) {
+	ts := setupServer(t, false, noWriteTimeout)
+
+	require.Equal(t, []string{"first", "second"}, readLines(t, ts))
+}
+
+func TestNoWriteTimeoutStreamsResponseWithTLS(t *testing.T) {
+	ts := setupServer(t, true, noWriteTimeout)
+
+	require.Equal(t, []string{"first", "second"}, readLines(t, ts))
+}
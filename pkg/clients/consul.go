@@ -1,8 +1,10 @@
 package clients
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 )
@@ -29,9 +31,10 @@ type Consul interface {
 	// for the primary and the candidate
 	CreateServiceSplitter(name string, primaryTraffic, candidateTraffic int) error
 
-	// CreateServiceRoutere creates or updates an existing service router for the given service
-	// routes are added to add retries for connection failures
-	CreateServiceRouter(name string) error
+	// CreateServiceRouter creates or updates an existing service router for the given service
+	// routes are added to add retries for connection failures, requests matching any of the
+	// candidateMatches are routed to the candidate
+	CreateServiceRouter(name string, candidateMatches []api.ServiceRouteHTTPMatch) error
 
 	// CreateUpstreamRouter creates or updates a service router that allows the candidate services
 	// to be called by specifying the correct HOST header
@@ -52,8 +55,8 @@ type Consul interface {
 	// DeleteServiceSplitter removes the service splitter resource created by the resolver
 	DeleteServiceSplitter(name string) error
 
-	// DeleteServiceResolver removes the service resolver, if the resolver was not created by the release controller
-	// this method restores the resolver to the original state
+	// DeleteServiceRouter removes the service router, if the router was not created by the release controller
+	// this method removes the routes for the primary and candidate
 	DeleteServiceRouter(name string) error
 
 	// DeleteServiceIntention removes any service intention allowing the release controller communication with the given service
@@ -62,11 +65,21 @@ type Consul interface {
 	// DeleteUpstreamRouter removes the upstream router that allows the controller to contact candidate services.
 	DeleteUpstreamRouter(name string) error
 
+	// WaitUntilConfigApplied blocks until the compiled discovery chain for the given service, as seen by the
+	// local Consul agent, contains all the config entry changes that were made before the call. The local
+	// agent uses the compiled chain to configure the Envoy proxies that it manages.
+	// Returns an error if the context is cancelled before the changes have been applied
+	WaitUntilConfigApplied(ctx context.Context, name string) error
+
 	// Check the Consul health of the service, returns an error when one or more endpoints are not healthy
 	// can accept a filter string to return a subset of a services instances https://www.consul.io/api-docs/health#filtering-2
 	// Returns an error if all health checks are not passing or if no service instances are found
 	CheckHealth(name string, filter string) error
 
+	// SidecarProxies returns the instances of the sidecar proxy for the named service, the filter selects a
+	// subset of the instances https://www.consul.io/api-docs/health#filtering-2
+	SidecarProxies(name string, filter string) ([]SidecarProxy, error)
+
 	// SetKV sets the data at the given path in the Consul Key Value store
 	SetKV(path string, data []byte) error
 
@@ -260,7 +273,7 @@ func (c *ConsulImpl) CreateServiceSplitter(name string, primaryTraffic, canaryTr
 }
 
 // CreateServiceRouter creates a new service router
-func (c *ConsulImpl) CreateServiceRouter(name string) error {
+func (c *ConsulImpl) CreateServiceRouter(name string, candidateMatches []api.ServiceRouteHTTPMatch) error {
 	qo := &api.QueryOptions{}
 	wo := &api.WriteOptions{}
 	defaults := &api.ServiceRouterConfigEntry{}
@@ -296,6 +309,20 @@ func (c *ConsulImpl) CreateServiceRouter(name string) error {
 		defaults = ce.(*api.ServiceRouterConfigEntry)
 	}
 
+	primarySubset := fmt.Sprintf("%s-%s-primary", SubsetPrefix, name)
+	candidateSubset := fmt.Sprintf("%s-%s-candidate", SubsetPrefix, name)
+
+	// routes are evaluated in order, the primary and candidate routes need to be before
+	// any existing routes. remove any routes from a previous call so that they are not duplicated
+	existingRoutes := []api.ServiceRoute{}
+	for _, r := range defaults.Routes {
+		if r.Destination == nil || (r.Destination.ServiceSubset != primarySubset && r.Destination.ServiceSubset != candidateSubset) {
+			existingRoutes = append(existingRoutes, r)
+		}
+	}
+
+	routes := []api.ServiceRoute{}
+
 	// create the routes
 	primaryRoute := api.ServiceRoute{}
 
@@ -308,14 +335,14 @@ func (c *ConsulImpl) CreateServiceRouter(name string) error {
 
 	primaryRoute.Destination = &api.ServiceRouteDestination{
 		Service:               name,
-		ServiceSubset:         fmt.Sprintf("%s-%s-primary", SubsetPrefix, name),
+		ServiceSubset:         primarySubset,
 		NumRetries:            5,
 		RetryOnConnectFailure: true,
 		RetryOnStatusCodes:    []uint32{503},
 	}
 
 	primaryRoute.Match = primaryRouteHTTP
-	defaults.Routes = append(defaults.Routes, primaryRoute)
+	routes = append(routes, primaryRoute)
 
 	canaryRoute := api.ServiceRoute{}
 
@@ -328,14 +355,33 @@ func (c *ConsulImpl) CreateServiceRouter(name string) error {
 
 	canaryRoute.Destination = &api.ServiceRouteDestination{
 		Service:               name,
-		ServiceSubset:         fmt.Sprintf("%s-%s-candidate", SubsetPrefix, name),
+		ServiceSubset:         candidateSubset,
 		NumRetries:            5,
 		RetryOnConnectFailure: true,
 		RetryOnStatusCodes:    []uint32{503},
 	}
 
 	canaryRoute.Match = canaryRouteHTTP
-	defaults.Routes = append(defaults.Routes, canaryRoute)
+	routes = append(routes, canaryRoute)
+
+	// add a route to the candidate for each of the matches, Consul combines the conditions in a
+	// single match using AND, separate routes ensure that a request matching any rule is sent
+	// to the candidate
+	for i := range candidateMatches {
+		matchRoute := api.ServiceRoute{}
+		matchRoute.Match = &api.ServiceRouteMatch{HTTP: &candidateMatches[i]}
+		matchRoute.Destination = &api.ServiceRouteDestination{
+			Service:               name,
+			ServiceSubset:         candidateSubset,
+			NumRetries:            5,
+			RetryOnConnectFailure: true,
+			RetryOnStatusCodes:    []uint32{503},
+		}
+
+		routes = append(routes, matchRoute)
+	}
+
+	defaults.Routes = append(routes, existingRoutes...)
 
 	_, _, err = c.client.ConfigEntries().Set(defaults, wo)
 	return err
@@ -582,26 +628,35 @@ func (c *ConsulImpl) DeleteServiceRouter(name string) error {
 
 	// check that we created this
 	ce, _, err := c.client.ConfigEntries().Get(api.ServiceRouter, name, qo)
-	if err != nil && ce != nil {
-		return nil
-	}
+	if err != nil {
+		// the router does not exist, nothing to clean up
+		if strings.Contains(err.Error(), "404") {
+			return nil
+		}
 
-	if err != nil || ce == nil {
 		return err
 	}
 
+	if ce == nil {
+		return nil
+	}
+
 	// if we did not create the router do an update removing the routes for the primary and candidate
 	if ce.GetMeta()[MetaCreatedTag] != MetaCreatedValue {
+		sre := ce.(*api.ServiceRouterConfigEntry)
 		routes := []api.ServiceRoute{}
 
-		for _, r := range ce.(*api.ServiceRouterConfigEntry).Routes {
-			if r.Destination.ServiceSubset != fmt.Sprintf("%s-%s-primary", SubsetPrefix, name) &&
-				r.Destination.ServiceSubset != fmt.Sprintf("%s-%s-candidate", SubsetPrefix, name) {
+		for _, r := range sre.Routes {
+			if r.Destination == nil ||
+				(r.Destination.ServiceSubset != fmt.Sprintf("%s-%s-primary", SubsetPrefix, name) &&
+					r.Destination.ServiceSubset != fmt.Sprintf("%s-%s-candidate", SubsetPrefix, name)) {
 				routes = append(routes, r)
 			}
 		}
 
-		_, _, err := c.client.ConfigEntries().Set(ce, wo)
+		sre.Routes = routes
+
+		_, _, err := c.client.ConfigEntries().Set(sre, wo)
 		return err
 	}
 
@@ -699,6 +754,51 @@ func (c *ConsulImpl) DeleteServiceIntention(name string) error {
 	return err
 }
 
+// WaitUntilConfigApplied uses blocking queries to watch the discovery chain for the service until the index of the
+// chain is greater or equal to the index of the config entries at the time of the call
+func (c *ConsulImpl) WaitUntilConfigApplied(ctx context.Context, name string) error {
+	qo := &api.QueryOptions{}
+
+	if c.options.Namespace != "" {
+		qo.Namespace = c.options.Namespace
+	}
+
+	if c.options.Partition != "" {
+		qo.Partition = c.options.Partition
+	}
+
+	// the index returned when listing config entries is the index of the latest change to any config entry
+	_, meta, err := c.client.ConfigEntries().List(api.ServiceDefaults, qo.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("unable to read config entries index: %s", err)
+	}
+
+	configIndex := meta.LastIndex
+	chainIndex := uint64(0)
+
+	for {
+		// use the agent cache as this is the chain the agent uses to configure the proxies
+		cqo := &api.QueryOptions{
+			Namespace: qo.Namespace,
+			Partition: qo.Partition,
+			UseCache:  true,
+			WaitIndex: chainIndex,
+			WaitTime:  10 * time.Second,
+		}
+
+		_, meta, err := c.client.DiscoveryChain().Get(name, nil, cqo.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("unable to read discovery chain for service %s: %s", name, err)
+		}
+
+		if meta.LastIndex >= configIndex {
+			return nil
+		}
+
+		chainIndex = meta.LastIndex
+	}
+}
+
 // CheckHealth returns an error if the named service has any health checks that are failing
 func (c *ConsulImpl) CheckHealth(name string, filter string) error {
 	qo := &api.QueryOptions{Filter: filter}
@@ -746,6 +846,56 @@ func (c *ConsulImpl) CheckHealth(name string, filter string) error {
 	return nil
 }
 
+// SidecarProxy is an instance of the Envoy sidecar proxy for a service
+type SidecarProxy struct {
+	// Address of the proxy instance
+	Address string
+
+	// PrometheusBindAddr is the envoy_prometheus_bind_addr from the proxy config, empty when
+	// the proxy does not expose Prometheus metrics
+	PrometheusBindAddr string
+}
+
+// SidecarProxies returns the instances of the sidecar proxy for the named service that match the filter
+func (c *ConsulImpl) SidecarProxies(name string, filter string) ([]SidecarProxy, error) {
+	qo := &api.QueryOptions{Filter: filter}
+
+	if c.options.Namespace != "" {
+		qo.Namespace = c.options.Namespace
+	}
+
+	if c.options.Partition != "" {
+		qo.Partition = c.options.Partition
+	}
+
+	proxyName := name + "-sidecar-proxy"
+
+	entries, _, err := c.client.Health().Service(proxyName, "", false, qo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list instances for service %s: %s", proxyName, err)
+	}
+
+	proxies := []SidecarProxy{}
+	for _, e := range entries {
+		p := SidecarProxy{Address: e.Service.Address}
+
+		// the service address is optional, when not set the service uses the address of the node
+		if p.Address == "" {
+			p.Address = e.Node.Address
+		}
+
+		if e.Service.Proxy != nil {
+			if ba, ok := e.Service.Proxy.Config["envoy_prometheus_bind_addr"].(string); ok {
+				p.PrometheusBindAddr = ba
+			}
+		}
+
+		proxies = append(proxies, p)
+	}
+
+	return proxies, nil
+}
+
 // SetKV sets the data at the given path in the Consul Key Value store
 func (c *ConsulImpl) SetKV(path string, data []byte) error {
 	kvp := &api.KVPair{}
@@ -1,6 +1,9 @@
 package clients
 
 import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -26,8 +29,8 @@ func (mc *ConsulMock) CreateServiceSplitter(name string, primaryTraffic, canaryT
 	return args.Error(0)
 }
 
-func (mc *ConsulMock) CreateServiceRouter(name string) error {
-	args := mc.Called(name)
+func (mc *ConsulMock) CreateServiceRouter(name string, candidateMatches []api.ServiceRouteHTTPMatch) error {
+	args := mc.Called(name, candidateMatches)
 
 	return args.Error(0)
 }
@@ -87,6 +90,16 @@ func (mc *ConsulMock) CheckHealth(name, filter string) error {
 	return args.Error(0)
 }
 
+func (mc *ConsulMock) SidecarProxies(name, filter string) ([]SidecarProxy, error) {
+	args := mc.Called(name, filter)
+
+	if a, ok := args.Get(0).([]SidecarProxy); ok {
+		return a, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 // SetKV sets the data at the given path in the Consul Key Value store
 func (mc *ConsulMock) SetKV(path string, data []byte) error {
 	args := mc.Mock.Called(path, data)
@@ -120,3 +133,9 @@ func (mc *ConsulMock) ListKV(path string) ([]string, error) {
 
 	return nil, args.Error(1)
 }
+
+func (mc *ConsulMock) WaitUntilConfigApplied(ctx context.Context, name string) error {
+	args := mc.Called(ctx, name)
+
+	return args.Error(0)
+}
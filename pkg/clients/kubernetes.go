@@ -13,6 +13,7 @@ import (
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/sethvargo/go-retry"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -44,6 +45,10 @@ type Kubernetes interface {
 
 	// DeleteRelease deletes the given Kubernetes Release
 	DeleteRelease(ctx context.Context, name, namespace string) error
+
+	// GetKubernetesDeploymentPods returns the pods for the current revision of the given deployment, pods
+	// from other deployments that share the same labels, such as the primary, are not returned
+	GetKubernetesDeploymentPods(ctx context.Context, name, namespace string) ([]corev1.Pod, error)
 }
 
 // NewKubernetes creates a new Kubernetes implementation
@@ -81,9 +86,15 @@ func NewKubernetes(configPath string, timeout, interval time.Duration, l hclog.L
 	return &KubernetesImpl{clientset: cs, controllerClient: cc, timeout: timeout, interval: interval, logger: l}, nil
 }
 
+// NewKubernetesWithClientset creates a new Kubernetes implementation that uses the given clientset, this can be
+// used to create a client for a fake clientset when testing
+func NewKubernetesWithClientset(cs kubernetes.Interface, timeout, interval time.Duration, l hclog.Logger) Kubernetes {
+	return &KubernetesImpl{clientset: cs, timeout: timeout, interval: interval, logger: l}
+}
+
 // KubernetesImpl is the concrete implementation of the Kubernetes client interface
 type KubernetesImpl struct {
-	clientset        *kubernetes.Clientset
+	clientset        kubernetes.Interface
 	controllerClient controller.Client
 	timeout          time.Duration
 	interval         time.Duration
@@ -237,6 +248,59 @@ func (k *KubernetesImpl) DeleteRelease(ctx context.Context, name, namespace stri
 	return err
 }
 
+func (k *KubernetesImpl) GetKubernetesDeploymentPods(ctx context.Context, name, namespace string) ([]corev1.Pod, error) {
+	dep, err := k.GetKubernetesDeployment(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := v1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector for deployment %s: %s", name, err)
+	}
+
+	listOptions := v1.ListOptions{LabelSelector: selector.String()}
+
+	rsl, err := k.clientset.AppsV1().ReplicaSets(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list replica sets for deployment %s: %s", name, err)
+	}
+
+	// find the replica sets for the current revision of the deployment, when the deployment does not
+	// have a revision all of its replica sets are used
+	revision := dep.Annotations[deploymentRevisionAnnotation]
+	replicaSets := map[string]bool{}
+
+	for _, rs := range rsl.Items {
+		if !ownedBy(rs.OwnerReferences, "Deployment", name) {
+			continue
+		}
+
+		if revision != "" && rs.Annotations[deploymentRevisionAnnotation] != revision {
+			continue
+		}
+
+		replicaSets[rs.Name] = true
+	}
+
+	pl, err := k.clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pods for deployment %s: %s", name, err)
+	}
+
+	pods := []corev1.Pod{}
+	for _, p := range pl.Items {
+		for rs := range replicaSets {
+			if ownedBy(p.OwnerReferences, "ReplicaSet", rs) {
+				pods = append(pods, p)
+				break
+			}
+		}
+	}
+
+	return pods, nil
+}
+
 func (k *KubernetesImpl) GetDeployment(ctx context.Context, name, namespace string) (*interfaces.Deployment, error) {
 	dep, err := k.GetKubernetesDeployment(ctx, name, namespace)
 	if dep != nil {
@@ -331,3 +395,15 @@ func (k *KubernetesImpl) CandidateSubsetFilter() string {
 func (k *KubernetesImpl) PrimarySubsetFilter() string {
 	return fmt.Sprintf(`Service.ID contains "%s"`, "primary")
 }
+
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+func ownedBy(refs []v1.OwnerReference, kind, name string) bool {
+	for _, r := range refs {
+		if r.Kind == kind && r.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
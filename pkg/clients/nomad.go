@@ -30,6 +30,11 @@ type Nomad interface {
 
 	GetJobWithSelector(ctx context.Context, selector, namespace string) (*api.Job, error)
 
+	// GetJobAllocations returns the allocations for the current version of the Nomad job
+	// matching the given name and namespace.
+	// If the job does not exist a DeploymentNotFound error will be returned
+	GetJobAllocations(ctx context.Context, name, namespace string) ([]*api.AllocationListStub, error)
+
 	UpsertJob(ctx context.Context, job *api.Job) error
 
 	DeleteJob(ctx context.Context, id string, namespace string) error
@@ -90,9 +95,32 @@ func (ni *NomadImpl) GetJobWithSelector(ctx context.Context, selector, namespace
 	return nil, interfaces.ErrDeploymentNotFound
 }
 
+// GetJob returns the running or pending job with the given name and namespace, the name must match
+// exactly and is not treated as a regular expression
 func (ni *NomadImpl) GetJob(ctx context.Context, name, namespace string) (*api.Job, error) {
+	return ni.GetJobWithSelector(ctx, "^"+regexp.QuoteMeta(name)+"$", namespace)
+}
+
+func (ni *NomadImpl) GetJobAllocations(ctx context.Context, name, namespace string) ([]*api.AllocationListStub, error) {
+	job, err := ni.GetJob(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	allocs, _, err := ni.client.Jobs().Allocations(*job.ID, false, &api.QueryOptions{Namespace: namespace})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list allocations for job %s: %s", *job.ID, err)
+	}
+
+	// allocations from previous versions of the job are not part of the current deployment
+	current := []*api.AllocationListStub{}
+	for _, a := range allocs {
+		if job.Version == nil || a.JobVersion == *job.Version {
+			current = append(current, a)
+		}
+	}
 
-	return ni.GetJobWithSelector(ctx, name, namespace)
+	return current, nil
 }
 
 func (ni *NomadImpl) UpsertJob(ctx context.Context, job *api.Job) error {
@@ -2,8 +2,13 @@ package clients
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/api"
@@ -19,16 +24,79 @@ type Prometheus interface {
 	Query(ctx context.Context, address, query string, ts time.Time) (model.Value, v1.Warnings, error)
 }
 
+// PrometheusOptions configure the authentication and TLS used when connecting to the Prometheus server,
+// secrets are read from files or environment variables each time a query is made so that rotated
+// credentials are used without restarting the controller
+type PrometheusOptions struct {
+	// BearerTokenFile or BearerTokenEnv contain the token sent in the Authorization header
+	BearerTokenFile string
+	BearerTokenEnv  string
+
+	// Username and the password from PasswordFile or PasswordEnv are sent using basic auth
+	Username     string
+	PasswordFile string
+	PasswordEnv  string
+
+	// Headers are added to every request, e.g. X-Scope-OrgID for Cortex or Mimir
+	Headers map[string]string
+
+	// CAFile is the PEM encoded CA bundle used to verify the server certificate
+	CAFile string
+
+	// CertFile and KeyFile are the PEM encoded client certificate and key
+	CertFile string
+	KeyFile  string
+
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
 type PrometheusImpl struct {
+	roundTripper http.RoundTripper
 }
 
-func NewPrometheus() (Prometheus, error) {
-	return &PrometheusImpl{}, nil
+// NewPrometheus creates a new Prometheus client, options are optional and when nil the
+// client connects without authentication
+func NewPrometheus(options *PrometheusOptions) (Prometheus, error) {
+	if options == nil {
+		return &PrometheusImpl{}, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         options.ServerName,
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+
+	if options.CAFile != "" {
+		ca, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %s: %s", options.CAFile, err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("unable to parse CA file %s, no PEM encoded certificates found", options.CAFile)
+		}
+	}
+
+	if options.CertFile != "" || options.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s and key %s: %s", options.CertFile, options.KeyFile, err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := api.DefaultRoundTripper.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &PrometheusImpl{roundTripper: &prometheusRoundTripper{options: options, next: transport}}, nil
 }
 
 func (p *PrometheusImpl) Query(ctx context.Context, address, query string, ts time.Time) (model.Value, v1.Warnings, error) {
 	// create the promethus client
-	c, err := api.NewClient(api.Config{Address: address})
+	c, err := api.NewClient(api.Config{Address: address, RoundTripper: p.roundTripper})
 	if err != nil {
 		return nil, v1.Warnings{}, fmt.Errorf("unable to create new Prometheus client: %s", err)
 	}
@@ -64,3 +132,61 @@ func (p *PrometheusImpl) Query(ctx context.Context, address, query string, ts ti
 
 	return value, warn, queryErr
 }
+
+// prometheusRoundTripper adds the headers and credentials to the requests made to the Prometheus server
+type prometheusRoundTripper struct {
+	options *PrometheusOptions
+	next    http.RoundTripper
+}
+
+func (rt *prometheusRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	for k, v := range rt.options.Headers {
+		req.Header.Set(k, v)
+	}
+
+	token, err := ReadSecret(rt.options.BearerTokenFile, rt.options.BearerTokenEnv)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read bearer token: %s", err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if rt.options.Username != "" {
+		password, err := ReadSecret(rt.options.PasswordFile, rt.options.PasswordEnv)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read basic auth password: %s", err)
+		}
+
+		req.SetBasicAuth(rt.options.Username, password)
+	}
+
+	return rt.next.RoundTrip(req)
+}
+
+// ReadSecret returns the secret from the file or the environment variable, surrounding whitespace is
+// removed from secrets read from a file
+func ReadSecret(file, env string) (string, error) {
+	if file != "" {
+		d, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSpace(string(d)), nil
+	}
+
+	if env != "" {
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", env)
+		}
+
+		return v, nil
+	}
+
+	return "", nil
+}
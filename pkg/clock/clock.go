@@ -0,0 +1,182 @@
+package clock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Real is a clock that uses the system time
+type Real struct{}
+
+// NewReal creates a clock that uses the system time
+func NewReal() *Real {
+	return &Real{}
+}
+
+// Now returns the current system time
+func (r *Real) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the
+// returned channel
+func (r *Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WithTimeout returns a copy of the context that is cancelled when the duration elapses
+func (r *Real) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, d)
+}
+
+// Virtual is a clock that only moves forward when a caller waits on it, rather than
+// blocking the clock is advanced by the duration of the wait. Virtual is used to
+// simulate releases where the statemachine only has a single active caller at any time.
+type Virtual struct {
+	m        sync.Mutex
+	now      time.Time
+	contexts []*virtualContext
+}
+
+// virtualContext is a context that expires when the virtual clock reaches its deadline
+type virtualContext struct {
+	context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	m   sync.Mutex
+	err error
+}
+
+func newVirtualContext(parent context.Context, deadline time.Time) *virtualContext {
+	c := &virtualContext{Context: parent, deadline: deadline, done: make(chan struct{})}
+
+	// propagate the cancellation of the parent
+	go func() {
+		select {
+		case <-parent.Done():
+			c.cancel(parent.Err())
+		case <-c.done:
+		}
+	}()
+
+	return c
+}
+
+// Deadline returns the virtual time when the context expires
+func (c *virtualContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+// String describes the context without reading its state, this allows the context to be printed
+// while it is cancelled
+func (c *virtualContext) String() string {
+	return fmt.Sprintf("%v.WithVirtualDeadline(%s)", c.Context, c.deadline)
+}
+
+// Done returns a channel that is closed when the context is cancelled or expires
+func (c *virtualContext) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns context.DeadlineExceeded when the context expired or context.Canceled when
+// it was cancelled
+func (c *virtualContext) Err() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.err
+}
+
+// cancel closes the done channel and records the error, only the first call has any effect
+func (c *virtualContext) cancel(err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	c.err = err
+	close(c.done)
+}
+
+// NewVirtual creates a virtual clock that starts at the given time
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+// Now returns the current virtual time
+func (v *Virtual) Now() time.Time {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	return v.now
+}
+
+// After advances the virtual time by the duration and returns a channel that
+// has already received the new time, any contexts whose deadline has been reached
+// are cancelled before the time is sent
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	if d > 0 {
+		v.now = v.now.Add(d)
+	}
+
+	v.expire()
+
+	c := make(chan time.Time, 1)
+	c <- v.now
+
+	return c
+}
+
+// WithTimeout returns a copy of the context that is cancelled when the virtual time
+// reaches the deadline, virtual time only moves while a caller waits on the clock
+func (v *Virtual) WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	vc := newVirtualContext(ctx, v.now.Add(d))
+
+	v.contexts = append(v.contexts, vc)
+	v.expire()
+
+	return vc, func() {
+		vc.cancel(context.Canceled)
+		v.remove(vc)
+	}
+}
+
+// expire cancels the contexts that have reached their deadline, the caller must hold the lock
+func (v *Virtual) expire() {
+	active := []*virtualContext{}
+
+	for _, c := range v.contexts {
+		if !v.now.Before(c.deadline) {
+			c.cancel(context.DeadlineExceeded)
+			continue
+		}
+
+		active = append(active, c)
+	}
+
+	v.contexts = active
+}
+
+// remove stops tracking the deadline of a cancelled context
+func (v *Virtual) remove(vc *virtualContext) {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	for i, c := range v.contexts {
+		if c == vc {
+			v.contexts = append(v.contexts[:i], v.contexts[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtualAfterAdvancesTime(t *testing.T) {
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	c := NewVirtual(start)
+
+	now := <-c.After(10 * time.Minute)
+
+	require.Equal(t, start.Add(10*time.Minute), now)
+	require.Equal(t, start.Add(10*time.Minute), c.Now())
+}
+
+func TestVirtualAfterWithNegativeDurationDoesNotChangeTime(t *testing.T) {
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	c := NewVirtual(start)
+
+	<-c.After(-1 * time.Minute)
+
+	require.Equal(t, start, c.Now())
+}
+
+func TestVirtualWithTimeoutDoesNotCancelOrChangeTime(t *testing.T) {
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	c := NewVirtual(start)
+
+	ctx, cancel := c.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	require.NoError(t, ctx.Err())
+	require.Equal(t, start, c.Now())
+
+	d, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.Equal(t, start.Add(time.Minute), d)
+}
+
+func TestVirtualWithTimeoutExpiresWhenTimeReachesDeadline(t *testing.T) {
+	start := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	c := NewVirtual(start)
+
+	ctx, cancel := c.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	<-c.After(30 * time.Second)
+	require.NoError(t, ctx.Err())
+
+	<-c.After(30 * time.Second)
+	require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	require.True(t, isClosed(ctx.Done()))
+}
+
+func TestVirtualWithTimeoutExpiresChildContexts(t *testing.T) {
+	c := NewVirtual(time.Now())
+
+	ctx, cancel := c.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	child, childCancel := context.WithCancel(ctx)
+	defer childCancel()
+
+	<-c.After(time.Minute)
+
+	require.Eventually(t, func() bool { return child.Err() == context.DeadlineExceeded }, time.Second, time.Millisecond)
+}
+
+func TestVirtualWithTimeoutCancelledByParentReturnsCanceled(t *testing.T) {
+	c := NewVirtual(time.Now())
+
+	parent, parentCancel := context.WithCancel(context.Background())
+
+	ctx, cancel := c.WithTimeout(parent, time.Minute)
+	defer cancel()
+
+	parentCancel()
+
+	require.Eventually(t, func() bool { return ctx.Err() == context.Canceled }, time.Second, time.Millisecond)
+
+	// the deadline does not change the error once the context has been cancelled
+	<-c.After(time.Minute)
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestVirtualWithTimeoutWithNoDurationExpiresImmediately(t *testing.T) {
+	c := NewVirtual(time.Now())
+
+	ctx, cancel := c.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
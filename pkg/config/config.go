@@ -126,3 +126,27 @@ func KubernetesControllerPort() int {
 
 	return 19443
 }
+
+// PrometheusPresetsFile returns the path to a YAML or JSON file containing preset queries for the
+// Prometheus monitor, keyed by runtime and preset name
+func PrometheusPresetsFile() string {
+	return os.Getenv("PROMETHEUS_PRESETS_FILE")
+}
+
+// PrometheusPresetsConsulKVPath returns the Consul KV key containing preset queries for the Prometheus
+// monitor, the value has the same format as the presets file
+func PrometheusPresetsConsulKVPath() string {
+	return os.Getenv("PROMETHEUS_PRESETS_CONSUL_KV_PATH")
+}
+
+// DatadogPresetsFile returns the path to a YAML or JSON file containing preset queries for the
+// Datadog monitor, keyed by runtime and preset name
+func DatadogPresetsFile() string {
+	return os.Getenv("DATADOG_PRESETS_FILE")
+}
+
+// DatadogPresetsConsulKVPath returns the Consul KV key containing preset queries for the Datadog
+// monitor, the value has the same format as the presets file
+func DatadogPresetsConsulKVPath() string {
+	return os.Getenv("DATADOG_PRESETS_CONSUL_KV_PATH")
+}
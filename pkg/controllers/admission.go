@@ -9,6 +9,7 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/statemachine"
 )
 
 // AdmissionResponse is returned by the controller
@@ -109,6 +110,13 @@ func (a *AdmissionImpl) Check(ctx context.Context, name string, namespace string
 
 			// if the state of the release is inactive, update the config
 			if sm.CurrentState() == interfaces.StateIdle || sm.CurrentState() == interfaces.StateFail {
+				// reject the deployment when a release that it depends on has failed, when the dependencies
+				// have deployments in progress the deployment is admitted and waits in the pending state
+				_, err = statemachine.CheckDependencies(a.provider.GetDataStore(), rel)
+				if err != nil {
+					a.log.Debug("Reject deployment, dependencies for the release have failed", "name", name, "namespace", namespace, "release", rel.Name, "error", err)
+					return AdmissionRejected, fmt.Errorf("unable to deploy %s, %s", name, err)
+				}
 
 				// update the release candidate name so that the runtime plugin knows which deployment to clone
 				a.log.Debug("Fetch plugin state", "name", rel.Name)
@@ -127,8 +135,9 @@ func (a *AdmissionImpl) Check(ctx context.Context, name string, namespace string
 
 				// update the candidate name
 				// TODO, find a better way of updating the state than this
-				a.log.Debug("Set CandidateName to plugin state", "name", rel.Name, "candidate_name", name)
+				a.log.Debug("Set CandidateName to plugin state", "name", rel.Name, "candidate_name", name, "candidate_version", version)
 				ps.CandidateName = name
+				ps.CandidateVersion = version
 
 				confData, err := json.Marshal(ps)
 				if err != nil {
@@ -152,6 +161,13 @@ func (a *AdmissionImpl) Check(ctx context.Context, name string, namespace string
 					return AdmissionError, err
 				}
 
+				// deployments that are admitted outside of the release schedule are queued, the candidate is
+				// deployed but the release waits for the schedule before sending traffic to the candidate
+				err = rel.Schedule.Check(a.provider.GetClock().Now())
+				if err != nil {
+					a.log.Info("Deployment queued until the release schedule allows changes", "name", name, "namespace", namespace, "release", rel.Name, "reason", err)
+				}
+
 				// kick off a new deployment
 				err = sm.Deploy()
 				if err != nil {
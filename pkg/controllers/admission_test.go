@@ -74,10 +74,44 @@ func TestCallsDeployForNewDeploymentWhenIdle(t *testing.T) {
 	mm.StateMachineMock.AssertCalled(t, "Deploy")
 	mm.StoreMock.AssertCalled(t, "UpsertState", mock.Anything)
 
-	// check that the kubernetes deployment name is saved to the release config
+	// check that the kubernetes deployment name and version is saved to the release config
 	rbc := getUpsertReleaseState(&mm.StoreMock.Mock)
 	require.NotNil(t, rbc)
 	require.Equal(t, "test-deployment", rbc.CandidateName)
+	require.Equal(t, "2", rbc.CandidateVersion)
+}
+
+func TestCallsDeployForNewDeploymentOutsideSchedule(t *testing.T) {
+	d, mm := setupAdmission(t, "test-deployment", "default")
+
+	rels, _ := mm.StoreMock.ListReleases(&interfaces.ListOptions{Runtime: "kubernetes"})
+	rels[0].Schedule = &models.Schedule{Freezes: []models.ScheduleFreeze{{Start: "2000-01-01", End: "2100-01-01"}}}
+
+	resp, err := d.Check(context.TODO(), "test-deployment", "default", map[string]string{}, "2", "kubernetes")
+	require.Equal(t, resp, AdmissionGranted)
+	require.NoError(t, err)
+	mm.StateMachineMock.AssertCalled(t, "Deploy")
+}
+
+func TestReturnsRejectedWhenDependencyRolledBack(t *testing.T) {
+	d, mm := setupAdmission(t, "test-deployment", "default")
+
+	rels, _ := mm.StoreMock.ListReleases(&interfaces.ListOptions{Runtime: "kubernetes"})
+	rels[0].DependsOn = []string{"gateway"}
+
+	dep := &models.Release{Name: "gateway"}
+	dep.UpdateState(interfaces.StateIdle)
+
+	testutils.ClearMockCall(&mm.StoreMock.Mock, "GetRelease")
+	mm.StoreMock.On("GetRelease", "gateway").Return(dep, nil)
+
+	testutils.ClearMockCall(&mm.StoreMock.Mock, "ListDeployments")
+	mm.StoreMock.On("ListDeployments", "gateway").Return([]*models.Deployment{{Outcome: models.DeploymentOutcomeRolledBack}}, nil)
+
+	resp, err := d.Check(context.TODO(), "test-deployment", "default", map[string]string{}, "2", "kubernetes")
+	require.Equal(t, resp, AdmissionRejected)
+	require.Error(t, err)
+	mm.StateMachineMock.AssertNotCalled(t, "Deploy")
 }
 
 func TestReturnsErrorWhenNewDeploymentUpsertReleaseFails(t *testing.T) {
@@ -40,19 +40,23 @@ func (r *Release) ConvertToModel() *models.Release {
 		Config: getJSONRaw(spc),
 	}
 
-	mpq := []monitorQuerySnake{}
-	for _, q := range r.Spec.Monitor.Config.Queries {
-		mpq = append(mpq, monitorQuerySnake(q))
+	mr.Monitor = &models.PluginConfig{
+		Name:   r.Spec.Monitor.PluginName,
+		Config: getJSONRaw(getMonitorConfig(r.Spec.Monitor.Config)),
 	}
 
-	mpc := monitorConfigSnake{
-		Address: r.Spec.Monitor.Config.Address,
-		Queries: mpq,
+	for _, m := range r.Spec.Monitors {
+		mr.Monitors = append(mr.Monitors, &models.Monitor{
+			Name:       m.Name,
+			PluginName: m.PluginName,
+			Weight:     m.Weight,
+			Config:     getJSONRaw(getMonitorConfig(m.Config)),
+		})
 	}
 
-	mr.Monitor = &models.PluginConfig{
-		Name:   r.Spec.Monitor.PluginName,
-		Config: getJSONRaw(mpc),
+	if r.Spec.MonitorPolicy != nil {
+		mp := models.MonitorPolicy(*r.Spec.MonitorPolicy)
+		mr.MonitorPolicy = &mp
 	}
 
 	webhooks := []*models.PluginConfig{}
@@ -75,6 +79,39 @@ func (r *Release) ConvertToModel() *models.Release {
 		}
 	}
 
+	if r.Spec.Timeouts != nil {
+		to := models.Timeouts(*r.Spec.Timeouts)
+		mr.Timeouts = &to
+	}
+
+	if r.Spec.Schedule != nil {
+		sc := &models.Schedule{Timezone: r.Spec.Schedule.Timezone}
+
+		for _, w := range r.Spec.Schedule.Windows {
+			sc.Windows = append(sc.Windows, models.ScheduleWindow(w))
+		}
+
+		for _, f := range r.Spec.Schedule.Freezes {
+			sc.Freezes = append(sc.Freezes, models.ScheduleFreeze(f))
+		}
+
+		mr.Schedule = sc
+	}
+
+	mr.DependsOn = r.Spec.DependsOn
+
+	for _, g := range r.Spec.Gates {
+		gcs := gateConfigSnake(g.Config)
+		mr.Gates = append(mr.Gates, &models.Gate{
+			Name:       g.Name,
+			PluginName: g.PluginName,
+			Stage:      g.Stage,
+			Interval:   g.Interval,
+			Timeout:    g.Timeout,
+			Config:     getJSONRaw(gcs),
+		})
+	}
+
 	return mr
 }
 
@@ -83,12 +120,68 @@ func getJSONRaw(i interface{}) json.RawMessage {
 	return d
 }
 
+func getMonitorConfig(c MonitorConfig) monitorConfigSnake {
+	mpq := []monitorQuerySnake{}
+	for _, q := range c.Queries {
+		mq := monitorQuerySnake{
+			Name:       q.Name,
+			Preset:     q.Preset,
+			Min:        q.Min,
+			Max:        q.Max,
+			Quantile:   q.Quantile,
+			Query:      q.Query,
+			Evaluation: q.Evaluation,
+		}
+
+		if q.Compare != nil {
+			cs := comparisonSnake(*q.Compare)
+			mq.Compare = &cs
+		}
+
+		mpq = append(mpq, mq)
+	}
+
+	mc := monitorConfigSnake{
+		Address:                c.Address,
+		APIKeyFile:             c.APIKeyFile,
+		APIKeyEnv:              c.APIKeyEnv,
+		ApplicationKeyFile:     c.ApplicationKeyFile,
+		ApplicationKeyEnv:      c.ApplicationKeyEnv,
+		ConsulService:          c.ConsulService,
+		Headers:                c.Headers,
+		IgnoreCrashLoopBackOff: c.IgnoreCrashLoopBackOff,
+		IgnoreOOMKilled:        c.IgnoreOOMKilled,
+		MaxReadinessChanges:    c.MaxReadinessChanges,
+		MaxRestarts:            c.MaxRestarts,
+		Port:                   c.Port,
+		Path:                   c.Path,
+		Queries:                mpq,
+	}
+
+	if c.Auth != nil {
+		a := monitorAuthSnake(*c.Auth)
+		mc.Auth = &a
+	}
+
+	if c.TLS != nil {
+		t := monitorTLSSnake(*c.TLS)
+		mc.TLS = &t
+	}
+
+	return mc
+}
+
 type webhookConfigSnake struct {
-	ID       string   `json:"id"`
-	Token    string   `json:"token"`
-	URL      string   `json:"url"`
-	Template string   `json:"template"`
-	Status   []string `json:"status,omitempty"`
+	ID            string            `json:"id"`
+	Token         string            `json:"token"`
+	URL           string            `json:"url"`
+	Template      string            `json:"template"`
+	Status        []string          `json:"status,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Secret        string            `json:"secret,omitempty"`
+	Retries       *int              `json:"retries,omitempty"`
+	RetryInterval string            `json:"retry_interval,omitempty"`
+	Timeout       string            `json:"timeout,omitempty"`
 }
 
 type releaserConfigSnake struct {
@@ -103,25 +196,73 @@ type runtimeConfigSnake struct {
 }
 
 type strategyConfigSnake struct {
-	InitialDelay   string `json:"initial_delay,omitempty"`
-	Interval       string `json:"interval,omitempty"`
-	InitialTraffic int    `json:"initial_traffic,omitempty"`
-	TrafficStep    int    `json:"traffic_step,omitempty"`
-	MaxTraffic     int    `json:"max_traffic,omitempty"`
-	ErrorThreshold int    `json:"error_threshold,omitempty"`
+	InitialDelay    string  `json:"initial_delay,omitempty"`
+	Interval        string  `json:"interval,omitempty"`
+	InitialTraffic  int     `json:"initial_traffic,omitempty"`
+	TrafficStep     int     `json:"traffic_step,omitempty"`
+	MaxTraffic      int     `json:"max_traffic,omitempty"`
+	ErrorThreshold  int     `json:"error_threshold,omitempty"`
+	ManualPromotion bool    `json:"manual_promotion,omitempty"`
+	RollbackWindow  string  `json:"rollback_window,omitempty"`
+	RequiredChecks  int     `json:"required_checks,omitempty"`
+	Routes          []Route `json:"routes,omitempty"`
+	Steps           []Step  `json:"steps,omitempty"`
+	OnFailed        string  `json:"on_failed,omitempty"`
+	OnNoMetrics     string  `json:"on_no_metrics,omitempty"`
+	OnError         string  `json:"on_error,omitempty"`
+	MaxRetries      *int    `json:"max_retries,omitempty"`
 }
 
 type monitorConfigSnake struct {
-	Address string              `json:"address,omitempty"`
-	Queries []monitorQuerySnake `json:"queries,omitempty"`
+	Address                string              `json:"address,omitempty"`
+	APIKeyFile             string              `json:"api_key_file,omitempty"`
+	APIKeyEnv              string              `json:"api_key_env,omitempty"`
+	ApplicationKeyFile     string              `json:"application_key_file,omitempty"`
+	ApplicationKeyEnv      string              `json:"application_key_env,omitempty"`
+	Auth                   *monitorAuthSnake   `json:"auth,omitempty"`
+	ConsulService          string              `json:"consul_service,omitempty"`
+	Headers                map[string]string   `json:"headers,omitempty"`
+	IgnoreCrashLoopBackOff bool                `json:"ignore_crash_loop_back_off,omitempty"`
+	IgnoreOOMKilled        bool                `json:"ignore_oom_killed,omitempty"`
+	MaxReadinessChanges    *int                `json:"max_readiness_changes,omitempty"`
+	MaxRestarts            *int                `json:"max_restarts,omitempty"`
+	Port                   int                 `json:"port,omitempty"`
+	Path                   string              `json:"path,omitempty"`
+	TLS                    *monitorTLSSnake    `json:"tls,omitempty"`
+	Queries                []monitorQuerySnake `json:"queries,omitempty"`
+}
+
+type monitorAuthSnake struct {
+	BearerTokenFile string `json:"bearer_token_file,omitempty"`
+	BearerTokenEnv  string `json:"bearer_token_env,omitempty"`
+	Username        string `json:"username,omitempty"`
+	PasswordFile    string `json:"password_file,omitempty"`
+	PasswordEnv     string `json:"password_env,omitempty"`
+}
+
+type monitorTLSSnake struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
 }
 
 type monitorQuerySnake struct {
-	Name   string `json:"name,omitempty"`
-	Preset string `json:"preset,omitempty"`
-	Min    int    `json:"min,omitempty"`
-	Max    int    `json:"max,omitempty"`
-	Query  string `json:"query,omitempty"`
+	Name       string           `json:"name,omitempty"`
+	Preset     string           `json:"preset,omitempty"`
+	Min        *float64         `json:"min,omitempty"`
+	Max        *float64         `json:"max,omitempty"`
+	Quantile   *float64         `json:"quantile,omitempty"`
+	Query      string           `json:"query,omitempty"`
+	Evaluation string           `json:"evaluation,omitempty"`
+	Compare    *comparisonSnake `json:"compare,omitempty"`
+}
+
+type comparisonSnake struct {
+	Degradation string   `json:"degradation,omitempty"`
+	MaxAbsolute *float64 `json:"max_absolute,omitempty"`
+	MaxRelative *float64 `json:"max_relative,omitempty"`
 }
 
 type testConfigSnake struct {
@@ -132,3 +273,8 @@ type testConfigSnake struct {
 	Interval           string `json:"interval"`
 	Timeout            string `json:"timeout"`
 }
+
+type gateConfigSnake struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
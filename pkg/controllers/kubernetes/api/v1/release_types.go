@@ -72,8 +72,27 @@ type ReleaseSpec struct {
 	// Monitor defines the configuration for the strategy plugin
 	Monitor Monitor `json:"monitor,omitempty"`
 
+	// Monitors are combined using the MonitorPolicy to check the health of the candidate, when
+	// Monitors is set Monitor is not used
+	Monitors      []NamedMonitor `json:"monitors,omitempty"`
+	MonitorPolicy *MonitorPolicy `json:"monitorPolicy,omitempty"`
+
 	// PostDeploymentTest defines the configuration for the post deployment tests plugin
 	PostDeploymentTest Test `json:"postDeploymentTest,omitempty"`
+
+	// Timeouts defines the maximum duration for each state of the release
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+
+	// Schedule defines when the release can change the traffic sent to a candidate
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// DependsOn lists the releases that must complete their latest deployment before a new
+	// deployment for this release starts
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Gates are external approvals that must be granted before traffic is sent to the
+	// candidate or before the candidate is promoted
+	Gates []Gate `json:"gates,omitempty"`
 }
 
 type Webhook struct {
@@ -83,11 +102,16 @@ type Webhook struct {
 }
 
 type WebhookConfig struct {
-	ID       string   `json:"id,omitempty"`
-	Token    string   `json:"token,omitempty"`
-	URL      string   `json:"url,omitempty"`
-	Template string   `json:"template,omitempty"`
-	Status   []string `json:"status,omitempty"`
+	ID            string            `json:"id,omitempty"`
+	Token         string            `json:"token,omitempty"`
+	URL           string            `json:"url,omitempty"`
+	Template      string            `json:"template,omitempty"`
+	Status        []string          `json:"status,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Secret        string            `json:"secret,omitempty"`
+	Retries       *int              `json:"retries,omitempty"`
+	RetryInterval string            `json:"retryInterval,omitempty"`
+	Timeout       string            `json:"timeout,omitempty"`
 }
 
 type Releaser struct {
@@ -117,12 +141,33 @@ type Strategy struct {
 }
 
 type StrategyConfig struct {
-	InitialDelay   string `json:"initialDelay,omitempty"`
-	Interval       string `json:"interval,omitempty"`
-	InitialTraffic int    `json:"initialTraffic,omitempty"`
-	TrafficStep    int    `json:"trafficStep,omitempty"`
-	MaxTraffic     int    `json:"maxTraffic,omitempty"`
-	ErrorThreshold int    `json:"errorThreshold,omitempty"`
+	InitialDelay    string  `json:"initialDelay,omitempty"`
+	Interval        string  `json:"interval,omitempty"`
+	InitialTraffic  int     `json:"initialTraffic,omitempty"`
+	TrafficStep     int     `json:"trafficStep,omitempty"`
+	MaxTraffic      int     `json:"maxTraffic,omitempty"`
+	ErrorThreshold  int     `json:"errorThreshold,omitempty"`
+	ManualPromotion bool    `json:"manualPromotion,omitempty"`
+	RollbackWindow  string  `json:"rollbackWindow,omitempty"`
+	RequiredChecks  int     `json:"requiredChecks,omitempty"`
+	Routes          []Route `json:"routes,omitempty"`
+	Steps           []Step  `json:"steps,omitempty"`
+	OnFailed        string  `json:"onFailed,omitempty"`
+	OnNoMetrics     string  `json:"onNoMetrics,omitempty"`
+	OnError         string  `json:"onError,omitempty"`
+	MaxRetries      *int    `json:"maxRetries,omitempty"`
+}
+
+type Step struct {
+	Traffic int    `json:"traffic"`
+	Hold    string `json:"hold"`
+}
+
+type Route struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Exact string `json:"exact,omitempty"`
+	Regex string `json:"regex,omitempty"`
 }
 
 type Monitor struct {
@@ -130,17 +175,68 @@ type Monitor struct {
 	Config     MonitorConfig `json:"config"`
 }
 
+type NamedMonitor struct {
+	Name       string        `json:"name"`
+	PluginName string        `json:"pluginName"`
+	Weight     int           `json:"weight,omitempty"`
+	Config     MonitorConfig `json:"config"`
+}
+
+type MonitorPolicy struct {
+	Type      string `json:"type,omitempty"`
+	Threshold int    `json:"threshold,omitempty"`
+}
+
 type MonitorConfig struct {
-	Address string  `json:"address"`
-	Queries []Query `json:"queries,omitempty"`
+	Address                string            `json:"address,omitempty"`
+	APIKeyFile             string            `json:"apiKeyFile,omitempty"`
+	APIKeyEnv              string            `json:"apiKeyEnv,omitempty"`
+	ApplicationKeyFile     string            `json:"applicationKeyFile,omitempty"`
+	ApplicationKeyEnv      string            `json:"applicationKeyEnv,omitempty"`
+	Auth                   *MonitorAuth      `json:"auth,omitempty"`
+	ConsulService          string            `json:"consulService,omitempty"`
+	Headers                map[string]string `json:"headers,omitempty"`
+	IgnoreCrashLoopBackOff bool              `json:"ignoreCrashLoopBackOff,omitempty"`
+	IgnoreOOMKilled        bool              `json:"ignoreOOMKilled,omitempty"`
+	MaxReadinessChanges    *int              `json:"maxReadinessChanges,omitempty"`
+	MaxRestarts            *int              `json:"maxRestarts,omitempty"`
+	Port                   int               `json:"port,omitempty"`
+	Path                   string            `json:"path,omitempty"`
+	TLS                    *MonitorTLS       `json:"tls,omitempty"`
+	Queries                []Query           `json:"queries,omitempty"`
+}
+
+type MonitorAuth struct {
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+	BearerTokenEnv  string `json:"bearerTokenEnv,omitempty"`
+	Username        string `json:"username,omitempty"`
+	PasswordFile    string `json:"passwordFile,omitempty"`
+	PasswordEnv     string `json:"passwordEnv,omitempty"`
+}
+
+type MonitorTLS struct {
+	CAFile             string `json:"caFile,omitempty"`
+	CertFile           string `json:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty"`
+	ServerName         string `json:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
 }
 
 type Query struct {
-	Name   string `json:"name,omitempty"`
-	Preset string `json:"preset,omitempty"`
-	Min    int    `json:"min,omitempty"`
-	Max    int    `json:"max,omitempty"`
-	Query  string `json:"query,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	Preset     string      `json:"preset,omitempty"`
+	Min        *float64    `json:"min,omitempty"`
+	Max        *float64    `json:"max,omitempty"`
+	Quantile   *float64    `json:"quantile,omitempty"`
+	Query      string      `json:"query,omitempty"`
+	Evaluation string      `json:"evaluation,omitempty"`
+	Compare    *Comparison `json:"compare,omitempty"`
+}
+
+type Comparison struct {
+	Degradation string   `json:"degradation,omitempty"`
+	MaxAbsolute *float64 `json:"maxAbsolute,omitempty"`
+	MaxRelative *float64 `json:"maxRelative,omitempty"`
 }
 
 type Test struct {
@@ -157,6 +253,48 @@ type TestConfig struct {
 	Timeout            string `json:"timeout"`
 }
 
+type Gate struct {
+	Name       string     `json:"name"`
+	PluginName string     `json:"pluginName"`
+	Stage      string     `json:"stage,omitempty"`
+	Interval   string     `json:"interval,omitempty"`
+	Timeout    string     `json:"timeout,omitempty"`
+	Config     GateConfig `json:"config"`
+}
+
+type GateConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type Timeouts struct {
+	Configure string `json:"configure,omitempty"`
+	Deploy    string `json:"deploy,omitempty"`
+	Monitor   string `json:"monitor,omitempty"`
+	Scale     string `json:"scale,omitempty"`
+	Promote   string `json:"promote,omitempty"`
+	Rollback  string `json:"rollback,omitempty"`
+	Destroy   string `json:"destroy,omitempty"`
+}
+
+type Schedule struct {
+	Timezone string           `json:"timezone,omitempty"`
+	Windows  []ScheduleWindow `json:"windows,omitempty"`
+	Freezes  []ScheduleFreeze `json:"freezes,omitempty"`
+}
+
+type ScheduleWindow struct {
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+type ScheduleFreeze struct {
+	Name  string `json:"name,omitempty"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
 func init() {
 	SchemeBuilder.Register(&Release{}, &ReleaseList{})
 }
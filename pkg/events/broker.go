@@ -0,0 +1,71 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+// subscriberBuffer is the number of events that are buffered for each subscriber before
+// events are dropped
+var subscriberBuffer = 100
+
+// Broker is an in memory implementation of the Events interface
+type Broker struct {
+	m           sync.Mutex
+	nextID      int
+	subscribers map[int]*subscriber
+}
+
+type subscriber struct {
+	name   string
+	events chan interfaces.ReleaseEvent
+}
+
+// NewBroker creates a new Broker with no subscribers
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[int]*subscriber{}}
+}
+
+// Publish sends the event to the subscribers for the release and the subscribers for all releases
+func (b *Broker) Publish(e interfaces.ReleaseEvent) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for _, s := range b.subscribers {
+		if s.name != "" && s.name != e.Release {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			// the subscriber is not keeping up, drop the event rather than block the release
+		}
+	}
+}
+
+// Subscribe returns a channel that receives the events for the named release, or all releases
+// when name is empty
+func (b *Broker) Subscribe(name string) (<-chan interfaces.ReleaseEvent, func()) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	id := b.nextID
+	b.nextID++
+
+	s := &subscriber{name: name, events: make(chan interfaces.ReleaseEvent, subscriberBuffer)}
+	b.subscribers[id] = s
+
+	once := sync.Once{}
+
+	return s.events, func() {
+		once.Do(func() {
+			b.m.Lock()
+			defer b.m.Unlock()
+
+			delete(b.subscribers, id)
+			close(s.events)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeReceivesEventsForRelease(t *testing.T) {
+	b := NewBroker()
+
+	c, cancel := b.Subscribe("api")
+	defer cancel()
+
+	b.Publish(interfaces.ReleaseEvent{Release: "web", Type: interfaces.ReleaseEventState})
+	b.Publish(interfaces.ReleaseEvent{Release: "api", Type: interfaces.ReleaseEventTraffic})
+
+	e := <-c
+	require.Equal(t, "api", e.Release)
+	require.Equal(t, interfaces.ReleaseEventTraffic, e.Type)
+	require.Len(t, c, 0)
+}
+
+func TestSubscribeWithoutNameReceivesAllEvents(t *testing.T) {
+	b := NewBroker()
+
+	c, cancel := b.Subscribe("")
+	defer cancel()
+
+	b.Publish(interfaces.ReleaseEvent{Release: "web"})
+	b.Publish(interfaces.ReleaseEvent{Release: "api"})
+
+	require.Len(t, c, 2)
+}
+
+func TestPublishDropsEventsWhenSubscriberFull(t *testing.T) {
+	subscriberBuffer = 1
+	b := NewBroker()
+
+	c, cancel := b.Subscribe("api")
+	defer cancel()
+
+	b.Publish(interfaces.ReleaseEvent{Release: "api", Message: "one"})
+	b.Publish(interfaces.ReleaseEvent{Release: "api", Message: "two"})
+
+	e := <-c
+	require.Equal(t, "one", e.Message)
+	require.Len(t, c, 0)
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	b := NewBroker()
+
+	c, cancel := b.Subscribe("api")
+	cancel()
+	cancel()
+
+	_, ok := <-c
+	require.False(t, ok)
+
+	// publishing after unsubscribe does not panic
+	b.Publish(interfaces.ReleaseEvent{Release: "api"})
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DeploymentOutcomeInProgress is the outcome of a deployment that has not yet finished
+	DeploymentOutcomeInProgress = "in_progress"
+	// DeploymentOutcomePromoted is the outcome when the candidate was promoted to the primary
+	DeploymentOutcomePromoted = "promoted"
+	// DeploymentOutcomeRolledBack is the outcome when the candidate failed its checks and was rolled back
+	DeploymentOutcomeRolledBack = "rolled_back"
+	// DeploymentOutcomeAborted is the outcome when the release was aborted and the candidate was rolled back
+	DeploymentOutcomeAborted = "aborted"
+	// DeploymentOutcomeFailed is the outcome when the deployment failed with an error
+	DeploymentOutcomeFailed = "failed"
+	// DeploymentOutcomeTimedOut is the outcome when a state of the deployment did not complete within its timeout
+	DeploymentOutcomeTimedOut = "timed_out"
+	// DeploymentOutcomeDestroyed is the outcome when the release was removed before the deployment finished
+	DeploymentOutcomeDestroyed = "destroyed"
+)
+
+// MaxDeployments is the number of deployment records that are kept for a release, the oldest
+// records are removed when a new deployment is added to the history
+const MaxDeployments = 50
+
+// Deployment is the record of a single roll out of a candidate for a release
+type Deployment struct {
+	// ID uniquely identifies the deployment for the release, ids sort in the order
+	// that the deployments were started
+	ID string `json:"id"`
+
+	CandidateName    string `json:"candidate_name"`
+	CandidateVersion string `json:"candidate_version"`
+
+	Started time.Time `json:"started"`
+	Ended   time.Time `json:"ended"`
+
+	Outcome       string `json:"outcome"`
+	FailureReason string `json:"failure_reason,omitempty"`
+
+	// TrafficSteps are the traffic splits that were sent to the candidate during the roll out
+	TrafficSteps []TrafficStep `json:"traffic_steps"`
+}
+
+// TrafficStep is the percentage of traffic sent to the candidate at a point in time
+type TrafficStep struct {
+	Time    time.Time `json:"time"`
+	Traffic int       `json:"traffic"`
+}
+
+// NewDeployment creates a new in progress deployment for the given candidate that was started at now
+func NewDeployment(candidateName, candidateVersion string, now time.Time) *Deployment {
+	return &Deployment{
+		ID:               fmt.Sprintf("%020d", now.UnixNano()),
+		CandidateName:    candidateName,
+		CandidateVersion: candidateVersion,
+		Started:          now,
+		Outcome:          DeploymentOutcomeInProgress,
+		TrafficSteps:     []TrafficStep{},
+	}
+}
+
+// AddTrafficStep records that the given percentage of traffic was sent to the candidate at now
+func (d *Deployment) AddTrafficStep(traffic int, now time.Time) {
+	d.TrafficSteps = append(d.TrafficSteps, TrafficStep{Time: now, Traffic: traffic})
+}
+
+// End sets the outcome of the deployment that ended at now, err is recorded as the failure
+// reason when not nil
+func (d *Deployment) End(outcome string, err error, now time.Time) {
+	d.Ended = now
+	d.Outcome = outcome
+
+	if err != nil {
+		d.FailureReason = err.Error()
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// GateStageTraffic gates are evaluated before any traffic is sent to the candidate
+	GateStageTraffic = "before_traffic"
+	// GateStagePromote gates are evaluated before the candidate is promoted
+	GateStagePromote = "before_promote"
+)
+
+// Gate is an external approval that must be granted before the release can continue
+type Gate struct {
+	// Name identifies the gate, decisions sent to the API use the name to select the gate
+	Name string `json:"name"`
+
+	// PluginName is the name of the gate plugin
+	PluginName string `json:"plugin_name"`
+
+	// Stage is the point in the release at which the gate is evaluated, either before_traffic
+	// or before_promote, defaults to before_promote
+	Stage string `json:"stage,omitempty"`
+
+	// Interval between checks of a pending gate, defaults to 30s
+	Interval string `json:"interval,omitempty"`
+
+	// Timeout is the maximum duration to wait for the gate to be approved, a gate that has not
+	// been approved before the timeout rejects the release. When not set the release waits
+	// until the gate is approved or rejected
+	Timeout string `json:"timeout,omitempty"`
+
+	Config json.RawMessage `json:"config"`
+}
+
+// GetStage returns the stage of the gate, gates without a stage are evaluated before promotion
+func (g *Gate) GetStage() string {
+	if g.Stage == "" {
+		return GateStagePromote
+	}
+
+	return g.Stage
+}
+
+// Validate returns an error if the gate does not have a name and plugin, or contains an
+// invalid stage, interval, or timeout
+func (g *Gate) Validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("gate must have a name")
+	}
+
+	if g.PluginName == "" {
+		return fmt.Errorf("gate %s must have a plugin_name", g.Name)
+	}
+
+	if g.GetStage() != GateStageTraffic && g.GetStage() != GateStagePromote {
+		return fmt.Errorf("gate %s has an invalid stage %s, please specify one of %s, %s", g.Name, g.Stage, GateStageTraffic, GateStagePromote)
+	}
+
+	durations := []struct{ name, value string }{
+		{"interval", g.Interval},
+		{"timeout", g.Timeout},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		_, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("gate %s %s is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)", g.Name, d.name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateGetStageDefaultsToPromote(t *testing.T) {
+	g := &Gate{Name: "cab", PluginName: "http"}
+
+	require.NoError(t, g.Validate())
+	require.Equal(t, GateStagePromote, g.GetStage())
+}
+
+func TestGateValidateReturnsErrorWhenInvalid(t *testing.T) {
+	tests := map[string]*Gate{
+		"name":     {PluginName: "http"},
+		"plugin":   {Name: "cab"},
+		"stage":    {Name: "cab", PluginName: "http", Stage: "before_lunch"},
+		"interval": {Name: "cab", PluginName: "http", Interval: "often"},
+		"timeout":  {Name: "cab", PluginName: "http", Timeout: "1 day"},
+	}
+
+	for name, g := range tests {
+		require.Error(t, g.Validate(), name)
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// MonitorPolicyAll requires every monitor to pass
+	MonitorPolicyAll = "all"
+	// MonitorPolicyAny requires at least one monitor to pass
+	MonitorPolicyAny = "any"
+	// MonitorPolicyWeighted requires the total weight of the passing monitors to reach a threshold
+	MonitorPolicyWeighted = "weighted"
+)
+
+// Monitor is one of a number of monitors used to check the health of the candidate
+type Monitor struct {
+	// Name identifies the monitor in logs and webhooks
+	Name string `json:"name"`
+
+	// PluginName is the name of the monitor plugin
+	PluginName string `json:"plugin_name"`
+
+	// Weight of the monitor when using the weighted policy, defaults to 1
+	Weight int `json:"weight,omitempty"`
+
+	Config json.RawMessage `json:"config"`
+}
+
+// GetWeight returns the weight of the monitor, monitors without a weight have a weight of 1
+func (m *Monitor) GetWeight() int {
+	if m.Weight == 0 {
+		return 1
+	}
+
+	return m.Weight
+}
+
+// Validate returns an error if the monitor does not have a name and plugin, or has a negative weight
+func (m *Monitor) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("monitor must have a name")
+	}
+
+	if m.PluginName == "" {
+		return fmt.Errorf("monitor %s must have a plugin_name", m.Name)
+	}
+
+	if m.Weight < 0 {
+		return fmt.Errorf("monitor %s has a negative weight, weight must be greater than 0", m.Name)
+	}
+
+	return nil
+}
+
+// MonitorPolicy defines how the results of multiple monitors are combined
+type MonitorPolicy struct {
+	// Type of the policy, either all, any, or weighted, defaults to all
+	Type string `json:"type,omitempty"`
+
+	// Threshold is the minimum percentage of the total weight of all monitors that must pass
+	// when using the weighted policy
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// GetType returns the type of the policy, when no policy is set every monitor must pass
+func (p *MonitorPolicy) GetType() string {
+	if p == nil || p.Type == "" {
+		return MonitorPolicyAll
+	}
+
+	return p.Type
+}
+
+// Validate returns an error if the policy type is not valid, or the weighted policy does not have
+// a threshold between 1 and 100
+func (p *MonitorPolicy) Validate() error {
+	switch p.GetType() {
+	case MonitorPolicyAll, MonitorPolicyAny:
+		return nil
+	case MonitorPolicyWeighted:
+		if p.Threshold < 1 || p.Threshold > 100 {
+			return fmt.Errorf("weighted monitor policy must have a threshold between 1 and 100")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("invalid monitor policy %s, please specify one of %s, %s, %s", p.Type, MonitorPolicyAll, MonitorPolicyAny, MonitorPolicyWeighted)
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitorPolicyDefaultsToAll(t *testing.T) {
+	var p *MonitorPolicy
+
+	require.NoError(t, p.Validate())
+	require.Equal(t, MonitorPolicyAll, p.GetType())
+}
+
+func TestMonitorPolicyValidateReturnsErrorWhenInvalid(t *testing.T) {
+	tests := map[string]*MonitorPolicy{
+		"type":      {Type: "most"},
+		"threshold": {Type: MonitorPolicyWeighted, Threshold: 101},
+	}
+
+	for name, p := range tests {
+		require.Error(t, p.Validate(), name)
+	}
+}
+
+func TestMonitorValidateReturnsErrorWhenInvalid(t *testing.T) {
+	tests := map[string]*Monitor{
+		"name":   {PluginName: "prometheus"},
+		"plugin": {Name: "latency"},
+		"weight": {Name: "latency", PluginName: "prometheus", Weight: -1},
+	}
+
+	for name, m := range tests {
+		require.Error(t, m.Validate(), name)
+	}
+}
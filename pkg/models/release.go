@@ -23,6 +23,28 @@ type Release struct {
 	Webhooks           []*PluginConfig `json:"webhooks"`
 	PostDeploymentTest *PluginConfig   `json:"post_deployment_test"`
 
+	// Monitors are combined using the MonitorPolicy to check the health of the candidate, when
+	// Monitors is set Monitor is not used
+	Monitors      []*Monitor     `json:"monitors,omitempty"`
+	MonitorPolicy *MonitorPolicy `json:"monitor_policy,omitempty"`
+
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+
+	// Schedule restricts when the release can change the traffic sent to a candidate
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// DependsOn lists the names of releases that must have successfully completed their latest
+	// deployment before a new deployment for this release starts
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Gates are external approvals that must be granted before traffic is sent to the candidate
+	// or before the candidate is promoted
+	Gates []*Gate `json:"gates,omitempty"`
+
+	// Deployment is the record for the deployment that is currently in progress, once the deployment
+	// finishes the record is added to the deployment history in the data store
+	Deployment *Deployment `json:"deployment,omitempty"`
+
 	Statehistory []StateHistory `json:"state_history"`
 }
 
@@ -32,6 +54,56 @@ type StateHistory struct {
 	State string    `json:"state"`
 }
 
+// Timeouts defines the maximum time that the release can spend performing the work for a state,
+// values are specified using Go duration format e.g (30s, 10m, 1h). States without a timeout use the
+// default timeout of 30 minutes
+type Timeouts struct {
+	Configure string `json:"configure,omitempty"`
+	Deploy    string `json:"deploy,omitempty"`
+	// Monitor is the timeout for a single execution of the strategy, it must be longer than the
+	// time the strategy takes to check the candidate
+	Monitor string `json:"monitor,omitempty"`
+	// Scale is the timeout for scaling traffic, including scaling traffic while awaiting promotion
+	Scale    string `json:"scale,omitempty"`
+	Promote  string `json:"promote,omitempty"`
+	Rollback string `json:"rollback,omitempty"`
+	Destroy  string `json:"destroy,omitempty"`
+}
+
+// Validate returns an error if any of the timeouts are not a valid duration greater than zero
+func (t *Timeouts) Validate() error {
+	if t == nil {
+		return nil
+	}
+
+	timeouts := []struct{ name, value string }{
+		{"configure", t.Configure},
+		{"deploy", t.Deploy},
+		{"monitor", t.Monitor},
+		{"scale", t.Scale},
+		{"promote", t.Promote},
+		{"rollback", t.Rollback},
+		{"destroy", t.Destroy},
+	}
+
+	for _, to := range timeouts {
+		if to.value == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(to.value)
+		if err != nil {
+			return fmt.Errorf("timeout %s is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)", to.name)
+		}
+
+		if d <= 0 {
+			return fmt.Errorf("timeout %s must be greater than zero", to.name)
+		}
+	}
+
+	return nil
+}
+
 type PluginConfig struct {
 	Name   string          `json:"plugin_name"`
 	Config json.RawMessage `json:"config"`
@@ -37,3 +37,31 @@ func TestStateHistoryLimitedTo50Items(t *testing.T) {
 
 	require.Len(t, r.StateHistory(), 50)
 }
+
+func TestTimeoutsValidateReturnsNoErrorWhenNil(t *testing.T) {
+	var to *Timeouts
+
+	require.NoError(t, to.Validate())
+}
+
+func TestTimeoutsValidateReturnsErrorWhenInvalid(t *testing.T) {
+	to := &Timeouts{Deploy: "10m", Monitor: "abc"}
+
+	err := to.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timeout monitor")
+}
+
+func TestTimeoutsValidateReturnsErrorWhenNotGreaterThanZero(t *testing.T) {
+	to := &Timeouts{Deploy: "0s"}
+
+	err := to.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timeout deploy must be greater than zero")
+
+	to = &Timeouts{Rollback: "-5m"}
+
+	err = to.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timeout rollback must be greater than zero")
+}
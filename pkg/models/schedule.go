@@ -0,0 +1,230 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// embed the timezone database so that schedules work in containers without zoneinfo
+	_ "time/tzdata"
+)
+
+const dateFormat = "2006-01-02"
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// Schedule defines when a release is allowed to change the traffic sent to a candidate.
+// When a schedule does not define any windows changes are allowed at any time outside of
+// the freezes
+type Schedule struct {
+	// Timezone is the IANA name of the timezone for the windows and date only freezes,
+	// e.g. Europe/London, defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
+
+	Windows []ScheduleWindow `json:"windows,omitempty"`
+	Freezes []ScheduleFreeze `json:"freezes,omitempty"`
+}
+
+// ScheduleWindow is a period on the given days when changes are allowed
+type ScheduleWindow struct {
+	// Days the window applies to e.g. monday, tuesday, when empty the window applies every day
+	Days []string `json:"days,omitempty"`
+
+	// Start and End are the times of day in 24 hour format e.g 09:00, 17:30, End
+	// is not included in the window and can be 24:00 for the end of the day
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// ScheduleFreeze is a period when no changes are allowed
+type ScheduleFreeze struct {
+	Name string `json:"name,omitempty"`
+
+	// Start and End are either RFC3339 times e.g. 2022-12-23T17:00:00Z, or dates e.g. 2022-12-23
+	// in the schedule timezone, an End date is included in the freeze
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Validate returns an error if the schedule contains an invalid timezone, window, or freeze
+func (s *Schedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return err
+	}
+
+	for i, w := range s.Windows {
+		for _, d := range w.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("schedule window %d has an invalid day %s", i, d)
+			}
+		}
+
+		start, err := parseTimeOfDay(w.Start)
+		if err != nil {
+			return fmt.Errorf("schedule window %d has an invalid start: %s", i, err)
+		}
+
+		end, err := parseTimeOfDay(w.End)
+		if err != nil {
+			return fmt.Errorf("schedule window %d has an invalid end: %s", i, err)
+		}
+
+		if end <= start {
+			return fmt.Errorf("schedule window %d must end after it starts, windows that span midnight must be defined as two windows", i)
+		}
+	}
+
+	for i, f := range s.Freezes {
+		start, end, err := f.period(loc)
+		if err != nil {
+			return fmt.Errorf("schedule freeze %d %s", i, err)
+		}
+
+		if !end.After(start) {
+			return fmt.Errorf("schedule freeze %d must end after it starts", i)
+		}
+	}
+
+	return nil
+}
+
+// Check returns nil when changes are allowed at the given time, when changes are not
+// allowed an error describing the reason is returned
+func (s *Schedule) Check(t time.Time) error {
+	if s == nil {
+		return nil
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return err
+	}
+
+	t = t.In(loc)
+
+	for _, f := range s.Freezes {
+		start, end, err := f.period(loc)
+		if err != nil {
+			return fmt.Errorf("freeze %s %s", f.Name, err)
+		}
+
+		if !t.Before(start) && t.Before(end) {
+			return fmt.Errorf("change freeze %s is active until %s", f.Name, end.Format(time.RFC3339))
+		}
+	}
+
+	if len(s.Windows) == 0 {
+		return nil
+	}
+
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	for _, w := range s.Windows {
+		if !w.appliesTo(t.Weekday()) {
+			continue
+		}
+
+		start, err := parseTimeOfDay(w.Start)
+		if err != nil {
+			return err
+		}
+
+		end, err := parseTimeOfDay(w.End)
+		if err != nil {
+			return err
+		}
+
+		if tod >= start && tod < end {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is outside of the deployment windows", t.Format(time.RFC3339))
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("schedule timezone %s is not a valid IANA timezone", s.Timezone)
+	}
+
+	return loc, nil
+}
+
+func (w *ScheduleWindow) appliesTo(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// period returns the start and end time of the freeze in the given location
+func (f *ScheduleFreeze) period(loc *time.Location) (time.Time, time.Time, error) {
+	start, _, err := parseFreezeTime(f.Start, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("has an invalid start: %s", err)
+	}
+
+	end, dateOnly, err := parseFreezeTime(f.End, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("has an invalid end: %s", err)
+	}
+
+	// end dates include the whole day
+	if dateOnly {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return start, end, nil
+}
+
+// parseFreezeTime parses a RFC3339 time or a date in the given location, the returned
+// bool is true when the value is a date
+func parseFreezeTime(v string, loc *time.Location) (time.Time, bool, error) {
+	t, err := time.ParseInLocation(dateFormat, v, loc)
+	if err == nil {
+		return t, true, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s is not a RFC3339 time or a date e.g. 2022-12-23", v)
+	}
+
+	return t, false, nil
+}
+
+// parseTimeOfDay parses a 24 hour time HH:MM and returns the duration since midnight
+func parseTimeOfDay(v string) (time.Duration, error) {
+	var h, m int
+	_, err := fmt.Sscanf(v, "%d:%d", &h, &m)
+	if err != nil || len(v) != 5 || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%s is not a time in 24 hour format e.g 09:00", v)
+	}
+
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
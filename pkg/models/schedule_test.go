@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupSchedule(t *testing.T) *Schedule {
+	s := &Schedule{
+		Timezone: "Europe/London",
+		Windows: []ScheduleWindow{
+			{Days: []string{"monday", "tuesday", "wednesday", "thursday"}, Start: "09:00", End: "17:00"},
+			{Days: []string{"Friday"}, Start: "09:00", End: "12:00"},
+		},
+		Freezes: []ScheduleFreeze{
+			{Name: "holidays", Start: "2022-12-23", End: "2022-12-27"},
+		},
+	}
+
+	require.NoError(t, s.Validate())
+
+	return s
+}
+
+func parseTime(t *testing.T, v string) time.Time {
+	tm, err := time.Parse(time.RFC3339, v)
+	require.NoError(t, err)
+
+	return tm
+}
+
+func TestScheduleValidateReturnsNoErrorWhenNil(t *testing.T) {
+	var s *Schedule
+
+	require.NoError(t, s.Validate())
+	require.NoError(t, s.Check(time.Now()))
+}
+
+func TestScheduleValidateReturnsErrorWhenInvalid(t *testing.T) {
+	tests := map[string]*Schedule{
+		"timezone": {Timezone: "Mars/Olympus"},
+		"day":      {Windows: []ScheduleWindow{{Days: []string{"funday"}, Start: "09:00", End: "17:00"}}},
+		"start":    {Windows: []ScheduleWindow{{Start: "9am", End: "17:00"}}},
+		"end":      {Windows: []ScheduleWindow{{Start: "09:00", End: "25:00"}}},
+		"order":    {Windows: []ScheduleWindow{{Start: "22:00", End: "06:00"}}},
+		"freeze":   {Freezes: []ScheduleFreeze{{Start: "christmas", End: "2022-12-27"}}},
+		"period":   {Freezes: []ScheduleFreeze{{Start: "2022-12-27T00:00:00Z", End: "2022-12-23T00:00:00Z"}}},
+	}
+
+	for name, s := range tests {
+		require.Error(t, s.Validate(), name)
+	}
+}
+
+func TestScheduleCheckAllowsChangesInsideWindow(t *testing.T) {
+	s := setupSchedule(t)
+
+	// thursday 10:00 BST
+	require.NoError(t, s.Check(parseTime(t, "2022-06-02T09:00:00Z")))
+}
+
+func TestScheduleCheckUsesTimezone(t *testing.T) {
+	s := setupSchedule(t)
+
+	// friday 08:30 UTC is 09:30 BST
+	require.NoError(t, s.Check(parseTime(t, "2022-06-03T08:30:00Z")))
+
+	// friday 11:30 UTC is 12:30 BST
+	require.Error(t, s.Check(parseTime(t, "2022-06-03T11:30:00Z")))
+}
+
+func TestScheduleCheckReturnsErrorOutsideWindows(t *testing.T) {
+	s := setupSchedule(t)
+
+	// saturday
+	err := s.Check(parseTime(t, "2022-06-04T10:00:00Z"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "outside of the deployment windows")
+}
+
+func TestScheduleCheckReturnsErrorDuringFreeze(t *testing.T) {
+	s := setupSchedule(t)
+
+	// tuesday inside the window but the end date of the freeze is included
+	err := s.Check(parseTime(t, "2022-12-27T11:00:00Z"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "change freeze holidays")
+
+	require.NoError(t, s.Check(parseTime(t, "2022-12-28T11:00:00Z")))
+}
+
+func TestScheduleCheckWithoutWindowsOnlyAppliesFreezes(t *testing.T) {
+	s := &Schedule{Freezes: []ScheduleFreeze{{Start: "2022-12-23T17:00:00Z", End: "2022-12-24T00:00:00Z"}}}
+
+	require.NoError(t, s.Check(parseTime(t, "2022-12-23T16:59:00Z")))
+	require.Error(t, s.Check(parseTime(t, "2022-12-23T17:00:00Z")))
+}
@@ -0,0 +1,271 @@
+package abtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+type Plugin struct {
+	log        hclog.Logger
+	config     *PluginConfig
+	store      interfaces.PluginStateStore
+	monitoring interfaces.Monitor
+	state      *PluginState
+	clock      interfaces.Clock
+}
+
+type PluginState struct {
+	CandidateTraffic int    `json:"candidate_traffic"`
+	Status           string `json:"status"`
+	// SuccessfulChecks is the number of checks that have passed since routing started
+	SuccessfulChecks int `json:"successful_checks"`
+}
+
+type PluginConfig struct {
+	// InitialDelay before routing requests to the candidate
+	InitialDelay string `hcl:"initial_delay,optional" json:"initial_delay,omitempty" validate:"duration"`
+	// Interval between checks
+	Interval string `hcl:"interval" json:"interval" validate:"required,duration"`
+	// Routes that select the requests sent to the candidate, a request that matches any route
+	// is sent to the candidate, all other requests are sent to the primary
+	Routes []interfaces.RouteMatch `hcl:"routes,block" json:"routes" validate:"required,min=1,dive"`
+	// RequiredChecks is the number of successful checks before the candidate is promoted
+	RequiredChecks int `hcl:"required_checks" json:"required_checks" validate:"required,gte=1"`
+	// ErrorThreshold is the number of consecutive failed checks before rolling back
+	ErrorThreshold int `hcl:"error_threshold" json:"error_threshold" validate:"required,gte=1"`
+}
+
+var ErrInvalidInitialDelay = fmt.Errorf("InitialDelay is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
+var ErrInvalidInterval = fmt.Errorf("Interval is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
+var ErrInvalidRoutes = fmt.Errorf("Routes must contain at least one route with a type of header, cookie, or query and a name")
+var ErrRequiredChecks = fmt.Errorf("RequiredChecks must contain a value greater than 0")
+var ErrThreshold = fmt.Errorf("ErrorThreshold must contain a value greater than 0")
+
+func New(m interfaces.Monitor, c interfaces.Clock) (*Plugin, error) {
+	return &Plugin{monitoring: m, clock: c}, nil
+}
+
+// Configure the plugin with the given json
+// returns an error when validation fails for the config
+func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	p.log = log
+	p.store = store
+	p.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, p.config)
+	if err != nil {
+		return err
+	}
+
+	// if no initial delay use the interval
+	if p.config.InitialDelay == "" {
+		p.config.InitialDelay = p.config.Interval
+	}
+
+	// validate the plugin config
+	validate := validator.New()
+	validate.RegisterValidation("duration", interfaces.ValidateDuration)
+	err = validate.Struct(p.config)
+
+	if err != nil {
+		errorMessage := ""
+		routesInvalid := false
+
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.InitialDelay":
+				errorMessage += ErrInvalidInitialDelay.Error() + "\n"
+			case "PluginConfig.Interval":
+				errorMessage += ErrInvalidInterval.Error() + "\n"
+			case "PluginConfig.RequiredChecks":
+				errorMessage += ErrRequiredChecks.Error() + "\n"
+			case "PluginConfig.ErrorThreshold":
+				errorMessage += ErrThreshold.Error() + "\n"
+			default:
+				// only report invalid routes once regardless of how many routes fail validation
+				if strings.HasPrefix(err.Namespace(), "PluginConfig.Routes") && !routesInvalid {
+					routesInvalid = true
+					errorMessage += ErrInvalidRoutes.Error() + "\n"
+				}
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	// load the state
+	p.state = &PluginState{}
+	d, err := store.GetState()
+	if err != nil {
+		log.Debug("Unable to load state", "error", err)
+		p.resetState()
+		return nil
+	}
+
+	err = json.Unmarshal(d, p.state)
+	if err != nil {
+		log.Debug("Unable to unmarshal state", "error", err)
+		p.resetState()
+	}
+
+	return nil
+}
+
+// Execute the strategy
+//
+// On the first run interfaces.StrategyStatusSuccess is returned once the initial delay has elapsed,
+// the candidate receives no share of the traffic split but requests that match the configured
+// routes are sent to the candidate.
+//
+// On subsequent runs the candidate is monitored until the required number of checks have passed,
+// interfaces.StrategyStatusComplete is returned when the checks pass and
+// interfaces.StrategyStatusFailed when the error threshold is reached.
+func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.StrategyStatus, int, error) {
+	p.log.Info("Executing strategy", "type", "abtest", "successful_checks", p.state.SuccessfulChecks)
+
+	// save the state on exit
+	defer p.saveState()
+
+	// first run, wait for the initial delay before routing requests
+	if p.state.CandidateTraffic == -1 {
+		d, err := time.ParseDuration(p.config.InitialDelay)
+		if err != nil {
+			p.state.Status = interfaces.StrategyStatusFailed
+			return interfaces.StrategyStatusFailed, 0, fmt.Errorf("unable to parse initial delay: %s", err)
+		}
+
+		p.log.Debug("Waiting for initial grace before routing requests to candidate", "type", "abtest", "delay", d.Seconds())
+		err = p.sleep(ctx, d)
+		if err != nil {
+			return interfaces.StrategyStatusFailed, 0, err
+		}
+
+		p.state.CandidateTraffic = 0
+		p.state.SuccessfulChecks = 0
+		p.state.Status = interfaces.StrategyStatusSuccess
+		return interfaces.StrategyStatusSuccess, 0, nil
+	}
+
+	interval, err := time.ParseDuration(p.config.Interval)
+	if err != nil {
+		p.state.Status = interfaces.StrategyStatusFailed
+		return interfaces.StrategyStatusFailed, 0, fmt.Errorf("unable to parse interval: %s", err)
+	}
+
+	failCount := 0
+	for p.state.SuccessfulChecks < p.config.RequiredChecks {
+		err := p.sleep(ctx, interval)
+		if err != nil {
+			return interfaces.StrategyStatusFailed, 0, err
+		}
+
+		if p.check(ctx, candidateName, interval) {
+			failCount = 0
+			p.state.SuccessfulChecks++
+			p.state.Status = interfaces.StrategyStatusSuccess
+			p.saveState()
+
+			continue
+		}
+
+		// the strategy has been cancelled while checking, do not record the result
+		if ctx.Err() != nil {
+			return interfaces.StrategyStatusFailed, 0, ctx.Err()
+		}
+
+		failCount++
+		if failCount >= p.config.ErrorThreshold {
+			p.log.Debug("Candidate checks failed", "type", "abtest")
+
+			p.resetState()
+			p.state.Status = interfaces.StrategyStatusFailed
+			return interfaces.StrategyStatusFailed, 0, nil
+		}
+
+		p.state.Status = interfaces.StrategyStatusFailing
+		p.saveState()
+	}
+
+	// strategy is complete
+	p.log.Debug("Strategy complete", "type", "abtest", "successful_checks", p.state.SuccessfulChecks)
+
+	p.resetState()
+	p.state.Status = interfaces.StrategyStatusComplete
+	return interfaces.StrategyStatusComplete, 100, nil
+}
+
+// GetPrimaryTraffic returns the percentage of traffic distributed to the primary, requests
+// that match the routes are sent to the candidate regardless of the traffic split
+func (p *Plugin) GetPrimaryTraffic() int {
+	return 100
+}
+
+// GetCandidateTraffic returns the percentage of traffic distributed to the candidate, requests
+// that match the routes are sent to the candidate regardless of the traffic split
+func (p *Plugin) GetCandidateTraffic() int {
+	return 0
+}
+
+// GetCandidateRoutes returns the routes that select the requests sent to the candidate
+func (p *Plugin) GetCandidateRoutes() []interfaces.RouteMatch {
+	return p.config.Routes
+}
+
+// check calls the monitor and returns true if the check passed
+func (p *Plugin) check(ctx context.Context, candidateName string, interval time.Duration) bool {
+	queryCtx, done := context.WithTimeout(ctx, 30*time.Second)
+	defer done()
+
+	p.log.Debug("Checking metrics", "type", "abtest")
+
+	_, err := p.monitoring.Check(queryCtx, candidateName, interval)
+	if err != nil {
+		p.log.Debug("Check failed", "type", "abtest", "error", err)
+		return false
+	}
+
+	return true
+}
+
+// Reset clears the progress of the strategy so the next call to Execute starts a new roll out
+func (p *Plugin) Reset() {
+	p.resetState()
+	p.saveState()
+}
+
+func (p *Plugin) resetState() {
+	p.state.CandidateTraffic = -1
+	p.state.SuccessfulChecks = 0
+	p.state.Status = interfaces.StrategyStatusSuccess
+}
+
+func (p *Plugin) saveState() {
+	d, err := json.Marshal(p.state)
+	if err != nil {
+		p.log.Error("Unable to marshal state to json", "error", err)
+		return
+	}
+
+	err = p.store.UpsertState(d)
+	if err != nil {
+		p.log.Error("Unable to save state", "error", err)
+	}
+}
+
+// sleep blocks for the given duration, returns the context error if the context is
+// cancelled before the duration elapses
+func (p *Plugin) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.clock.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,211 @@
+package abtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPlugin(t *testing.T, config string) (*Plugin, *mocks.MonitorMock) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+
+	err := p.Configure([]byte(config), log, m.StoreMock)
+	require.NoError(t, err)
+
+	return p, m.MonitorMock
+}
+
+func TestConfigureSetsState(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetState")
+	m.StoreMock.On("GetState").Return([]byte(`{"candidate_traffic":0,"successful_checks":2}`), nil)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+	err := p.Configure([]byte(abTestStrategy), log, m.StoreMock)
+
+	require.NoError(t, err)
+	require.Equal(t, 0, p.state.CandidateTraffic)
+	require.Equal(t, 2, p.state.SuccessfulChecks)
+}
+
+func TestConfigureSetsDefaultStateOnError(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetState")
+	m.StoreMock.On("GetState").Return(nil, fmt.Errorf("boom"))
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+	err := p.Configure([]byte(abTestStrategy), log, m.StoreMock)
+
+	require.NoError(t, err)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+}
+
+func TestSetsDefaultsWhenNotSet(t *testing.T) {
+	p, _ := setupPlugin(t, abTestStrategyWithoutOptional)
+
+	require.Equal(t, p.config.Interval, p.config.InitialDelay)
+}
+
+func TestValidatesConfig(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+
+	err := p.Configure([]byte(abTestStrategyWithValidationErrors), log, m.StoreMock)
+	require.Error(t, err)
+
+	require.Contains(t, err.Error(), ErrInvalidInitialDelay.Error())
+	require.Contains(t, err.Error(), ErrInvalidInterval.Error())
+	require.Contains(t, err.Error(), ErrInvalidRoutes.Error())
+	require.Contains(t, err.Error(), ErrRequiredChecks.Error())
+	require.Contains(t, err.Error(), ErrThreshold.Error())
+}
+
+func TestValidatesConfigWithoutRoutes(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+
+	err := p.Configure([]byte(abTestStrategyWithoutRoutes), log, m.StoreMock)
+	require.Error(t, err)
+
+	require.Contains(t, err.Error(), ErrInvalidRoutes.Error())
+}
+
+func TestReturnsCandidateRoutes(t *testing.T) {
+	p, _ := setupPlugin(t, abTestStrategy)
+
+	routes := p.GetCandidateRoutes()
+	require.Len(t, routes, 2)
+
+	require.Equal(t, interfaces.RouteMatchTypeHeader, routes[0].Type)
+	require.Equal(t, "x-beta", routes[0].Name)
+	require.Equal(t, "true", routes[0].Exact)
+
+	require.Equal(t, interfaces.RouteMatchTypeCookie, routes[1].Type)
+	require.Equal(t, "group", routes[1].Name)
+}
+
+func TestFirstRunReturnsSuccessWithoutCheckingOrSplittingTraffic(t *testing.T) {
+	p, mm := setupPlugin(t, abTestStrategy)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 0, traffic)
+	require.Equal(t, 0, p.GetCandidateTraffic())
+	require.Equal(t, 100, p.GetPrimaryTraffic())
+
+	mm.AssertNotCalled(t, "Check", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSecondRunReturnsCompleteAfterRequiredChecks(t *testing.T) {
+	p, mm := setupPlugin(t, abTestStrategy)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusComplete, string(status))
+	require.Equal(t, 100, traffic)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+
+	mm.AssertNumberOfCalls(t, "Check", 3)
+}
+
+func TestSecondRunResumesFromSavedChecks(t *testing.T) {
+	p, mm := setupPlugin(t, abTestStrategy)
+	p.state.CandidateTraffic = 0
+	p.state.SuccessfulChecks = 2
+
+	status, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusComplete, string(status))
+	mm.AssertNumberOfCalls(t, "Check", 1)
+}
+
+func TestSecondRunReturnsFailedWhenChecksFail(t *testing.T) {
+	p, mm := setupPlugin(t, abTestStrategy)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckFailed, fmt.Errorf("boom"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusFailed, string(status))
+	require.Equal(t, 0, traffic)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+
+	// should call check 2 times due to error threshold
+	mm.AssertNumberOfCalls(t, "Check", 2)
+}
+
+const abTestStrategy = `
+{
+  "initial_delay": "10ms",
+  "interval": "10ms",
+  "routes": [
+    {"type": "header", "name": "x-beta", "exact": "true"},
+    {"type": "cookie", "name": "group", "regex": "beta-.*"}
+  ],
+  "required_checks": 3,
+  "error_threshold": 2
+}
+`
+
+const abTestStrategyWithoutOptional = `
+{
+  "interval": "10ms",
+  "routes": [
+    {"type": "query", "name": "beta"}
+  ],
+  "required_checks": 3,
+  "error_threshold": 2
+}
+`
+
+const abTestStrategyWithoutRoutes = `
+{
+  "interval": "10ms",
+  "required_checks": 3,
+  "error_threshold": 2
+}
+`
+
+const abTestStrategyWithValidationErrors = `
+{
+  "initial_delay": "acs",
+  "interval": "30",
+  "routes": [
+    {"type": "path", "name": "x-beta"},
+    {"type": "header"}
+  ],
+  "required_checks": 0,
+  "error_threshold": -1
+}
+`
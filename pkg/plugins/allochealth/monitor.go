@@ -0,0 +1,164 @@
+package allochealth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/api"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+const (
+	clientStatusFailed = "failed"
+	clientStatusLost   = "lost"
+)
+
+type Plugin struct {
+	log       hclog.Logger
+	config    *PluginConfig
+	store     interfaces.PluginStateStore
+	client    clients.Nomad
+	namespace string
+}
+
+type PluginConfig struct {
+	// MaxRestarts is the maximum number of times any task in a candidate allocation can restart, defaults to 3
+	MaxRestarts *int `json:"max_restarts,omitempty" validate:"omitempty,gte=0"`
+
+	// MaxFailedAllocations is the maximum number of candidate allocations that can be failed or lost, defaults to 0
+	MaxFailedAllocations *int `json:"max_failed_allocations,omitempty" validate:"omitempty,gte=0"`
+
+	// MaxUnhealthyAllocations is the maximum number of candidate allocations that the Nomad deployment can
+	// mark as unhealthy, defaults to 0
+	MaxUnhealthyAllocations *int `json:"max_unhealthy_allocations,omitempty" validate:"omitempty,gte=0"`
+}
+
+// New creates a new allocation health monitor for the candidate job in the given namespace
+func New(namespace string, client clients.Nomad, l hclog.Logger) (*Plugin, error) {
+	return &Plugin{
+		log:       l,
+		client:    client,
+		namespace: namespace,
+	}, nil
+}
+
+var ErrInvalidMaxRestarts = fmt.Errorf("MaxRestarts must be greater than or equal to 0")
+var ErrInvalidMaxFailedAllocations = fmt.Errorf("MaxFailedAllocations must be greater than or equal to 0")
+var ErrInvalidMaxUnhealthyAllocations = fmt.Errorf("MaxUnhealthyAllocations must be greater than or equal to 0")
+
+func (s *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	s.log = log
+	s.store = store
+	s.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, s.config)
+	if err != nil {
+		return fmt.Errorf("unable to decode Monitoring config: %s", err)
+	}
+
+	validate := validator.New()
+	err = validate.Struct(s.config)
+
+	if err != nil {
+		errorMessage := ""
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.MaxRestarts":
+				errorMessage += ErrInvalidMaxRestarts.Error() + "\n"
+			case "PluginConfig.MaxFailedAllocations":
+				errorMessage += ErrInvalidMaxFailedAllocations.Error() + "\n"
+			case "PluginConfig.MaxUnhealthyAllocations":
+				errorMessage += ErrInvalidMaxUnhealthyAllocations.Error() + "\n"
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	if s.config.MaxRestarts == nil {
+		three := 3
+		s.config.MaxRestarts = &three
+	}
+
+	if s.config.MaxFailedAllocations == nil {
+		zero := 0
+		s.config.MaxFailedAllocations = &zero
+	}
+
+	if s.config.MaxUnhealthyAllocations == nil {
+		zero := 0
+		s.config.MaxUnhealthyAllocations = &zero
+	}
+
+	return nil
+}
+
+// Check inspects the allocations of the current version of the candidate job and returns CheckFailed when a task
+// has restarted more than the allowed number of times, or more than the allowed number of allocations are failed,
+// lost, or marked as unhealthy by the Nomad deployment. Failed or lost allocations are counted even when Nomad has
+// replaced them
+func (s *Plugin) Check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	allocs, err := s.client.GetJobAllocations(ctx, candidateName, s.namespace)
+	if err != nil {
+		s.log.Error("unable to get candidate allocations", "name", candidateName, "namespace", s.namespace, "error", err)
+
+		return interfaces.CheckError, fmt.Errorf("unable to get allocations for candidate %s: %s", candidateName, err)
+	}
+
+	if len(allocs) == 0 {
+		return interfaces.CheckNoMetrics, fmt.Errorf("no allocations found for candidate %s in namespace %s", candidateName, s.namespace)
+	}
+
+	problems := []string{}
+	failed := []string{}
+	unhealthy := []string{}
+
+	for _, a := range allocs {
+		problems = append(problems, s.checkTasks(a)...)
+
+		// allocations that Nomad has replaced are still counted, the replacements of a crashing
+		// candidate start with no restarts
+		if a.ClientStatus == clientStatusFailed || a.ClientStatus == clientStatusLost {
+			failed = append(failed, a.Name)
+		}
+
+		if a.DeploymentStatus != nil && a.DeploymentStatus.Healthy != nil && !*a.DeploymentStatus.Healthy {
+			unhealthy = append(unhealthy, a.Name)
+		}
+	}
+
+	if len(failed) > *s.config.MaxFailedAllocations {
+		problems = append(problems, fmt.Sprintf("%d allocations failed or lost, more than the limit %d [%s]", len(failed), *s.config.MaxFailedAllocations, strings.Join(failed, " ")))
+	}
+
+	if len(unhealthy) > *s.config.MaxUnhealthyAllocations {
+		problems = append(problems, fmt.Sprintf("%d allocations unhealthy, more than the limit %d [%s]", len(unhealthy), *s.config.MaxUnhealthyAllocations, strings.Join(unhealthy, " ")))
+	}
+
+	s.log.Debug("checked candidate allocations", "name", candidateName, "namespace", s.namespace, "allocations", len(allocs), "problems", len(problems))
+
+	if len(problems) > 0 {
+		return interfaces.CheckFailed, fmt.Errorf("check failed for candidate %s, %s", candidateName, strings.Join(problems, ", "))
+	}
+
+	return interfaces.CheckSuccess, nil
+}
+
+// checkTasks returns the problems found with the tasks in the allocation
+func (s *Plugin) checkTasks(a *api.AllocationListStub) []string {
+	problems := []string{}
+
+	for name, ts := range a.TaskStates {
+		if int(ts.Restarts) > *s.config.MaxRestarts {
+			problems = append(problems, fmt.Sprintf("allocation %s task %s restarted %d times, more than the limit %d", a.Name, name, ts.Restarts, *s.config.MaxRestarts))
+		}
+	}
+
+	return problems
+}
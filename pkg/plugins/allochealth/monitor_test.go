@@ -0,0 +1,219 @@
+package allochealth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/api"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPlugin creates a plugin with a Nomad client that uses a stubbed Nomad HTTP API, the returned allocations
+// can be modified by the test before calling Check
+func setupPlugin(t *testing.T, config string) (*Plugin, []*api.AllocationListStub) {
+	l := hclog.NewNullLogger()
+
+	allocs := []*api.AllocationListStub{
+		alloc("api.api[0]", 2),
+		alloc("api.api[1]", 2),
+		// allocations from the previous version of the job should not be checked
+		failed(alloc("api.api[0]", 1)),
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/jobs":
+			json.NewEncoder(rw).Encode([]*api.JobListStub{
+				// jobs whose name only ends with the candidate name should not be checked
+				{ID: "web-api-deployment", Name: "web-api-deployment"},
+				{ID: "api-deployment", Name: "api-deployment"},
+			})
+		case "/v1/job/web-api-deployment":
+			json.NewEncoder(rw).Encode(&api.Job{ID: stringPtr("web-api-deployment"), Name: stringPtr("web-api-deployment"), Status: stringPtr("running"), Version: uint64Ptr(2)})
+		case "/v1/job/web-api-deployment/allocations":
+			json.NewEncoder(rw).Encode([]*api.AllocationListStub{failed(alloc("web.web[0]", 2))})
+		case "/v1/job/api-deployment":
+			json.NewEncoder(rw).Encode(&api.Job{ID: stringPtr("api-deployment"), Name: stringPtr("api-deployment"), Status: stringPtr("running"), Version: uint64Ptr(2)})
+		case "/v1/job/api-deployment/allocations":
+			json.NewEncoder(rw).Encode(allocs)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+	t.Setenv("NOMAD_ADDR", ts.URL)
+
+	nc, err := clients.NewNomad(time.Millisecond, time.Second, l)
+	require.NoError(t, err)
+
+	p, _ := New("default", nc, l)
+
+	err = p.Configure([]byte(config), l, &mocks.StoreMock{})
+	require.NoError(t, err)
+
+	return p, allocs
+}
+
+func alloc(name string, version uint64) *api.AllocationListStub {
+	return &api.AllocationListStub{
+		ID:            name,
+		Name:          name,
+		JobID:         "api-deployment",
+		JobVersion:    version,
+		DesiredStatus: "run",
+		ClientStatus:  "running",
+		TaskStates: map[string]*api.TaskState{
+			"api": {State: "running"},
+		},
+	}
+}
+
+func failed(a *api.AllocationListStub) *api.AllocationListStub {
+	a.ClientStatus = "failed"
+	a.TaskStates["api"].Restarts = 10
+
+	return a
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func uint64Ptr(i uint64) *uint64 {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestConfigureSetsDefaults(t *testing.T) {
+	p, _ := setupPlugin(t, `{}`)
+
+	require.Equal(t, 3, *p.config.MaxRestarts)
+	require.Equal(t, 0, *p.config.MaxFailedAllocations)
+	require.Equal(t, 0, *p.config.MaxUnhealthyAllocations)
+}
+
+func TestConfigureReturnsErrorWhenLimitsInvalid(t *testing.T) {
+	p, _ := New("default", nil, hclog.NewNullLogger())
+
+	err := p.Configure([]byte(`{"max_failed_allocations": -1}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidMaxFailedAllocations.Error())
+}
+
+func TestCheckReturnsSuccessWhenCandidateAllocationsHealthy(t *testing.T) {
+	p, _ := setupPlugin(t, `{}`)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestCheckReturnsNoMetricsWhenNoAllocations(t *testing.T) {
+	p, allocs := setupPlugin(t, `{}`)
+
+	allocs[0].JobVersion = 1
+	allocs[1].JobVersion = 1
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestCheckReturnsErrorWhenJobNotFound(t *testing.T) {
+	p, _ := setupPlugin(t, `{}`)
+
+	result, err := p.Check(context.Background(), "web-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckError, result)
+}
+
+func TestCheckDoesNotTreatCandidateNameAsRegularExpression(t *testing.T) {
+	p, _ := setupPlugin(t, `{}`)
+
+	result, err := p.Check(context.Background(), "api.deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckError, result)
+}
+
+func TestCheckReturnsFailedWhenRestartsGreaterThanMax(t *testing.T) {
+	p, allocs := setupPlugin(t, `{"max_restarts": 1}`)
+
+	allocs[1].TaskStates["api"].Restarts = 2
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "allocation api.api[1] task api restarted 2 times, more than the limit 1")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckReturnsFailedWhenAllocationsFailedOrLost(t *testing.T) {
+	p, allocs := setupPlugin(t, `{"max_failed_allocations": 1}`)
+
+	allocs[0].ClientStatus = "failed"
+	allocs[1].ClientStatus = "lost"
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "2 allocations failed or lost, more than the limit 1 [api.api[0] api.api[1]]")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckReturnsSuccessWhenFailedAllocationsWithinLimit(t *testing.T) {
+	p, allocs := setupPlugin(t, `{"max_failed_allocations": 1}`)
+
+	allocs[0].ClientStatus = "lost"
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestCheckReturnsFailedWhenFailedAllocationsHaveBeenReplaced(t *testing.T) {
+	p, allocs := setupPlugin(t, `{}`)
+
+	// Nomad has stopped the failed allocation and placed a replacement that has not restarted
+	allocs[0].ClientStatus = "failed"
+	allocs[0].DesiredStatus = "stop"
+	allocs[1].RescheduleTracker = &api.RescheduleTracker{Events: []*api.RescheduleEvent{{PrevAllocID: allocs[0].ID}}}
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 allocations failed or lost, more than the limit 0 [api.api[0]]")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckReturnsFailedWhenStoppedAllocationLost(t *testing.T) {
+	p, allocs := setupPlugin(t, `{}`)
+
+	allocs[0].ClientStatus = "lost"
+	allocs[0].DesiredStatus = "stop"
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 allocations failed or lost, more than the limit 0 [api.api[0]]")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckReturnsFailedWhenAllocationsUnhealthy(t *testing.T) {
+	p, allocs := setupPlugin(t, `{}`)
+
+	allocs[0].DeploymentStatus = &api.AllocDeploymentStatus{Healthy: boolPtr(true)}
+	allocs[1].DeploymentStatus = &api.AllocDeploymentStatus{Healthy: boolPtr(false)}
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 allocations unhealthy, more than the limit 0 [api.api[1]]")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
@@ -0,0 +1,419 @@
+package bluegreen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+type Plugin struct {
+	log        hclog.Logger
+	config     *PluginConfig
+	store      interfaces.PluginStateStore
+	monitoring interfaces.Monitor
+	state      *PluginState
+	clock      interfaces.Clock
+}
+
+type PluginState struct {
+	CandidateTraffic int    `json:"candidate_traffic"`
+	Status           string `json:"status"`
+	// TrafficSwitched is the time that all traffic was sent to the candidate
+	TrafficSwitched time.Time `json:"traffic_switched"`
+}
+
+type PluginConfig struct {
+	// InitialDelay before running the first checks against the candidate
+	InitialDelay string `hcl:"initial_delay,optional" json:"initial_delay,omitempty" validate:"duration"`
+	// Interval between checks
+	Interval string `hcl:"interval" json:"interval" validate:"required,duration"`
+	// RollbackWindow is the duration the old primary is kept running after all traffic has been
+	// switched to the candidate. Should the candidate fail its checks during this window, traffic
+	// is switched back to the primary.
+	RollbackWindow string `hcl:"rollback_window,optional" json:"rollback_window,omitempty" validate:"duration"`
+	// ErrorThreshold is the number of consecutive failed checks before rolling back traffic
+	ErrorThreshold int `hcl:"error_threshold" json:"error_threshold" validate:"required,gte=1"`
+	// OnFailed is the policy applied when a check returns values that are not in tolerance, defaults to fail
+	OnFailed string `hcl:"on_failed,optional" json:"on_failed,omitempty" validate:"omitempty,oneof=fail retry pause pass"`
+	// OnNoMetrics is the policy applied when a check returns no metrics, defaults to pass as the candidate
+	// does not receive any traffic until it has been checked
+	OnNoMetrics string `hcl:"on_no_metrics,optional" json:"on_no_metrics,omitempty" validate:"omitempty,oneof=fail retry pause pass"`
+	// OnError is the policy applied when a check fails due to an internal error, defaults to fail
+	OnError string `hcl:"on_error,optional" json:"on_error,omitempty" validate:"omitempty,oneof=fail retry pause pass"`
+	// MaxRetries is the number of consecutive checks that can be retried by the retry policy, once the retries
+	// are exhausted the check is handled by the fail policy, defaults to 10
+	MaxRetries *int `hcl:"max_retries,optional" json:"max_retries,omitempty" validate:"omitempty,gte=0"`
+}
+
+const (
+	// PolicyFail counts the check towards the ErrorThreshold
+	PolicyFail = "fail"
+	// PolicyRetry waits for the interval and retries the check without counting it towards the ErrorThreshold,
+	// up to MaxRetries times
+	PolicyRetry = "retry"
+	// PolicyPause pauses the release until it is manually resumed or aborted
+	PolicyPause = "pause"
+	// PolicyPass treats the check as passed
+	PolicyPass = "pass"
+)
+
+var ErrInvalidInitialDelay = fmt.Errorf("InitialDelay is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
+var ErrInvalidInterval = fmt.Errorf("Interval is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
+var ErrInvalidRollbackWindow = fmt.Errorf("RollbackWindow is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
+var ErrThreshold = fmt.Errorf("ErrorThreshold must contain a value greater than 0")
+var ErrOnFailed = fmt.Errorf("OnFailed must be one of fail, retry, pause, or pass")
+var ErrOnNoMetrics = fmt.Errorf("OnNoMetrics must be one of fail, retry, pause, or pass")
+var ErrOnError = fmt.Errorf("OnError must be one of fail, retry, pause, or pass")
+var ErrMaxRetries = fmt.Errorf("MaxRetries must contain a value greater than or equal to 0")
+
+func New(m interfaces.Monitor, c interfaces.Clock) (*Plugin, error) {
+	return &Plugin{monitoring: m, clock: c}, nil
+}
+
+// Configure the plugin with the given json
+// returns an error when validation fails for the config
+func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	p.log = log
+	p.store = store
+	p.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, p.config)
+	if err != nil {
+		return err
+	}
+
+	// if no initial delay use the interval
+	if p.config.InitialDelay == "" {
+		p.config.InitialDelay = p.config.Interval
+	}
+
+	// by default the primary is removed as soon as the candidate has been switched
+	if p.config.RollbackWindow == "" {
+		p.config.RollbackWindow = "0s"
+	}
+
+	if p.config.OnNoMetrics == "" {
+		p.config.OnNoMetrics = PolicyPass
+	}
+
+	// validate the plugin config
+	validate := validator.New()
+	validate.RegisterValidation("duration", interfaces.ValidateDuration)
+	err = validate.Struct(p.config)
+
+	if err != nil {
+		errorMessage := ""
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.InitialDelay":
+				errorMessage += ErrInvalidInitialDelay.Error() + "\n"
+			case "PluginConfig.Interval":
+				errorMessage += ErrInvalidInterval.Error() + "\n"
+			case "PluginConfig.RollbackWindow":
+				errorMessage += ErrInvalidRollbackWindow.Error() + "\n"
+			case "PluginConfig.ErrorThreshold":
+				errorMessage += ErrThreshold.Error() + "\n"
+			case "PluginConfig.OnFailed":
+				errorMessage += ErrOnFailed.Error() + "\n"
+			case "PluginConfig.OnNoMetrics":
+				errorMessage += ErrOnNoMetrics.Error() + "\n"
+			case "PluginConfig.OnError":
+				errorMessage += ErrOnError.Error() + "\n"
+			case "PluginConfig.MaxRetries":
+				errorMessage += ErrMaxRetries.Error() + "\n"
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	if p.config.MaxRetries == nil {
+		ten := 10
+		p.config.MaxRetries = &ten
+	}
+
+	// load the state
+	p.state = &PluginState{}
+	d, err := store.GetState()
+	if err != nil {
+		log.Debug("Unable to load state", "error", err)
+		p.resetState()
+		return nil
+	}
+
+	err = json.Unmarshal(d, p.state)
+	if err != nil {
+		log.Debug("Unable to unmarshal state", "error", err)
+		p.resetState()
+	}
+
+	return nil
+}
+
+// Execute the strategy
+//
+// On the first run the candidate is checked while it receives no traffic, when the checks pass
+// interfaces.StrategyStatusSuccess and 100 is returned to switch all traffic to the candidate.
+//
+// On subsequent runs the candidate is monitored for the duration of the rollback window,
+// interfaces.StrategyStatusComplete is returned when the window elapses without failure and
+// interfaces.StrategyStatusFailed when the checks fail.
+//
+// interfaces.StrategyStatusPaused, the current traffic, and the check error are returned when a check fails
+// with the pause policy.
+func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.StrategyStatus, int, error) {
+	p.log.Info("Executing strategy", "type", "bluegreen", "traffic", p.state.CandidateTraffic)
+
+	// save the state on exit
+	defer p.saveState()
+
+	interval, err := time.ParseDuration(p.config.Interval)
+	if err != nil {
+		p.state.Status = interfaces.StrategyStatusFailed
+		return interfaces.StrategyStatusFailed, 0, fmt.Errorf("unable to parse interval: %s", err)
+	}
+
+	// the candidate has not received any traffic, check it before switching
+	if p.state.CandidateTraffic < 100 {
+		if p.state.CandidateTraffic == -1 {
+			d, err := time.ParseDuration(p.config.InitialDelay)
+			if err != nil {
+				p.state.Status = interfaces.StrategyStatusFailed
+				return interfaces.StrategyStatusFailed, 0, fmt.Errorf("unable to parse initial delay: %s", err)
+			}
+
+			p.log.Debug("Waiting for initial grace before checking candidate", "type", "bluegreen", "delay", d.Seconds())
+			err = p.sleep(ctx, d)
+			if err != nil {
+				return interfaces.StrategyStatusFailed, 0, err
+			}
+
+			p.state.CandidateTraffic = 0
+		}
+
+		failCount := 0
+		retries := 0
+	checks:
+		for {
+			result, err := p.check(ctx, candidateName, interval)
+			if err == nil {
+				break
+			}
+
+			// the strategy has been cancelled while checking, do not record the result
+			if ctx.Err() != nil {
+				return interfaces.StrategyStatusFailed, 0, ctx.Err()
+			}
+
+			switch p.policy(result, retries) {
+			case PolicyPass:
+				p.log.Debug("Check passed by policy", "type", "bluegreen", "result", result)
+				break checks
+			case PolicyRetry:
+				retries++
+				p.log.Debug("Retrying check", "type", "bluegreen", "result", result, "retries", retries)
+
+				err := p.sleep(ctx, interval)
+				if err != nil {
+					return interfaces.StrategyStatusFailed, 0, err
+				}
+
+				continue
+			case PolicyPause:
+				p.log.Info("Pausing strategy", "type", "bluegreen", "result", result, "error", err)
+				p.state.Status = interfaces.StrategyStatusPaused
+				return interfaces.StrategyStatusPaused, 0, err
+			}
+
+			failCount++
+			if failCount >= p.config.ErrorThreshold {
+				p.log.Debug("Candidate checks failed before switching traffic", "type", "bluegreen")
+
+				p.resetState()
+				p.state.Status = interfaces.StrategyStatusFailed
+				return interfaces.StrategyStatusFailed, 0, nil
+			}
+
+			p.state.Status = interfaces.StrategyStatusFailing
+			p.saveState()
+
+			err = p.sleep(ctx, interval)
+			if err != nil {
+				return interfaces.StrategyStatusFailed, 0, err
+			}
+		}
+
+		// switch all traffic to the candidate in a single step
+		p.log.Debug("Candidate healthy, switching traffic", "type", "bluegreen")
+
+		p.state.CandidateTraffic = 100
+		p.state.TrafficSwitched = p.clock.Now()
+		p.state.Status = interfaces.StrategyStatusSuccess
+		return interfaces.StrategyStatusSuccess, 100, nil
+	}
+
+	// traffic has been switched, monitor the candidate until the rollback window elapses
+	window, err := time.ParseDuration(p.config.RollbackWindow)
+	if err != nil {
+		p.state.Status = interfaces.StrategyStatusFailed
+		return interfaces.StrategyStatusFailed, 0, fmt.Errorf("unable to parse rollback window: %s", err)
+	}
+
+	failCount := 0
+	retries := 0
+	for p.clock.Now().Sub(p.state.TrafficSwitched) < window {
+		err := p.sleep(ctx, interval)
+		if err != nil {
+			return interfaces.StrategyStatusFailed, 0, err
+		}
+
+		result, err := p.check(ctx, candidateName, interval)
+		if err == nil {
+			failCount = 0
+			retries = 0
+			p.state.Status = interfaces.StrategyStatusSuccess
+			continue
+		}
+
+		// the strategy has been cancelled while checking, do not record the result
+		if ctx.Err() != nil {
+			return interfaces.StrategyStatusFailed, 0, ctx.Err()
+		}
+
+		switch p.policy(result, retries) {
+		case PolicyPass:
+			p.log.Debug("Check passed by policy", "type", "bluegreen", "result", result)
+			continue
+		case PolicyRetry:
+			retries++
+			p.log.Debug("Retrying check", "type", "bluegreen", "result", result, "retries", retries)
+			continue
+		case PolicyPause:
+			p.log.Info("Pausing strategy", "type", "bluegreen", "result", result, "error", err)
+			p.state.Status = interfaces.StrategyStatusPaused
+			return interfaces.StrategyStatusPaused, 100, err
+		}
+
+		failCount++
+		if failCount >= p.config.ErrorThreshold {
+			p.log.Debug("Candidate checks failed inside rollback window", "type", "bluegreen")
+
+			p.resetState()
+			p.state.Status = interfaces.StrategyStatusFailed
+			return interfaces.StrategyStatusFailed, 0, nil
+		}
+
+		p.state.Status = interfaces.StrategyStatusFailing
+		p.saveState()
+	}
+
+	// strategy is complete
+	p.log.Debug("Strategy complete", "type", "bluegreen", "traffic", p.state.CandidateTraffic)
+
+	p.resetState()
+	p.state.Status = interfaces.StrategyStatusComplete
+	return interfaces.StrategyStatusComplete, 100, nil
+}
+
+// GetWindow returns the longest time that a single call to Execute monitors the candidate after
+// traffic has been switched, the rollback window and the final interval before the window is checked
+func (p *Plugin) GetWindow() time.Duration {
+	window, _ := time.ParseDuration(p.config.RollbackWindow)
+	interval, _ := time.ParseDuration(p.config.Interval)
+
+	return window + interval
+}
+
+func (p *Plugin) GetPrimaryTraffic() int {
+	return 100 - p.GetCandidateTraffic()
+}
+
+func (p *Plugin) GetCandidateTraffic() int {
+	if p.state.CandidateTraffic < 100 {
+		return 0
+	}
+
+	return 100
+}
+
+// check calls the monitor and returns the result, an error is returned when the check did not pass
+func (p *Plugin) check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	queryCtx, done := context.WithTimeout(ctx, 30*time.Second)
+	defer done()
+
+	p.log.Debug("Checking metrics", "type", "bluegreen")
+
+	result, err := p.monitoring.Check(queryCtx, candidateName, interval)
+	if err != nil {
+		p.log.Debug("Check failed", "type", "bluegreen", "result", result, "error", err)
+	}
+
+	return result, err
+}
+
+// policy returns the configured policy for a failed check result, the retry policy is replaced
+// by the fail policy once retries reaches MaxRetries
+func (p *Plugin) policy(result interfaces.CheckResult, retries int) string {
+	policy := ""
+
+	switch result {
+	case interfaces.CheckNoMetrics:
+		policy = p.config.OnNoMetrics
+	case interfaces.CheckError:
+		policy = p.config.OnError
+	default:
+		policy = p.config.OnFailed
+	}
+
+	if policy == "" {
+		return PolicyFail
+	}
+
+	if policy == PolicyRetry && retries >= *p.config.MaxRetries {
+		p.log.Debug("Retries exhausted, failing check", "type", "bluegreen", "result", result, "max_retries", *p.config.MaxRetries)
+		return PolicyFail
+	}
+
+	return policy
+}
+
+// Reset clears the progress of the strategy so the next call to Execute starts a new roll out
+func (p *Plugin) Reset() {
+	p.resetState()
+	p.saveState()
+}
+
+func (p *Plugin) resetState() {
+	p.state.CandidateTraffic = -1
+	p.state.Status = interfaces.StrategyStatusSuccess
+	p.state.TrafficSwitched = time.Time{}
+}
+
+func (p *Plugin) saveState() {
+	d, err := json.Marshal(p.state)
+	if err != nil {
+		p.log.Error("Unable to marshal state to json", "error", err)
+		return
+	}
+
+	err = p.store.UpsertState(d)
+	if err != nil {
+		p.log.Error("Unable to save state", "error", err)
+	}
+}
+
+// sleep blocks for the given duration, returns the context error if the context is
+// cancelled before the duration elapses
+func (p *Plugin) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.clock.After(d):
+		return nil
+	}
+}
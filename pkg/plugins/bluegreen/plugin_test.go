@@ -0,0 +1,284 @@
+package bluegreen
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPlugin(t *testing.T, config string) (*Plugin, *mocks.MonitorMock) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+
+	err := p.Configure([]byte(config), log, m.StoreMock)
+	require.NoError(t, err)
+
+	return p, m.MonitorMock
+}
+
+func TestConfigureSetsState(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetState")
+	m.StoreMock.On("GetState").Return([]byte(`{"candidate_traffic":100}`), nil)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+	err := p.Configure([]byte(blueGreenStrategy), log, m.StoreMock)
+
+	require.NoError(t, err)
+	require.Equal(t, 100, p.state.CandidateTraffic)
+}
+
+func TestConfigureSetsDefaultStateOnError(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetState")
+	m.StoreMock.On("GetState").Return(nil, fmt.Errorf("boom"))
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+	err := p.Configure([]byte(blueGreenStrategy), log, m.StoreMock)
+
+	require.NoError(t, err)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+}
+
+func TestSetsDefaultsWhenNotSet(t *testing.T) {
+	p, _ := setupPlugin(t, blueGreenStrategyWithoutOptional)
+
+	require.Equal(t, p.config.Interval, p.config.InitialDelay)
+	require.Equal(t, "0s", p.config.RollbackWindow)
+	require.Equal(t, PolicyPass, p.config.OnNoMetrics)
+	require.Equal(t, 10, *p.config.MaxRetries)
+}
+
+func TestValidatesConfig(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+
+	err := p.Configure([]byte(blueGreenStrategyWithValidationErrors), log, m.StoreMock)
+	require.Error(t, err)
+
+	require.Contains(t, err.Error(), ErrInvalidInitialDelay.Error())
+	require.Contains(t, err.Error(), ErrInvalidInterval.Error())
+	require.Contains(t, err.Error(), ErrInvalidRollbackWindow.Error())
+	require.Contains(t, err.Error(), ErrThreshold.Error())
+	require.Contains(t, err.Error(), ErrOnFailed.Error())
+	require.Contains(t, err.Error(), ErrOnNoMetrics.Error())
+	require.Contains(t, err.Error(), ErrOnError.Error())
+	require.Contains(t, err.Error(), ErrMaxRetries.Error())
+}
+
+func TestFirstRunChecksCandidateAndSwitchesAllTraffic(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategy)
+
+	require.Equal(t, 0, p.GetCandidateTraffic())
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 100, traffic)
+	require.Equal(t, 100, p.GetCandidateTraffic())
+	require.Equal(t, 0, p.GetPrimaryTraffic())
+
+	mm.AssertNumberOfCalls(t, "Check", 1)
+}
+
+func TestFirstRunReturnsFailedWhenChecksFail(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategy)
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckFailed, fmt.Errorf("boom"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusFailed, string(status))
+	require.Equal(t, 0, traffic)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+
+	// should call check 3 times due to error threshold
+	mm.AssertNumberOfCalls(t, "Check", 3)
+}
+
+func TestFirstRunSwitchesTrafficWhenNoMetricsByDefault(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategy)
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckNoMetrics, fmt.Errorf("no metrics"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 100, traffic)
+	mm.AssertNumberOfCalls(t, "Check", 1)
+}
+
+func TestFirstRunRetriesWithoutCountingWhenErrorAndPolicyRetry(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategyWithPolicies)
+
+	// return errors for more checks than the error threshold before succeeding
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckError, fmt.Errorf("boom")).Times(3)
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckSuccess, nil)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 100, traffic)
+	mm.AssertNumberOfCalls(t, "Check", 4)
+}
+
+func TestFirstRunFailsWhenRetriesExhausted(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategyWithPolicies)
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckError, fmt.Errorf("boom"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusFailed, string(status))
+	require.Equal(t, 0, traffic)
+
+	// 5 retries followed by 2 checks counted towards the error threshold
+	mm.AssertNumberOfCalls(t, "Check", 7)
+}
+
+func TestFirstRunReturnsPausedWhenNoMetricsAndPolicyPause(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategyWithPolicies)
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckNoMetrics, fmt.Errorf("no metrics"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.Error(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusPaused, string(status))
+	require.Equal(t, 0, traffic)
+	mm.AssertNumberOfCalls(t, "Check", 1)
+}
+
+func TestFirstRunDoesNotSwitchTrafficWhenCancelled(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := p.Execute(ctx, "test-deployment")
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Equal(t, -1, p.state.CandidateTraffic)
+	mm.AssertNotCalled(t, "Check", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSecondRunMonitorsRollbackWindowAndReturnsComplete(t *testing.T) {
+	st := time.Now()
+	p, mm := setupPlugin(t, blueGreenStrategy)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusComplete, string(status))
+	require.Equal(t, 100, traffic)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+
+	require.Greater(t, time.Since(st), 100*time.Millisecond, "Execute should monitor for the rollback window")
+	require.Greater(t, len(mm.Calls), 1)
+}
+
+func TestSecondRunReturnsFailedWhenChecksFailInRollbackWindow(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategy)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckFailed, fmt.Errorf("boom"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusFailed, string(status))
+	require.Equal(t, 0, traffic)
+	require.Equal(t, 0, p.GetCandidateTraffic())
+}
+
+func TestSecondRunReturnsCompleteImmediatelyWithoutRollbackWindow(t *testing.T) {
+	p, mm := setupPlugin(t, blueGreenStrategyWithoutOptional)
+	p.state.CandidateTraffic = 100
+	p.state.TrafficSwitched = time.Now()
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusComplete, string(status))
+	require.Equal(t, 100, traffic)
+
+	mm.AssertNotCalled(t, "Check", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetWindowReturnsRollbackWindowAndInterval(t *testing.T) {
+	p, _ := setupPlugin(t, blueGreenStrategy)
+
+	require.Equal(t, 110*time.Millisecond, p.GetWindow())
+}
+
+const blueGreenStrategy = `
+{
+  "initial_delay": "10ms",
+  "interval": "10ms",
+  "rollback_window": "100ms",
+  "error_threshold": 3
+}
+`
+
+const blueGreenStrategyWithoutOptional = `
+{
+  "interval": "10ms",
+  "error_threshold": 3
+}
+`
+
+const blueGreenStrategyWithPolicies = `
+{
+  "interval": "10ms",
+  "error_threshold": 2,
+  "on_no_metrics": "pause",
+  "on_error": "retry",
+  "max_retries": 5
+}
+`
+
+const blueGreenStrategyWithValidationErrors = `
+{
+  "initial_delay": "acs",
+  "interval": "30",
+  "rollback_window": "abc",
+  "error_threshold": -1,
+  "on_failed": "ignore",
+  "on_no_metrics": "wait",
+  "on_error": "alert",
+  "max_retries": -1
+}
+`
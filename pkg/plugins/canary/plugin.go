@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -17,30 +18,68 @@ type Plugin struct {
 	store      interfaces.PluginStateStore
 	monitoring interfaces.Monitor
 	state      *PluginState
+	clock      interfaces.Clock
 }
 
 type PluginState struct {
 	CandidateTraffic int    `json:"candidate_traffic"`
 	Status           string `json:"status"`
+	// Step is the index of the current step when the strategy is configured with Steps
+	Step int `json:"step"`
+	// StepStarted is the time that the traffic for the current step was set
+	StepStarted time.Time `json:"step_started"`
 }
 
 type PluginConfig struct {
 	// InitialDelay before configuring the first traffic split
 	InitialDelay string `hcl:"initial_delay,optional" json:"initial_delay,omitempty" validate:"duration"`
 	// Interval between checks
-	Interval string `hcl:"interval,optional" json:"interval,omitempty" validate:"duration"`
+	Interval string `hcl:"interval,optional" json:"interval,omitempty" validate:"required_without=Steps,omitempty,duration"`
 	// InitialTraffic percentage to send to the canary
 	InitialTraffic int `hcl:"initial_traffic,optional" json:"initial_traffic,omitempty" validate:"gte=0,lte=100"`
 	// TrafficStep is the percentage of traffic to increase with every step
-	TrafficStep int `hcl:"traffic_step,optional" json:"traffic_step,omitempty" validate:"gte=1,lte=100,required"`
+	TrafficStep int `hcl:"traffic_step,optional" json:"traffic_step,omitempty" validate:"required_without=Steps,omitempty,gte=1,lte=100"`
 	// MaxTraffic to send to the canary before promoting to primary
-	MaxTraffic int `hcl:"max_traffic,optional" json:"max_traffic,omitempty" validate:"gte=1,lte=100,required"`
+	MaxTraffic int `hcl:"max_traffic,optional" json:"max_traffic,omitempty" validate:"required_without=Steps,omitempty,gte=1,lte=100"`
+	// Steps is an explicit schedule of traffic to send to the canary, when set
+	// Steps replaces InitialTraffic, TrafficStep, and MaxTraffic
+	Steps []Step `hcl:"steps,block" json:"steps,omitempty" validate:"dive"`
 	// ErrorThreshold is the number of consecutive failed checks before rolling back traffic
 	ErrorThreshold int `hcl:"error_threshold,optional" json:"error_threshold,omitempty" validate:"required,gte=0"`
 	// DeleteCanaryOnFailed determines if the canary deployment is deleted on a failed check
 	DeleteCanaryOnFailed bool `hcl:"delete_canary_on_failed,optional" json:"delete_canary_on_failed,omitempty"`
 	// ManualPromotion requires manual intervention before the canary is promoted to primary
 	ManualPromotion bool `hcl:"manual_promotion,optional" json:"manual_promotion"`
+	// OnFailed is the policy applied when a check returns values that are not in tolerance, defaults to fail
+	OnFailed string `hcl:"on_failed,optional" json:"on_failed,omitempty" validate:"omitempty,oneof=fail retry pause"`
+	// OnNoMetrics is the policy applied when a check returns no metrics, defaults to fail
+	OnNoMetrics string `hcl:"on_no_metrics,optional" json:"on_no_metrics,omitempty" validate:"omitempty,oneof=fail retry pause"`
+	// OnError is the policy applied when a check fails due to an internal error, defaults to fail
+	OnError string `hcl:"on_error,optional" json:"on_error,omitempty" validate:"omitempty,oneof=fail retry pause"`
+	// MaxRetries is the number of consecutive checks that can be retried by the retry policy, once the retries
+	// are exhausted the check is handled by the fail policy, defaults to 10
+	MaxRetries *int `hcl:"max_retries,optional" json:"max_retries,omitempty" validate:"omitempty,gte=0"`
+}
+
+const (
+	// PolicyFail counts the check towards the ErrorThreshold
+	PolicyFail = "fail"
+	// PolicyRetry waits for the interval and retries the check without counting it towards the ErrorThreshold,
+	// up to MaxRetries times
+	PolicyRetry = "retry"
+	// PolicyPause pauses the release until it is manually resumed or aborted
+	PolicyPause = "pause"
+)
+
+// Step defines the traffic sent to the canary and how long the traffic is held
+// before moving to the next step
+type Step struct {
+	// Traffic percentage to send to the canary
+	Traffic int `hcl:"traffic" json:"traffic" validate:"gte=1,lte=100"`
+	// Hold is the duration the traffic is held, the canary is checked every Interval
+	// while the traffic is held, when Interval is not set the canary is checked once
+	// at the end of the hold
+	Hold string `hcl:"hold" json:"hold" validate:"required,duration"`
 }
 
 var ErrInvalidInitialDelay = fmt.Errorf("InitialDelay is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
@@ -49,9 +88,14 @@ var ErrInvalidInitialTraffic = fmt.Errorf("InitialTraffic must contain a value b
 var ErrTrafficStep = fmt.Errorf("TrafficStep must contain a value between 1 and 100")
 var ErrMaxTraffic = fmt.Errorf("MaxTraffic must contain a value between 1 and 100")
 var ErrThreshold = fmt.Errorf("ErrorThreshold must contain a value greater than 0")
-
-func New(m interfaces.Monitor) (*Plugin, error) {
-	return &Plugin{monitoring: m}, nil
+var ErrOnFailed = fmt.Errorf("OnFailed must be one of fail, retry, or pause")
+var ErrOnNoMetrics = fmt.Errorf("OnNoMetrics must be one of fail, retry, or pause")
+var ErrOnError = fmt.Errorf("OnError must be one of fail, retry, or pause")
+var ErrMaxRetries = fmt.Errorf("MaxRetries must contain a value greater than or equal to 0")
+var ErrInvalidSteps = fmt.Errorf("Steps must contain a traffic value between 1 and 100 and a hold specified using Go duration format e.g (30s, 30ms, 60m)")
+
+func New(m interfaces.Monitor, c interfaces.Clock) (*Plugin, error) {
+	return &Plugin{monitoring: m, clock: c}, nil
 }
 
 // Configure the plugin with the given json
@@ -66,11 +110,16 @@ func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfa
 		return err
 	}
 
-	// if no initial delay use the interval
+	// if no initial delay use the interval, a config with only steps has no interval
+	// and starts the first step immediately
 	if p.config.InitialDelay == "" {
 		p.config.InitialDelay = p.config.Interval
 	}
 
+	if p.config.InitialDelay == "" {
+		p.config.InitialDelay = "0s"
+	}
+
 	// validate the plugin config
 	validate := validator.New()
 	validate.RegisterValidation("duration", interfaces.ValidateDuration)
@@ -78,6 +127,8 @@ func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfa
 
 	if err != nil {
 		errorMessage := ""
+		stepsInvalid := false
+
 		for _, err := range err.(validator.ValidationErrors) {
 			switch err.Namespace() {
 			case "PluginConfig.InitialDelay":
@@ -92,27 +143,44 @@ func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfa
 				errorMessage += ErrMaxTraffic.Error() + "\n"
 			case "PluginConfig.ErrorThreshold":
 				errorMessage += ErrThreshold.Error() + "\n"
+			case "PluginConfig.OnFailed":
+				errorMessage += ErrOnFailed.Error() + "\n"
+			case "PluginConfig.OnNoMetrics":
+				errorMessage += ErrOnNoMetrics.Error() + "\n"
+			case "PluginConfig.OnError":
+				errorMessage += ErrOnError.Error() + "\n"
+			case "PluginConfig.MaxRetries":
+				errorMessage += ErrMaxRetries.Error() + "\n"
+			default:
+				// only report invalid steps once regardless of how many steps fail validation
+				if strings.HasPrefix(err.Namespace(), "PluginConfig.Steps") && !stepsInvalid {
+					stepsInvalid = true
+					errorMessage += ErrInvalidSteps.Error() + "\n"
+				}
 			}
 		}
 
 		return fmt.Errorf(errorMessage)
 	}
 
+	if p.config.MaxRetries == nil {
+		ten := 10
+		p.config.MaxRetries = &ten
+	}
+
 	// load the state
 	p.state = &PluginState{}
 	d, err := store.GetState()
 	if err != nil {
 		log.Debug("Unable to load state", "error", err)
-		p.state.CandidateTraffic = -1
-		p.state.Status = interfaces.StrategyStatusSuccess
+		p.resetState()
 		return nil
 	}
 
 	err = json.Unmarshal(d, p.state)
 	if err != nil {
 		log.Debug("Unable to unmarshal state", "error", err)
-		p.state.CandidateTraffic = -1
-		p.state.Status = interfaces.StrategyStatusSuccess
+		p.resetState()
 	}
 
 	return nil
@@ -121,21 +189,21 @@ func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfa
 // Execute the strategy
 // interfaces.StrategyStatusSuccess and the percentage of traffic to set to the canditate returned on success of the checks
 // interfaces.StrategyStatusFail and the percentage of traffic to set to the canditate returned on failure of the checks
+// interfaces.StrategyStatusAwaitingPromotion and the max traffic returned when complete and ManualPromotion is set
+// interfaces.StrategyStatusPaused, the current traffic, and the check error are returned when a check fails with the pause policy
 // interfaces.StrategyStatusFail and an error is returned on an internal error
+// interfaces.StrategyStatusFail and the context error is returned when the context is cancelled, the progress is not changed
+//
+// When Steps are configured the traffic for each step is held for the duration of the step
+// before the traffic for the next step is returned.
 func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.StrategyStatus, int, error) {
-	p.log.Info("Executing strategy", "type", "canary", "traffic", p.state.CandidateTraffic)
+	p.log.Info("Executing strategy", "type", "canary", "traffic", p.state.CandidateTraffic, "step", p.state.Step)
 
 	// save the state on exit
 	defer p.saveState()
 
 	// if this is the first run set the initial traffic and return
 	if p.state.CandidateTraffic == -1 {
-		p.state.CandidateTraffic = p.config.InitialTraffic
-
-		if p.config.InitialTraffic == 0 {
-			p.state.CandidateTraffic = p.config.TrafficStep
-		}
-
 		d, err := time.ParseDuration(p.config.InitialDelay)
 		if err != nil {
 			p.state.Status = interfaces.StrategyStatusFailed
@@ -143,7 +211,22 @@ func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.
 		}
 
 		p.log.Debug("Waiting for initial grace before starting rollout", "type", "canary", "delay", d.Seconds())
-		time.Sleep(d)
+		err = p.sleep(ctx, d)
+		if err != nil {
+			return interfaces.StrategyStatusFailed, 0, err
+		}
+
+		if len(p.config.Steps) > 0 {
+			p.state.Step = 0
+			p.state.StepStarted = p.clock.Now()
+			p.state.CandidateTraffic = p.config.Steps[0].Traffic
+		} else {
+			p.state.CandidateTraffic = p.config.InitialTraffic
+
+			if p.config.InitialTraffic == 0 {
+				p.state.CandidateTraffic = p.config.TrafficStep
+			}
+		}
 
 		p.state.Status = interfaces.StrategyStatusSuccess
 
@@ -151,6 +234,10 @@ func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.
 		return interfaces.StrategyStatusSuccess, p.state.CandidateTraffic, nil
 	}
 
+	if len(p.config.Steps) > 0 {
+		return p.executeStep(ctx, candidateName)
+	}
+
 	// sleep for duration before checking
 	d, err := time.ParseDuration(p.config.Interval)
 	if err != nil {
@@ -159,22 +246,35 @@ func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.
 	}
 
 	failCount := 0
+	retries := 0
 	for {
-		time.Sleep(d)
+		err := p.sleep(ctx, d)
+		if err != nil {
+			return interfaces.StrategyStatusFailed, 0, err
+		}
 
-		queryCtx, done := context.WithTimeout(context.Background(), 30*time.Second)
-		defer done()
+		result, err := p.check(ctx, candidateName, d)
+		if err != nil {
+			// the strategy has been cancelled while checking, do not record the result
+			if ctx.Err() != nil {
+				return interfaces.StrategyStatusFailed, 0, ctx.Err()
+			}
 
-		p.log.Debug("Checking metrics", "type", "canary")
+			switch p.policy(result, retries) {
+			case PolicyRetry:
+				retries++
+				p.log.Debug("Retrying check", "type", "canary", "result", result, "retries", retries)
+				continue
+			case PolicyPause:
+				p.log.Info("Pausing strategy", "type", "canary", "result", result, "error", err)
+				p.state.Status = interfaces.StrategyStatusPaused
+				return interfaces.StrategyStatusPaused, p.state.CandidateTraffic, err
+			}
 
-		_, err := p.monitoring.Check(queryCtx, candidateName, d)
-		if err != nil {
-			p.log.Debug("Check failed", "type", "canary", "error", err)
 			failCount++
 
 			if failCount >= p.config.ErrorThreshold {
-				// reset the state
-				p.state.CandidateTraffic = -1
+				p.resetState()
 				p.state.Status = interfaces.StrategyStatusFailed
 				return interfaces.StrategyStatusFailed, 0, nil
 			}
@@ -188,12 +288,21 @@ func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.
 		p.state.Status = interfaces.StrategyStatusSuccess
 		p.saveState()
 
+		if p.state.CandidateTraffic >= p.config.MaxTraffic && p.config.ManualPromotion {
+			// strategy is complete but the release must be promoted manually, hold the
+			// traffic at the max traffic until promoted
+			p.log.Debug("Strategy complete, awaiting manual promotion", "type", "canary", "traffic", p.config.MaxTraffic)
+
+			p.resetState()
+			p.state.Status = interfaces.StrategyStatusAwaitingPromotion
+			return interfaces.StrategyStatusAwaitingPromotion, p.config.MaxTraffic, nil
+		}
+
 		if p.state.CandidateTraffic >= p.config.MaxTraffic {
 			// strategy is complete
 			p.log.Debug("Strategy complete", "type", "canary", "traffic", p.state.CandidateTraffic)
 
-			// reset the state
-			p.state.CandidateTraffic = -1
+			p.resetState()
 			p.state.Status = interfaces.StrategyStatusComplete
 			return interfaces.StrategyStatusComplete, 100, nil
 		}
@@ -205,6 +314,117 @@ func (p *Plugin) Execute(ctx context.Context, candidateName string) (interfaces.
 	}
 }
 
+// executeStep checks the canary until the traffic for the current step has been held
+// for the duration of the step, the traffic for the next step is then returned
+func (p *Plugin) executeStep(ctx context.Context, candidateName string) (interfaces.StrategyStatus, int, error) {
+	// the saved step may no longer exist if the steps have been changed since the state was saved
+	if p.state.Step >= len(p.config.Steps) {
+		p.state.Step = len(p.config.Steps) - 1
+	}
+
+	step := p.config.Steps[p.state.Step]
+
+	hold, err := time.ParseDuration(step.Hold)
+	if err != nil {
+		p.state.Status = interfaces.StrategyStatusFailed
+		return interfaces.StrategyStatusFailed, 0, fmt.Errorf("unable to parse hold: %s", err)
+	}
+
+	// when no interval is set check once at the end of the hold
+	interval := hold
+	if p.config.Interval != "" {
+		interval, err = time.ParseDuration(p.config.Interval)
+		if err != nil {
+			p.state.Status = interfaces.StrategyStatusFailed
+			return interfaces.StrategyStatusFailed, 0, fmt.Errorf("unable to parse interval: %s", err)
+		}
+	}
+
+	failCount := 0
+	retries := 0
+	for {
+		// do not wait past the end of the hold, once the hold has elapsed failed checks
+		// are retried every interval
+		d := interval
+		remaining := hold - p.clock.Now().Sub(p.state.StepStarted)
+		if remaining > 0 && remaining < d {
+			d = remaining
+		}
+
+		err := p.sleep(ctx, d)
+		if err != nil {
+			return interfaces.StrategyStatusFailed, 0, err
+		}
+
+		result, err := p.check(ctx, candidateName, interval)
+		if err != nil {
+			// the strategy has been cancelled while checking, do not record the result
+			if ctx.Err() != nil {
+				return interfaces.StrategyStatusFailed, 0, ctx.Err()
+			}
+
+			switch p.policy(result, retries) {
+			case PolicyRetry:
+				retries++
+				p.log.Debug("Retrying check", "type", "canary", "result", result, "retries", retries)
+				continue
+			case PolicyPause:
+				p.log.Info("Pausing strategy", "type", "canary", "result", result, "error", err)
+				p.state.Status = interfaces.StrategyStatusPaused
+				return interfaces.StrategyStatusPaused, p.state.CandidateTraffic, err
+			}
+
+			failCount++
+
+			if failCount >= p.config.ErrorThreshold {
+				p.resetState()
+				p.state.Status = interfaces.StrategyStatusFailed
+				return interfaces.StrategyStatusFailed, 0, nil
+			}
+
+			p.state.Status = interfaces.StrategyStatusFailing
+			p.saveState()
+			continue
+		}
+
+		failCount = 0
+		retries = 0
+		p.state.Status = interfaces.StrategyStatusSuccess
+		p.saveState()
+
+		// keep checking until the hold has elapsed
+		if p.clock.Now().Sub(p.state.StepStarted) < hold {
+			continue
+		}
+
+		// final step is complete
+		if p.state.Step >= len(p.config.Steps)-1 {
+			if p.config.ManualPromotion {
+				traffic := p.state.CandidateTraffic
+				p.log.Debug("Strategy complete, awaiting manual promotion", "type", "canary", "traffic", traffic)
+
+				p.resetState()
+				p.state.Status = interfaces.StrategyStatusAwaitingPromotion
+				return interfaces.StrategyStatusAwaitingPromotion, traffic, nil
+			}
+
+			p.log.Debug("Strategy complete", "type", "canary", "traffic", p.state.CandidateTraffic)
+
+			p.resetState()
+			p.state.Status = interfaces.StrategyStatusComplete
+			return interfaces.StrategyStatusComplete, 100, nil
+		}
+
+		// move to the next step
+		p.state.Step++
+		p.state.StepStarted = p.clock.Now()
+		p.state.CandidateTraffic = p.config.Steps[p.state.Step].Traffic
+
+		p.log.Debug("Strategy success", "type", "canary", "traffic", p.state.CandidateTraffic, "step", p.state.Step)
+		return interfaces.StrategyStatusSuccess, p.state.CandidateTraffic, nil
+	}
+}
+
 func (p *Plugin) GetPrimaryTraffic() int {
 	if p.state.CandidateTraffic < 0 {
 		return 100
@@ -229,6 +449,60 @@ func (p *Plugin) GetCandidateTraffic() int {
 	return p.state.CandidateTraffic
 }
 
+// check calls the monitor and returns the result, an error is returned when the check did not pass
+func (p *Plugin) check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	queryCtx, done := context.WithTimeout(ctx, 30*time.Second)
+	defer done()
+
+	p.log.Debug("Checking metrics", "type", "canary")
+
+	result, err := p.monitoring.Check(queryCtx, candidateName, interval)
+	if err != nil {
+		p.log.Debug("Check failed", "type", "canary", "result", result, "error", err)
+	}
+
+	return result, err
+}
+
+// policy returns the configured policy for a failed check result, the retry policy is replaced
+// by the fail policy once retries reaches MaxRetries
+func (p *Plugin) policy(result interfaces.CheckResult, retries int) string {
+	policy := ""
+
+	switch result {
+	case interfaces.CheckNoMetrics:
+		policy = p.config.OnNoMetrics
+	case interfaces.CheckError:
+		policy = p.config.OnError
+	default:
+		policy = p.config.OnFailed
+	}
+
+	if policy == "" {
+		return PolicyFail
+	}
+
+	if policy == PolicyRetry && retries >= *p.config.MaxRetries {
+		p.log.Debug("Retries exhausted, failing check", "type", "canary", "result", result, "max_retries", *p.config.MaxRetries)
+		return PolicyFail
+	}
+
+	return policy
+}
+
+// Reset clears the progress of the strategy so the next call to Execute starts a new roll out
+func (p *Plugin) Reset() {
+	p.resetState()
+	p.saveState()
+}
+
+func (p *Plugin) resetState() {
+	p.state.CandidateTraffic = -1
+	p.state.Step = 0
+	p.state.StepStarted = time.Time{}
+	p.state.Status = interfaces.StrategyStatusSuccess
+}
+
 func (p *Plugin) saveState() {
 	d, err := json.Marshal(p.state)
 	if err != nil {
@@ -241,3 +515,14 @@ func (p *Plugin) saveState() {
 		p.log.Error("Unable to save state", "error", err)
 	}
 }
+
+// sleep blocks for the given duration, returns the context error if the context is
+// cancelled before the duration elapses
+func (p *Plugin) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.clock.After(d):
+		return nil
+	}
+}
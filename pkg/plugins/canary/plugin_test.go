@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
 	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
@@ -18,7 +19,7 @@ func setupPlugin(t *testing.T, config string) (*Plugin, *mocks.MonitorMock) {
 	log := hclog.NewNullLogger()
 	_, m := mocks.BuildMocks(t)
 
-	p, _ := New(m.MonitorMock)
+	p, _ := New(m.MonitorMock, clock.NewReal())
 
 	err := p.Configure([]byte(config), log, m.StoreMock)
 	require.NoError(t, err)
@@ -33,7 +34,7 @@ func TestConfigureSetsState(t *testing.T) {
 	testutils.ClearMockCall(&m.StoreMock.Mock, "GetState")
 	m.StoreMock.On("GetState").Return([]byte(`{"candidate_traffic":10}`), nil)
 
-	p, _ := New(m.MonitorMock)
+	p, _ := New(m.MonitorMock, clock.NewReal())
 	err := p.Configure([]byte(canaryStrategy), log, m.StoreMock)
 
 	require.NoError(t, err)
@@ -47,7 +48,7 @@ func TestConfigureSetsDefaultStateOnError(t *testing.T) {
 	testutils.ClearMockCall(&m.StoreMock.Mock, "GetState")
 	m.StoreMock.On("GetState").Return(nil, fmt.Errorf("boom"))
 
-	p, _ := New(m.MonitorMock)
+	p, _ := New(m.MonitorMock, clock.NewReal())
 	err := p.Configure([]byte(canaryStrategy), log, m.StoreMock)
 
 	require.NoError(t, err)
@@ -63,7 +64,7 @@ func TestValidatesConfig(t *testing.T) {
 	log := hclog.NewNullLogger()
 	_, m := mocks.BuildMocks(t)
 
-	p, _ := New(m.MonitorMock)
+	p, _ := New(m.MonitorMock, clock.NewReal())
 
 	err := p.Configure([]byte(canaryStrategyWithValidationErrors), log, m.StoreMock)
 	require.Error(t, err)
@@ -75,6 +76,36 @@ func TestValidatesConfig(t *testing.T) {
 	require.Contains(t, err.Error(), ErrThreshold.Error())
 }
 
+func TestValidatesConfigWithSteps(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+
+	err := p.Configure([]byte(canaryStrategyWithInvalidSteps), log, m.StoreMock)
+	require.Error(t, err)
+
+	require.Contains(t, err.Error(), ErrInvalidSteps.Error())
+	require.NotContains(t, err.Error(), ErrTrafficStep.Error())
+	require.NotContains(t, err.Error(), ErrMaxTraffic.Error())
+}
+
+func TestConfigureWithStepsDoesNotRequireTrafficStep(t *testing.T) {
+	p, _ := setupPlugin(t, canaryStrategyWithSteps)
+	require.Len(t, p.config.Steps, 3)
+}
+
+func TestConfigureWithOnlyStepsDefaultsInitialDelay(t *testing.T) {
+	p, _ := setupPlugin(t, canaryStrategyWithOnlySteps)
+	require.Equal(t, "0s", p.config.InitialDelay)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 10, traffic)
+}
+
 func TestSetsInitialTrafficAndReturnsFirstRun(t *testing.T) {
 	p, mm := setupPlugin(t, canaryStrategy)
 
@@ -140,6 +171,19 @@ func TestExecuteReturnsCompleteWhenAllChecksComplete(t *testing.T) {
 	require.Equal(t, 100, traffic)
 }
 
+func TestExecuteReturnsAwaitingPromotionWhenManualPromotion(t *testing.T) {
+	p, _ := setupPlugin(t, canaryStrategyWithManualPromotion)
+	p.state.CandidateTraffic = 70
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusAwaitingPromotion, string(status))
+	require.Equal(t, 90, traffic)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+	require.Equal(t, interfaces.StrategyStatusAwaitingPromotion, p.state.Status)
+}
+
 func TestReturnsErrorWhenChecksFail(t *testing.T) {
 	p, mm := setupPlugin(t, canaryStrategy)
 	testutils.ClearMockCall(&mm.Mock, "Check")
@@ -157,6 +201,239 @@ func TestReturnsErrorWhenChecksFail(t *testing.T) {
 	mm.AssertNumberOfCalls(t, "Check", 5)
 }
 
+func TestValidatesConfigPolicies(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+
+	err := p.Configure([]byte(canaryStrategyWithInvalidPolicies), log, m.StoreMock)
+	require.Error(t, err)
+
+	require.Contains(t, err.Error(), ErrOnFailed.Error())
+	require.Contains(t, err.Error(), ErrOnNoMetrics.Error())
+	require.Contains(t, err.Error(), ErrOnError.Error())
+	require.Contains(t, err.Error(), ErrMaxRetries.Error())
+}
+
+func TestExecuteRetriesWithoutCountingWhenNoMetricsAndPolicyRetry(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategyWithPolicies)
+	p.state.CandidateTraffic = 10
+
+	// return no metrics for more checks than the error threshold before succeeding
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckNoMetrics, fmt.Errorf("no metrics")).Times(3)
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckSuccess, nil)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 30, traffic)
+	mm.AssertNumberOfCalls(t, "Check", 4)
+}
+
+func TestExecuteFailsWhenRetriesExhausted(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategyWithPolicies)
+	p.state.CandidateTraffic = 10
+	three := 3
+	p.config.MaxRetries = &three
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckNoMetrics, fmt.Errorf("no metrics"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusFailed, string(status))
+	require.Equal(t, 0, traffic)
+
+	// 3 retries followed by 2 checks counted towards the error threshold
+	mm.AssertNumberOfCalls(t, "Check", 5)
+}
+
+func TestExecuteReturnsPausedWhenErrorAndPolicyPause(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategyWithPolicies)
+	p.state.CandidateTraffic = 10
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckError, fmt.Errorf("boom"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.Error(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusPaused, string(status))
+	require.Equal(t, 10, traffic)
+	require.Equal(t, 10, p.state.CandidateTraffic)
+	mm.AssertNumberOfCalls(t, "Check", 1)
+}
+
+func TestExecuteCountsFailedChecksWhenPolicyFail(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategyWithPolicies)
+	p.state.CandidateTraffic = 10
+
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckFailed, fmt.Errorf("boom"))
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusFailed, string(status))
+	require.Equal(t, 0, traffic)
+	mm.AssertNumberOfCalls(t, "Check", 2)
+}
+
+func TestExecuteDoesNotChangeTrafficWhenCancelled(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategy)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err = p.Execute(ctx, "test-deployment")
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Equal(t, 10, p.GetCandidateTraffic())
+	mm.AssertNotCalled(t, "Check", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResetStartsNewRollout(t *testing.T) {
+	p, _ := setupPlugin(t, canaryStrategy)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	p.Reset()
+	require.Equal(t, -1, p.state.CandidateTraffic)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 10, traffic)
+}
+
+func TestSetsFirstStepTrafficAndReturnsFirstRun(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategyWithSteps)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 1, traffic)
+	require.Equal(t, 0, p.state.Step)
+	require.False(t, p.state.StepStarted.IsZero())
+
+	mm.AssertNotCalled(t, "Check", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExecuteHoldsStepAndChecksEveryInterval(t *testing.T) {
+	st := time.Now()
+	p, mm := setupPlugin(t, canaryStrategyWithSteps)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 5, traffic)
+	require.Equal(t, 1, p.state.Step)
+
+	// hold of 60ms with an interval of 20ms
+	mm.AssertNumberOfCalls(t, "Check", 3)
+	require.Greater(t, time.Since(st), 60*time.Millisecond, "Execute should hold the step traffic")
+}
+
+func TestExecuteChecksOnceAtEndOfHoldWhenNoInterval(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategyWithStepsWithoutInterval)
+
+	_, _, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 50, traffic)
+
+	mm.AssertCalled(t, "Check", mock.Anything, mock.Anything, 30*time.Millisecond)
+	mm.AssertNumberOfCalls(t, "Check", 1)
+}
+
+func TestExecuteContinuesFromSavedStep(t *testing.T) {
+	log := hclog.NewNullLogger()
+	_, m := mocks.BuildMocks(t)
+
+	testutils.ClearMockCall(&m.StoreMock.Mock, "GetState")
+	m.StoreMock.On("GetState").Return([]byte(`{"candidate_traffic":5,"step":1,"step_started":"2022-01-01T00:00:00Z"}`), nil)
+
+	p, _ := New(m.MonitorMock, clock.NewReal())
+	err := p.Configure([]byte(canaryStrategyWithSteps), log, m.StoreMock)
+	require.NoError(t, err)
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	// the hold for the saved step has elapsed, a single check moves to the next step
+	require.Equal(t, interfaces.StrategyStatusSuccess, string(status))
+	require.Equal(t, 25, traffic)
+	require.Equal(t, 2, p.state.Step)
+	m.MonitorMock.AssertNumberOfCalls(t, "Check", 1)
+}
+
+func TestExecuteReturnsCompleteAfterFinalStep(t *testing.T) {
+	p, _ := setupPlugin(t, canaryStrategyWithSteps)
+	p.state.CandidateTraffic = 25
+	p.state.Step = 2
+	p.state.StepStarted = time.Now()
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusComplete, string(status))
+	require.Equal(t, 100, traffic)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+	require.Equal(t, 0, p.state.Step)
+}
+
+func TestExecuteReturnsAwaitingPromotionAfterFinalStepWhenManualPromotion(t *testing.T) {
+	p, _ := setupPlugin(t, canaryStrategyWithStepsWithoutInterval)
+	p.config.ManualPromotion = true
+	p.state.CandidateTraffic = 50
+	p.state.Step = 1
+	p.state.StepStarted = time.Now()
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusAwaitingPromotion, string(status))
+	require.Equal(t, 50, traffic)
+}
+
+func TestExecuteWithStepsReturnsFailedWhenChecksFail(t *testing.T) {
+	p, mm := setupPlugin(t, canaryStrategyWithSteps)
+	testutils.ClearMockCall(&mm.Mock, "Check")
+	mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckFailed, fmt.Errorf("boom"))
+
+	p.state.CandidateTraffic = 5
+	p.state.Step = 1
+	p.state.StepStarted = time.Now()
+
+	status, traffic, err := p.Execute(context.Background(), "test-deployment")
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StrategyStatusFailed, string(status))
+	require.Equal(t, 0, traffic)
+	require.Equal(t, -1, p.state.CandidateTraffic)
+	require.Equal(t, 0, p.state.Step)
+
+	mm.AssertNumberOfCalls(t, "Check", 2)
+}
+
 func TestGetPrimaryTrafficReturns100WhenMinusOne(t *testing.T) {
 	p, _ := setupPlugin(t, canaryStrategy)
 
@@ -230,6 +507,18 @@ const canaryStrategy = `
 }
 `
 
+const canaryStrategyWithManualPromotion = `
+{
+  "interval": "30ms",
+  "initial_traffic": 10,
+  "initial_delay": "30ms",
+  "traffic_step": 20,
+  "max_traffic": 90,
+  "error_threshold": 5,
+  "manual_promotion": true
+}
+`
+
 const canaryStrategyWithoutInitialTraffic = `
 {
   "interval": "30ms",
@@ -249,3 +538,74 @@ const canaryStrategyWithValidationErrors = `
   "error_threshold": -1
 }
 `
+
+const canaryStrategyWithSteps = `
+{
+  "interval": "20ms",
+  "initial_delay": "10ms",
+  "error_threshold": 2,
+  "steps": [
+    {"traffic": 1, "hold": "60ms"},
+    {"traffic": 5, "hold": "60ms"},
+    {"traffic": 25, "hold": "60ms"}
+  ]
+}
+`
+
+const canaryStrategyWithStepsWithoutInterval = `
+{
+  "initial_delay": "10ms",
+  "error_threshold": 2,
+  "steps": [
+    {"traffic": 10, "hold": "30ms"},
+    {"traffic": 50, "hold": "30ms"}
+  ]
+}
+`
+
+const canaryStrategyWithOnlySteps = `
+{
+  "error_threshold": 2,
+  "steps": [
+    {"traffic": 10, "hold": "30ms"},
+    {"traffic": 50, "hold": "30ms"}
+  ]
+}
+`
+
+const canaryStrategyWithInvalidSteps = `
+{
+  "interval": "20ms",
+  "error_threshold": 2,
+  "steps": [
+    {"traffic": 0, "hold": "60ms"},
+    {"traffic": 5, "hold": "abc"}
+  ]
+}
+`
+
+const canaryStrategyWithPolicies = `
+{
+  "interval": "10ms",
+  "initial_traffic": 10,
+  "traffic_step": 20,
+  "max_traffic": 90,
+  "error_threshold": 2,
+  "on_failed": "fail",
+  "on_no_metrics": "retry",
+  "on_error": "pause"
+}
+`
+
+const canaryStrategyWithInvalidPolicies = `
+{
+  "interval": "10ms",
+  "traffic_step": 20,
+  "max_traffic": 90,
+  "error_threshold": 2,
+  "on_failed": "ignore",
+  "on_no_metrics": "wait",
+  "on_error": "alert",
+  "max_retries": -1
+}
+`
@@ -4,16 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/go-hclog"
 	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/sethvargo/go-retry"
 )
 
-var syncDelay = 1 * time.Second
+// convergenceTimeout is the maximum time to wait for the service mesh to apply a configuration
+// change, when the change can not be confirmed before the timeout the release continues
+var convergenceTimeout = 30 * time.Second
 
 type Plugin struct {
 	log          hclog.Logger
@@ -92,7 +96,10 @@ func (p *Plugin) Setup(ctx context.Context, primarySubsetFilter, candidateSubset
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	// create the service defaults for the controller and the virtual service that allows
 	// access to candidate deployments
@@ -103,7 +110,10 @@ func (p *Plugin) Setup(ctx context.Context, primarySubsetFilter, candidateSubset
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	err = p.consulClient.CreateServiceDefaults(clients.UpstreamRouterName)
 	if err != nil {
@@ -112,7 +122,10 @@ func (p *Plugin) Setup(ctx context.Context, primarySubsetFilter, candidateSubset
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	// create the service resolver
 	p.log.Debug("Create service resolver", "service", p.config.ConsulService)
@@ -123,7 +136,10 @@ func (p *Plugin) Setup(ctx context.Context, primarySubsetFilter, candidateSubset
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	// create the service router to enable post deployment tests
 	p.log.Debug("Create upstream service router", "service", p.config.ConsulService)
@@ -134,7 +150,10 @@ func (p *Plugin) Setup(ctx context.Context, primarySubsetFilter, candidateSubset
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	// create the service intentions to allow an upstream from the controller to
 	p.log.Debug("Create service intentions for the upstreams", "service", p.config.ConsulService)
@@ -145,6 +164,11 @@ func (p *Plugin) Setup(ctx context.Context, primarySubsetFilter, candidateSubset
 		return err
 	}
 
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -162,6 +186,52 @@ func (p *Plugin) Scale(ctx context.Context, value int) error {
 		return err
 	}
 
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Plugin) Route(ctx context.Context, matches []interfaces.RouteMatch) error {
+	if len(matches) == 0 {
+		p.log.Info("Remove candidate routes", "name", p.config.ConsulService)
+
+		err := p.consulClient.DeleteServiceRouter(p.config.ConsulService)
+		if err != nil {
+			p.log.Error("Unable to delete Consul ServiceRouter", "name", p.config.ConsulService, "error", err)
+
+			return err
+		}
+
+		err = p.waitForConvergence(ctx)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	p.log.Info("Route requests to candidate", "name", p.config.ConsulService, "routes", len(matches))
+
+	httpMatches := []api.ServiceRouteHTTPMatch{}
+	for _, m := range matches {
+		httpMatches = append(httpMatches, routeHTTPMatch(m))
+	}
+
+	err := p.consulClient.CreateServiceRouter(p.config.ConsulService, httpMatches)
+	if err != nil {
+		p.log.Error("Unable to create Consul ServiceRouter", "name", p.config.ConsulService, "error", err)
+
+		return err
+	}
+
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -176,7 +246,24 @@ func (p *Plugin) Destroy(ctx context.Context) error {
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
+
+	// delete will only remove the candidate and primary routes if this plugin did not create the router
+	p.log.Debug("Cleanup service router", "name", p.config.ConsulService)
+	err = p.consulClient.DeleteServiceRouter(p.config.ConsulService)
+	if err != nil {
+		p.log.Error("Unable to delete Consul ServiceRouter", "name", p.config.ConsulService, "error", err)
+
+		return err
+	}
+
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	p.log.Debug("Cleanup upstream router", "name", p.config.ConsulService)
 	err = p.consulClient.DeleteUpstreamRouter(p.config.ConsulService)
@@ -186,7 +273,10 @@ func (p *Plugin) Destroy(ctx context.Context) error {
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	p.log.Debug("Cleanup resolver", "name", p.config.ConsulService)
 	err = p.consulClient.DeleteServiceResolver(p.config.ConsulService)
@@ -196,7 +286,10 @@ func (p *Plugin) Destroy(ctx context.Context) error {
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	// delete will only happen if this plugin created the defaults
 	p.log.Debug("Cleanup service intentions", "name", p.config.ConsulService)
@@ -207,7 +300,10 @@ func (p *Plugin) Destroy(ctx context.Context) error {
 		return err
 	}
 
-	time.Sleep(syncDelay)
+	err = p.waitForConvergence(ctx)
+	if err != nil {
+		return err
+	}
 
 	// delete will only happen if this plugin created the defaults
 	p.log.Debug("Cleanup defaults", "name", p.config.ConsulService)
@@ -243,3 +339,64 @@ func (p *Plugin) WaitUntilServiceHealthy(ctx context.Context, filter string) err
 
 	return err
 }
+
+// waitForConvergence blocks until the local Consul agent has applied the latest config entry changes
+// for the service, each change is given its own convergence timeout, if the changes can not be confirmed
+// before the timeout a warning is logged and the release continues. When the context is cancelled or
+// expires the error from the context is returned
+func (p *Plugin) waitForConvergence(ctx context.Context) error {
+	wctx, cancel := context.WithTimeout(ctx, convergenceTimeout)
+	defer cancel()
+
+	st := time.Now()
+
+	err := p.consulClient.WaitUntilConfigApplied(wctx, p.config.ConsulService)
+	if err != nil {
+		// the operation has been cancelled, the caller must not assume the change has been applied
+		if ctx.Err() != nil {
+			p.log.Error("Cancelled waiting for Consul config to be applied", "name", p.config.ConsulService, "error", ctx.Err())
+			return ctx.Err()
+		}
+
+		p.log.Warn("Unable to confirm Consul config has been applied, continuing", "name", p.config.ConsulService, "error", err)
+		return nil
+	}
+
+	p.log.Debug("Consul config applied", "name", p.config.ConsulService, "duration", time.Since(st).String())
+
+	return nil
+}
+
+// routeHTTPMatch converts a RouteMatch into a Consul HTTP route match, Consul does not have
+// a native cookie match so cookies are matched using a regular expression on the Cookie header
+func routeHTTPMatch(m interfaces.RouteMatch) api.ServiceRouteHTTPMatch {
+	switch m.Type {
+	case interfaces.RouteMatchTypeQuery:
+		qm := api.ServiceRouteHTTPMatchQueryParam{Name: m.Name, Exact: m.Exact, Regex: m.Regex}
+		qm.Present = m.Exact == "" && m.Regex == ""
+
+		return api.ServiceRouteHTTPMatch{QueryParam: []api.ServiceRouteHTTPMatchQueryParam{qm}}
+
+	case interfaces.RouteMatchTypeCookie:
+		value := ".*"
+		if m.Exact != "" {
+			value = regexp.QuoteMeta(m.Exact)
+		}
+
+		if m.Regex != "" {
+			value = m.Regex
+		}
+
+		hm := api.ServiceRouteHTTPMatchHeader{
+			Name:  "cookie",
+			Regex: fmt.Sprintf(`(^|.*;\s*)%s=(%s)(;.*|$)`, regexp.QuoteMeta(m.Name), value),
+		}
+
+		return api.ServiceRouteHTTPMatch{Header: []api.ServiceRouteHTTPMatchHeader{hm}}
+	}
+
+	hm := api.ServiceRouteHTTPMatchHeader{Name: m.Name, Exact: m.Exact, Regex: m.Regex}
+	hm.Present = m.Exact == "" && m.Regex == ""
+
+	return api.ServiceRouteHTTPMatch{Header: []api.ServiceRouteHTTPMatchHeader{hm}}
+}
@@ -7,8 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/go-hclog"
 	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
 	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
 	"github.com/stretchr/testify/assert"
@@ -17,8 +19,8 @@ import (
 )
 
 func setupPlugin(t *testing.T) (*Plugin, *clients.ConsulMock) {
-	// ensure the sync delay is set to a value for testing
-	syncDelay = 1 * time.Millisecond
+	// ensure the convergence timeout is set to a value for testing
+	convergenceTimeout = 10 * time.Millisecond
 
 	log := hclog.NewNullLogger()
 	mc := &clients.ConsulMock{}
@@ -28,12 +30,16 @@ func setupPlugin(t *testing.T) (*Plugin, *clients.ConsulMock) {
 	mc.On("CreateUpstreamRouter", mock.Anything, mock.Anything).Return(nil)
 	mc.On("CreateServiceSplitter", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mc.On("CreateServiceIntention", mock.Anything, mock.Anything).Return(nil)
+	mc.On("CreateServiceRouter", mock.Anything, mock.Anything).Return(nil)
 
 	mc.On("DeleteServiceSplitter", mock.Anything).Return(nil)
 	mc.On("DeleteServiceDefaults", mock.Anything).Return(nil)
 	mc.On("DeleteServiceResolver", mock.Anything).Return(nil)
 	mc.On("DeleteUpstreamRouter", mock.Anything).Return(nil)
 	mc.On("DeleteServiceIntention", mock.Anything).Return(nil)
+	mc.On("DeleteServiceRouter", mock.Anything).Return(nil)
+
+	mc.On("WaitUntilConfigApplied", mock.Anything, mock.Anything).Return(nil)
 
 	data := testutils.GetTestData(t, "valid_kubernetes_release.json")
 	dep := map[string]interface{}{}
@@ -126,6 +132,39 @@ func TestSetupFailsOnCreateServiceDefaultsForUpstreamError(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestSetupWaitsForConvergenceOfEachChange(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	// the config is never applied, each wait returns when its timeout is reached
+	testutils.ClearMockCall(&mc.Mock, "WaitUntilConfigApplied")
+	mc.On("WaitUntilConfigApplied", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(context.DeadlineExceeded)
+
+	err := p.Setup(context.Background(), "primary", "candidate")
+	require.NoError(t, err)
+
+	mc.AssertCalled(t, "CreateServiceIntention", "api")
+	mc.AssertNumberOfCalls(t, "WaitUntilConfigApplied", 6)
+}
+
+func TestSetupReturnsErrorWhenCancelledWaitingForConvergence(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	testutils.ClearMockCall(&mc.Mock, "WaitUntilConfigApplied")
+	mc.On("WaitUntilConfigApplied", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		cancel()
+	}).Return(context.Canceled)
+
+	err := p.Setup(ctx, "primary", "candidate")
+	require.ErrorIs(t, err, context.Canceled)
+
+	mc.AssertNotCalled(t, "CreateServiceIntention", "api")
+	mc.AssertNumberOfCalls(t, "WaitUntilConfigApplied", 1)
+}
+
 func TestSetupCreatesConsulServiceResolver(t *testing.T) {
 	p, mc := setupPlugin(t)
 
@@ -190,6 +229,44 @@ func TestScaleUpdatesServiceSplitter(t *testing.T) {
 	require.NoError(t, err)
 
 	mc.AssertCalled(t, "CreateServiceSplitter", "api", 20, 80)
+	mc.AssertCalled(t, "WaitUntilConfigApplied", mock.Anything, "api")
+}
+
+func TestScaleContinuesWhenConfigCanNotBeConfirmed(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	testutils.ClearMockCall(&mc.Mock, "WaitUntilConfigApplied")
+	mc.On("WaitUntilConfigApplied", mock.Anything, mock.Anything).Return(context.DeadlineExceeded)
+
+	err := p.Scale(context.Background(), 80)
+	require.NoError(t, err)
+
+	mc.AssertCalled(t, "CreateServiceSplitter", "api", 20, 80)
+}
+
+func TestScaleReturnsErrorWhenCancelledWaitingForConvergence(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testutils.ClearMockCall(&mc.Mock, "WaitUntilConfigApplied")
+	mc.On("WaitUntilConfigApplied", mock.Anything, mock.Anything).Return(context.Canceled)
+
+	err := p.Scale(ctx, 80)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestScaleDoesNotWaitOnUpdateError(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	testutils.ClearMockCall(&mc.Mock, "CreateServiceSplitter")
+	mc.On("CreateServiceSplitter", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.Scale(context.Background(), 80)
+	require.Error(t, err)
+
+	mc.AssertNotCalled(t, "WaitUntilConfigApplied", mock.Anything, mock.Anything)
 }
 
 func TestScaleReturnsErrorOnUpdateError(t *testing.T) {
@@ -202,6 +279,62 @@ func TestScaleReturnsErrorOnUpdateError(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestRouteCreatesServiceRouterWithMatches(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	matches := []interfaces.RouteMatch{
+		{Type: interfaces.RouteMatchTypeHeader, Name: "x-beta", Exact: "true"},
+		{Type: interfaces.RouteMatchTypeQuery, Name: "beta"},
+		{Type: interfaces.RouteMatchTypeCookie, Name: "group", Exact: "beta.users"},
+	}
+
+	err := p.Route(context.Background(), matches)
+	require.NoError(t, err)
+
+	mc.AssertCalled(t, "CreateServiceRouter", "api", mock.Anything)
+
+	httpMatches := mc.Calls[0].Arguments.Get(1).([]api.ServiceRouteHTTPMatch)
+	require.Len(t, httpMatches, 3)
+
+	require.Equal(t, "x-beta", httpMatches[0].Header[0].Name)
+	require.Equal(t, "true", httpMatches[0].Header[0].Exact)
+
+	require.Equal(t, "beta", httpMatches[1].QueryParam[0].Name)
+	require.True(t, httpMatches[1].QueryParam[0].Present)
+
+	require.Equal(t, "cookie", httpMatches[2].Header[0].Name)
+	require.Equal(t, `(^|.*;\s*)group=(beta\.users)(;.*|$)`, httpMatches[2].Header[0].Regex)
+}
+
+func TestRouteWithNoMatchesDeletesServiceRouter(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	err := p.Route(context.Background(), nil)
+	require.NoError(t, err)
+
+	mc.AssertCalled(t, "DeleteServiceRouter", "api")
+	mc.AssertNotCalled(t, "CreateServiceRouter", mock.Anything, mock.Anything)
+}
+
+func TestRouteReturnsErrorOnCreateError(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	testutils.ClearMockCall(&mc.Mock, "CreateServiceRouter")
+	mc.On("CreateServiceRouter", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	err := p.Route(context.Background(), []interfaces.RouteMatch{{Type: interfaces.RouteMatchTypeHeader, Name: "x-beta"}})
+	require.Error(t, err)
+}
+
+func TestDestroyDeletesServiceRouter(t *testing.T) {
+	p, mc := setupPlugin(t)
+
+	err := p.Destroy(context.Background())
+	require.NoError(t, err)
+
+	mc.AssertCalled(t, "DeleteServiceRouter", "api")
+}
+
 func TestDestroyDeletesServiceSplitter(t *testing.T) {
 	p, mc := setupPlugin(t)
 
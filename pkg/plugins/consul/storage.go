@@ -24,6 +24,7 @@ type Storage struct {
 const basePath = "consul-release-controller/releases"
 const configPath = "config"
 const pluginPath = "plugin-state"
+const deploymentsPath = "deployments"
 
 func NewStorage(l hclog.Logger) (*Storage, error) {
 	opts := &clients.ConsulOptions{}
@@ -120,6 +121,76 @@ func (s *Storage) DeleteRelease(name string) error {
 	return s.consulClient.DeleteKV(path)
 }
 
+// AppendDeployment adds the deployment record to the history for the named release, the oldest
+// records are removed when the history has more than models.MaxDeployments records
+func (s *Storage) AppendDeployment(releaseName string, d *models.Deployment) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("Appending deployment in Consul", "name", releaseName, "id", d.ID)
+
+	err = s.consulClient.SetKV(deploymentPath(releaseName, d.ID), data)
+	if err != nil {
+		return err
+	}
+
+	// the deployment has been recorded, failing to remove old records only delays the removal
+	// until the next deployment is added
+	keys, err := s.consulClient.ListKV(deploymentPath(releaseName, ""))
+	if err != nil {
+		s.log.Error("Unable to list deployments to remove old records", "name", releaseName, "error", err)
+		return nil
+	}
+
+	for i := 0; i < len(keys)-models.MaxDeployments; i++ {
+		s.log.Debug("Removing old deployment from Consul", "name", releaseName, "path", keys[i])
+
+		err := s.consulClient.DeleteKV(keys[i])
+		if err != nil {
+			s.log.Error("Unable to remove old deployment", "name", releaseName, "path", keys[i], "error", err)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// ListDeployments returns the deployment history for the named release ordered from the
+// oldest to the newest deployment, an empty list is returned when the release has no history
+func (s *Storage) ListDeployments(releaseName string) ([]*models.Deployment, error) {
+	// deployment ids sort in the order the deployments were started, Consul returns keys in
+	// lexical order
+	keys, err := s.consulClient.ListKV(deploymentPath(releaseName, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := []*models.Deployment{}
+	for _, k := range keys {
+		d, err := s.consulClient.GetKV(k)
+		if err != nil {
+			return nil, err
+		}
+
+		// the key has been removed since listing
+		if len(d) == 0 {
+			continue
+		}
+
+		dep := &models.Deployment{}
+		err = json.Unmarshal(d, dep)
+		if err != nil {
+			return nil, err
+		}
+
+		deployments = append(deployments, dep)
+	}
+
+	return deployments, nil
+}
+
 func (s *Storage) UpsertState(data []byte) error {
 	if s.pluginName == "" || s.releaseName == "" {
 		return fmt.Errorf("storage incorrectly configured, no pluginName or releaseName")
@@ -162,6 +233,12 @@ func releaseConfigPath(name string) string {
 	return fmt.Sprintf("%s/%s/%s", basePath, name, configPath)
 }
 
+// deploymentPath is a helper that returns the path for the deployment with the given id,
+// when id is empty the path containing all deployments for the release is returned
+func deploymentPath(name, id string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", basePath, name, deploymentsPath, id)
+}
+
 func releasePath(name string) string {
 	return fmt.Sprintf("%s/%s", basePath, name)
 }
@@ -172,3 +172,65 @@ func TestGetStateRetrunsErrorWhenNoState(t *testing.T) {
 
 	mc.AssertCalled(t, "GetKV", "consul-release-controller/releases/api/plugin-state/test-plugin", mock.Anything)
 }
+
+func TestAppendDeploymentSetsDeployment(t *testing.T) {
+	s, _, mc := testSetupStorage(t)
+
+	mc.On("SetKV", mock.Anything, mock.Anything).Return(nil)
+	mc.On("ListKV", mock.Anything).Return([]string{"consul-release-controller/releases/api/deployments/00000000000000000001"}, nil)
+
+	err := s.AppendDeployment("api", &models.Deployment{ID: "00000000000000000001"})
+	require.NoError(t, err)
+
+	mc.AssertCalled(t, "SetKV", "consul-release-controller/releases/api/deployments/00000000000000000001", mock.Anything)
+	mc.AssertNotCalled(t, "DeleteKV", mock.Anything)
+}
+
+func TestAppendDeploymentRemovesOldestDeploymentsOverLimit(t *testing.T) {
+	s, _, mc := testSetupStorage(t)
+
+	keys := []string{}
+	for i := 1; i <= models.MaxDeployments+2; i++ {
+		keys = append(keys, fmt.Sprintf("consul-release-controller/releases/api/deployments/%020d", i))
+	}
+
+	mc.On("SetKV", mock.Anything, mock.Anything).Return(nil)
+	mc.On("ListKV", mock.Anything).Return(keys, nil)
+	mc.On("DeleteKV", mock.Anything).Return(nil)
+
+	err := s.AppendDeployment("api", &models.Deployment{ID: fmt.Sprintf("%020d", models.MaxDeployments+2)})
+	require.NoError(t, err)
+
+	mc.AssertNumberOfCalls(t, "DeleteKV", 2)
+	mc.AssertCalled(t, "DeleteKV", "consul-release-controller/releases/api/deployments/00000000000000000001")
+	mc.AssertCalled(t, "DeleteKV", "consul-release-controller/releases/api/deployments/00000000000000000002")
+}
+
+func TestListDeploymentsReturnsDeployments(t *testing.T) {
+	s, _, mc := testSetupStorage(t)
+
+	mc.On("ListKV", mock.Anything).Return([]string{
+		"consul-release-controller/releases/api/deployments/00000000000000000001",
+		"consul-release-controller/releases/api/deployments/00000000000000000002",
+	}, nil)
+	mc.On("GetKV", "consul-release-controller/releases/api/deployments/00000000000000000001").Return([]byte(`{"id": "1"}`), nil)
+	mc.On("GetKV", "consul-release-controller/releases/api/deployments/00000000000000000002").Return([]byte(`{"id": "2"}`), nil)
+
+	deps, err := s.ListDeployments("api")
+	require.NoError(t, err)
+
+	mc.AssertCalled(t, "ListKV", "consul-release-controller/releases/api/deployments/")
+
+	require.Len(t, deps, 2)
+	require.Equal(t, "1", deps[0].ID)
+	require.Equal(t, "2", deps[1].ID)
+}
+
+func TestListDeploymentsReturnsErrorOnConsulError(t *testing.T) {
+	s, _, mc := testSetupStorage(t)
+
+	mc.On("ListKV", mock.Anything).Return(nil, fmt.Errorf("boom"))
+
+	_, err := s.ListDeployments("api")
+	require.Error(t, err)
+}
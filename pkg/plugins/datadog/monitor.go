@@ -0,0 +1,346 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/config"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/presets"
+)
+
+// DefaultAddress is the Datadog API used when the monitor does not define an address
+const DefaultAddress = "https://api.datadoghq.com"
+
+type Plugin struct {
+	log          hclog.Logger
+	config       *PluginConfig
+	store        interfaces.PluginStateStore
+	client       *http.Client
+	consulClient clients.Consul
+	runtime      string
+	name         string
+	namespace    string
+
+	// presets are the preset queries for the runtime, keyed by name
+	presets map[string]string
+}
+
+// PluginConfig for the Datadog monitor, keys are read from a file or an environment variable and
+// can not be specified inline
+type PluginConfig struct {
+	// Address of the Datadog API, defaults to https://api.datadoghq.com
+	Address string `json:"address" validate:"omitempty,url"`
+
+	// APIKeyFile is the path of a file containing the API key, the file is read for every query
+	APIKeyFile string `json:"api_key_file,omitempty" validate:"excluded_with=APIKeyEnv"`
+
+	// APIKeyEnv is the name of an environment variable containing the API key, defaults to DD_API_KEY
+	// when APIKeyFile is not specified
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+
+	// ApplicationKeyFile is the path of a file containing the application key, the file is read for every query
+	ApplicationKeyFile string `json:"application_key_file,omitempty" validate:"excluded_with=ApplicationKeyEnv"`
+
+	// ApplicationKeyEnv is the name of an environment variable containing the application key, defaults
+	// to DD_APP_KEY when ApplicationKeyFile is not specified
+	ApplicationKeyEnv string `json:"application_key_env,omitempty"`
+
+	Queries []Query `json:"queries"`
+}
+
+// Query config
+type Query struct {
+	// Name of the query
+	Name string `json:"name"`
+
+	// Preset is an optional default metric query
+	Preset string `json:"preset"`
+
+	// Query is an optional query when the preset is not specified
+	Query string `json:"query"`
+
+	// Minimum value for success, optional when Max specified
+	Min *float64 `json:"min,omitempty"` // default 0
+
+	// Maximum value for success, optional when Min specified
+	Max *float64 `json:"max,omitempty"` // default 0
+}
+
+// queryResponse is the response from the Datadog v1 query API
+type queryResponse struct {
+	Status string   `json:"status"`
+	Error  string   `json:"error"`
+	Series []series `json:"series"`
+}
+
+type series struct {
+	Metric    string       `json:"metric"`
+	Scope     string       `json:"scope"`
+	Pointlist [][]*float64 `json:"pointlist"`
+}
+
+func New(name, namespace, runtime string, l hclog.Logger) (*Plugin, error) {
+	cc, _ := clients.NewConsul(nil)
+
+	return &Plugin{
+		log:          l,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		consulClient: cc,
+		runtime:      runtime,
+		name:         name,
+		namespace:    namespace,
+	}, nil
+}
+
+var ErrInvalidAddress = fmt.Errorf("Address is not a valid URL, please specify the address of the Datadog API e.g. (https://api.datadoghq.eu)")
+var ErrInvalidAPIKey = fmt.Errorf("APIKey is a required field, please specify api_key_file or api_key_env, or set the environment variable DD_API_KEY")
+var ErrInvalidApplicationKey = fmt.Errorf("ApplicationKey is a required field, please specify application_key_file or application_key_env, or set the environment variable DD_APP_KEY")
+var ErrInvalidAPIKeyFile = fmt.Errorf("APIKeyFile can not be specified with APIKeyEnv, please specify only one of api_key_file or api_key_env")
+var ErrInvalidApplicationKeyFile = fmt.Errorf("ApplicationKeyFile can not be specified with ApplicationKeyEnv, please specify only one of application_key_file or application_key_env")
+
+func (s *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	s.log = log
+	s.store = store
+	s.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, s.config)
+	if err != nil {
+		return fmt.Errorf("unable to decode Monitoring config: %s", err)
+	}
+
+	if s.config.Address == "" {
+		s.config.Address = DefaultAddress
+	}
+
+	errorMessage := ""
+
+	validate := validator.New()
+	err = validate.Struct(s.config)
+
+	if err != nil {
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.Address":
+				errorMessage += ErrInvalidAddress.Error() + "\n"
+			case "PluginConfig.APIKeyFile":
+				errorMessage += ErrInvalidAPIKeyFile.Error() + "\n"
+			case "PluginConfig.ApplicationKeyFile":
+				errorMessage += ErrInvalidApplicationKeyFile.Error() + "\n"
+			}
+		}
+	}
+
+	if s.config.APIKeyFile == "" && s.config.APIKeyEnv == "" {
+		s.config.APIKeyEnv = "DD_API_KEY"
+	}
+
+	if s.config.ApplicationKeyFile == "" && s.config.ApplicationKeyEnv == "" {
+		s.config.ApplicationKeyEnv = "DD_APP_KEY"
+	}
+
+	// the environment of the controller does not change, keys from files are read for every query
+	// so that rotated keys are used without restarting the controller
+	if s.config.APIKeyFile == "" && os.Getenv(s.config.APIKeyEnv) == "" {
+		errorMessage += ErrInvalidAPIKey.Error() + "\n"
+	}
+
+	if s.config.ApplicationKeyFile == "" && os.Getenv(s.config.ApplicationKeyEnv) == "" {
+		errorMessage += ErrInvalidApplicationKey.Error() + "\n"
+	}
+
+	if errorMessage != "" {
+		return fmt.Errorf(errorMessage)
+	}
+
+	s.presets, err = s.loadPresets()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loadPresets returns the presets for the runtime, the built in presets are merged with the presets from
+// the controller's presets file and Consul KV path
+func (s *Plugin) loadPresets() (map[string]string, error) {
+	lib := presets.Library{
+		File:         config.DatadogPresetsFile(),
+		ConsulKVPath: config.DatadogPresetsConsulKVPath(),
+	}
+
+	return presets.Load(builtInPresets, s.runtime, lib, s.consulClient, s.log)
+}
+
+// Check executes queries using the Datadog query API and returns an error if any of the queries
+// are not within the defined min and max thresholds
+func (s *Plugin) Check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	querySQL := []string{}
+
+	// first check that the given queries have valid presets
+	for _, q := range s.config.Queries {
+		if q.Preset != "" {
+			// use a preset if present
+			preset, ok := s.presets[q.Preset]
+			if !ok {
+				return interfaces.CheckError, fmt.Errorf("preset query %s-%s, does not exist", s.runtime, q.Preset)
+			}
+
+			querySQL = append(querySQL, preset)
+		} else {
+			// use the custom query
+			querySQL = append(querySQL, q.Query)
+		}
+	}
+
+	// execute the queries
+	for i, q := range querySQL {
+		query := s.config.Queries[i]
+
+		// check the query is not empty
+		if q == "" {
+			return interfaces.CheckError, fmt.Errorf("query %s is empty, please specify a valid Datadog query", query.Name)
+		}
+
+		// add the interpolation for the queries
+		tmpl, err := template.New("query").Parse(q)
+		if err != nil {
+			return interfaces.CheckError, fmt.Errorf("unable to process query template: %s", err)
+		}
+
+		context := struct {
+			ReleaseName   string
+			CandidateName string
+			Namespace     string
+			Interval      string
+		}{
+			s.name,
+			candidateName,
+			s.namespace,
+			interval.String(),
+		}
+
+		out := bytes.NewBufferString("")
+		err = tmpl.Execute(out, context)
+		if err != nil {
+			return interfaces.CheckError, fmt.Errorf("unable to process query template: %s", err)
+		}
+
+		s.log.Debug("querying datadog", "address", s.config.Address, "name", query.Name, "query", out)
+
+		value, ok, err := s.query(ctx, out.String(), interval)
+		if err != nil {
+			s.log.Error("unable to query datadog", "error", err)
+
+			return interfaces.CheckError, fmt.Errorf("unable to query datadog: %s", err)
+		}
+
+		if !ok {
+			return interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, null value returned by query", query.Name, query.Preset)
+		}
+
+		s.log.Debug("query value returned", "name", query.Name, "preset", query.Preset, "value", value)
+
+		checkFail := false
+
+		if query.Min != nil && value < *query.Min {
+			s.log.Debug("query value less than min", "name", query.Name, "preset", query.Preset, "value", value)
+			checkFail = true
+		}
+
+		if query.Max != nil && value > *query.Max {
+			s.log.Debug("query value greater than max", "name", query.Name, "preset", query.Preset, "value", value)
+			checkFail = true
+		}
+
+		if checkFail {
+			return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, got value %g", query.Name, query.Preset, value)
+		}
+	}
+
+	return interfaces.CheckSuccess, nil
+}
+
+// query executes the query for the given interval, returning the most recent value of the first
+// series, ok is false when the query did not return a value
+func (s *Plugin) query(ctx context.Context, query string, interval time.Duration) (float64, bool, error) {
+	to := time.Now()
+	from := to.Add(-interval)
+
+	params := url.Values{}
+	params.Set("from", strconv.FormatInt(from.Unix(), 10))
+	params.Set("to", strconv.FormatInt(to.Unix(), 10))
+	params.Set("query", query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.config.Address, "/")+"/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	apiKey, err := clients.ReadSecret(s.config.APIKeyFile, s.config.APIKeyEnv)
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to read API key: %s", err)
+	}
+
+	appKey, err := clients.ReadSecret(s.config.ApplicationKeyFile, s.config.ApplicationKeyEnv)
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to read application key: %s", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("DD-API-KEY", apiKey)
+	req.Header.Set("DD-APPLICATION-KEY", appKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("error querying datadog: %s, %s", s.config.Address, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("error querying datadog: %s, expected status 200, got %d", s.config.Address, resp.StatusCode)
+	}
+
+	qr := &queryResponse{}
+
+	err = json.NewDecoder(resp.Body).Decode(qr)
+	if err != nil {
+		return 0, false, fmt.Errorf("unable to decode response from datadog: %s", err)
+	}
+
+	if qr.Status == "error" {
+		return 0, false, fmt.Errorf("error querying datadog: %s", qr.Error)
+	}
+
+	if len(qr.Series) == 0 {
+		return 0, false, nil
+	}
+
+	// points are ordered by time, use the most recent point that has a value
+	points := qr.Series[0].Pointlist
+	for i := len(points) - 1; i >= 0; i-- {
+		if len(points[i]) == 2 && points[i][1] != nil {
+			return *points[i][1], true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// compact removes the whitespace used to format the preset queries
+func compact(q string) string {
+	return strings.Join(strings.Fields(q), "")
+}
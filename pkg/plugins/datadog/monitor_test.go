@@ -0,0 +1,331 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+type datadogServer struct {
+	queries  []url.Values
+	headers  []http.Header
+	response string
+}
+
+func setupPlugin(t *testing.T, config string) (*Plugin, *datadogServer) {
+	l := hclog.NewNullLogger()
+	p, _ := New("api-deployment", "default", "kubernetes", l)
+
+	t.Setenv("TEST_DD_API_KEY", "abc")
+	t.Setenv("TEST_DD_APP_KEY", "123")
+
+	ds := &datadogServer{response: seriesResponse(100)}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/query", r.URL.Path)
+
+		ds.queries = append(ds.queries, r.URL.Query())
+		ds.headers = append(ds.headers, r.Header)
+
+		rw.Write([]byte(ds.response))
+	}))
+
+	t.Cleanup(ts.Close)
+
+	err := p.Configure([]byte(fmt.Sprintf(config, ts.URL)), l, &mocks.StoreMock{})
+	require.NoError(t, err)
+
+	return p, ds
+}
+
+func seriesResponse(value float64) string {
+	return fmt.Sprintf(`{
+		"status": "ok",
+		"series": [
+			{
+				"metric": "envoy.cluster.upstream_rq_xx",
+				"pointlist": [[1655000000000, 1], [1655000010000, %f], [1655000020000, null]]
+			}
+		]
+	}`, value)
+}
+
+func TestConfigureReturnsErrorWhenKeysMissing(t *testing.T) {
+	t.Setenv("DD_API_KEY", "")
+	t.Setenv("DD_APP_KEY", "")
+
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+	err := p.Configure([]byte(`{"queries": []}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidAPIKey.Error())
+	require.Contains(t, err.Error(), ErrInvalidApplicationKey.Error())
+}
+
+func TestConfigureUsesKeysFromEnvironment(t *testing.T) {
+	t.Setenv("DD_API_KEY", "env-api")
+	t.Setenv("DD_APP_KEY", "env-app")
+
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+	err := p.Configure([]byte(`{"queries": []}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.NoError(t, err)
+	require.Equal(t, DefaultAddress, p.config.Address)
+	require.Equal(t, "DD_API_KEY", p.config.APIKeyEnv)
+	require.Equal(t, "DD_APP_KEY", p.config.ApplicationKeyEnv)
+}
+
+func TestConfigureReturnsErrorWhenKeyFileAndEnvSpecified(t *testing.T) {
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+	err := p.Configure([]byte(`{"api_key_file": "/api", "api_key_env": "API", "application_key_file": "/app", "application_key_env": "APP"}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidAPIKeyFile.Error())
+	require.Contains(t, err.Error(), ErrInvalidApplicationKeyFile.Error())
+}
+
+func TestPluginReadsKeysFromFilesForEveryQuery(t *testing.T) {
+	dir := t.TempDir()
+	apiFile := filepath.Join(dir, "api")
+	appFile := filepath.Join(dir, "app")
+
+	os.WriteFile(apiFile, []byte("file-api\n"), 0600)
+	os.WriteFile(appFile, []byte("file-app\n"), 0600)
+
+	p, ds := setupPlugin(t, `{"address": "%s", "api_key_file": "`+apiFile+`", "application_key_file": "`+appFile+`", "queries": [{"name": "success", "preset": "envoy-request-success", "min": 99}]}`)
+
+	_, err := p.Check(context.Background(), "api-candidate", 30*time.Second)
+	require.NoError(t, err)
+
+	// rotated keys are used by the next check
+	os.WriteFile(apiFile, []byte("rotated-api"), 0600)
+
+	_, err = p.Check(context.Background(), "api-candidate", 30*time.Second)
+	require.NoError(t, err)
+
+	require.Equal(t, "file-api", ds.headers[0].Get("DD-API-KEY"))
+	require.Equal(t, "file-app", ds.headers[0].Get("DD-APPLICATION-KEY"))
+	require.Equal(t, "rotated-api", ds.headers[1].Get("DD-API-KEY"))
+}
+
+func TestPluginExecutesPresetFromLibrary(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "presets.yaml")
+	os.WriteFile(f, []byte("kubernetes:\n  http-requests: \"sum:http.requests{pod_name:{{ .CandidateName }}*}\"\n"), 0600)
+	t.Setenv("DATADOG_PRESETS_FILE", f)
+
+	p, ds := setupPlugin(t, `{"address": "%s", "api_key_env": "TEST_DD_API_KEY", "application_key_env": "TEST_DD_APP_KEY", "queries": [{"name": "requests", "preset": "http-requests", "min": 1}]}`)
+
+	_, err := p.Check(context.Background(), "api-candidate", 30*time.Second)
+	require.NoError(t, err)
+
+	require.Equal(t, "sum:http.requests{pod_name:api-candidate*}", ds.queries[0].Get("query"))
+}
+
+func TestConfigureReturnsErrorWhenPresetsFileInvalid(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "presets.yaml")
+	os.WriteFile(f, []byte("kubernetes: ["), 0600)
+	t.Setenv("DATADOG_PRESETS_FILE", f)
+	t.Setenv("DD_API_KEY", "env-api")
+	t.Setenv("DD_APP_KEY", "env-app")
+
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+	err := p.Configure([]byte(`{"queries": []}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+}
+
+func TestPluginReturnsErrorWhenPresetNotFound(t *testing.T) {
+	p, ds := setupPlugin(t, twoDefaultQueriesInvalidPreset)
+
+	_, err := p.Check(context.Background(), "test-deployment", 30*time.Second)
+	require.Error(t, err)
+
+	require.Len(t, ds.queries, 0)
+}
+
+func TestPluginReturnsErrorWhenCustomQueryBlank(t *testing.T) {
+	p, ds := setupPlugin(t, twoQueriesCustomEmpty)
+
+	_, err := p.Check(context.Background(), "test-deployment", 30*time.Second)
+	require.Error(t, err)
+
+	require.Len(t, ds.queries, 1)
+}
+
+func TestPluginReturnsNoMetricsWhenNoSeries(t *testing.T) {
+	p, ds := setupPlugin(t, twoDefaultQueries)
+	ds.response = `{"status": "ok", "series": []}`
+
+	result, err := p.Check(context.Background(), "test-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestPluginReturnsErrorWhenQueryFails(t *testing.T) {
+	p, ds := setupPlugin(t, twoDefaultQueries)
+	ds.response = `{"status": "error", "error": "invalid query"}`
+
+	result, err := p.Check(context.Background(), "test-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid query")
+	require.Equal(t, interfaces.CheckError, result)
+}
+
+func TestPluginReturnsErrorWhenQueryValueLessThanMin(t *testing.T) {
+	p, ds := setupPlugin(t, twoDefaultQueries)
+	ds.response = seriesResponse(1)
+
+	result, err := p.Check(context.Background(), "test-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckFailed, result)
+
+	require.Len(t, ds.queries, 1)
+}
+
+func TestPluginReturnsErrorWhenQueryValueGreaterThanMax(t *testing.T) {
+	p, ds := setupPlugin(t, twoDefaultQueries)
+	ds.response = seriesResponse(201)
+
+	result, err := p.Check(context.Background(), "test-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckFailed, result)
+
+	require.Len(t, ds.queries, 2)
+}
+
+func TestPluginExecutesQueriesAndChecksValue(t *testing.T) {
+	p, ds := setupPlugin(t, twoDefaultQueries)
+
+	result, err := p.Check(context.Background(), "api-candidate", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+
+	require.Len(t, ds.queries, 2)
+
+	// check the keys are sent
+	require.Equal(t, "abc", ds.headers[0].Get("DD-API-KEY"))
+	require.Equal(t, "123", ds.headers[0].Get("DD-APPLICATION-KEY"))
+
+	// check the time range uses the interval
+	require.NotEmpty(t, ds.queries[0].Get("to"))
+	require.NotEmpty(t, ds.queries[0].Get("from"))
+
+	// check that the query interpolation was added correctly
+	call1Args := ds.queries[0].Get("query")
+	call2Args := ds.queries[1].Get("query")
+
+	require.Contains(t, call1Args, `kube_namespace:default`)
+	require.Contains(t, call1Args, `!pod_name:api-deployment-primary*`)
+	require.Contains(t, call1Args, `pod_name:api-candidate*`)
+	require.NotContains(t, call1Args, " ")
+
+	require.Contains(t, call2Args, `kube_namespace:default`)
+	require.Contains(t, call2Args, `!pod_name:api-deployment-primary*`)
+}
+
+func TestPluginExecutesCustomQueriesAndChecksValue(t *testing.T) {
+	p, ds := setupPlugin(t, twoQueriesOneCustom)
+
+	_, err := p.Check(context.Background(), "api-candidate", 30*time.Second)
+	require.NoError(t, err)
+
+	require.Len(t, ds.queries, 2)
+
+	call2Args := ds.queries[1].Get("query")
+	require.Equal(t, `avg:trace.http.request.duration{service:api-candidate,env:default}.rollup(avg, 30s)`, call2Args)
+}
+
+const twoDefaultQueries = `
+{
+	"address": "%s",
+	"api_key_env": "TEST_DD_API_KEY",
+	"application_key_env": "TEST_DD_APP_KEY",
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "preset": "envoy-request-success",
+	    "min":99
+	  },
+	  {
+	    "name": "request-duration",
+	    "preset": "envoy-request-duration",
+	    "min":20,
+	    "max": 200
+	  }
+	]
+}
+`
+
+const twoDefaultQueriesInvalidPreset = `
+{
+	"address": "%s",
+	"api_key_env": "TEST_DD_API_KEY",
+	"application_key_env": "TEST_DD_APP_KEY",
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "preset": "envoy-request-success",
+	    "min":99
+	  },
+	  {
+	    "name": "request-duration",
+	    "preset": "not-found",
+	    "min":20,
+	    "max": 200
+	  }
+	]
+}
+`
+
+const twoQueriesCustomEmpty = `
+{
+	"address": "%s",
+	"api_key_env": "TEST_DD_API_KEY",
+	"application_key_env": "TEST_DD_APP_KEY",
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "preset": "envoy-request-success",
+	    "min":99
+	  },
+	  {
+	    "name": "request-duration",
+	    "min":20,
+	    "max": 200,
+	    "query": ""
+	  }
+	]
+}
+`
+
+const twoQueriesOneCustom = `
+{
+	"address": "%s",
+	"api_key_env": "TEST_DD_API_KEY",
+	"application_key_env": "TEST_DD_APP_KEY",
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "preset": "envoy-request-success",
+	    "min":99
+	  },
+	  {
+	    "name": "request-duration",
+	    "min":20,
+	    "max": 200,
+	    "query": "avg:trace.http.request.duration{service:{{ .CandidateName }},env:{{ .Namespace }}}.rollup(avg, {{ .Interval }})"
+	  }
+	]
+}
+`
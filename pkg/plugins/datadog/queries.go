@@ -0,0 +1,69 @@
+package datadog
+
+// builtInPresets are the built in preset queries keyed by runtime and preset name, the whitespace used
+// to format the queries is removed
+var builtInPresets = map[string]map[string]string{
+	"kubernetes": {
+		"envoy-request-success":  compact(KubernetesEnvoyRequestSuccess),
+		"envoy-request-duration": compact(KubernetesEnvoyRequestDuration),
+	},
+	"nomad": {
+		"envoy-request-success":  compact(NomadEnvoyRequestSuccess),
+		"envoy-request-duration": compact(NomadEnvoyRequestDuration),
+	},
+}
+
+const KubernetesEnvoyRequestSuccess = `
+100 - (
+  sum:envoy.cluster.upstream_rq_xx{
+    kube_namespace:{{ .Namespace }},
+    pod_name:{{ .CandidateName }}*,
+    !pod_name:{{ .ReleaseName }}-primary*,
+    envoy_cluster:local_app,
+    envoy_response_code_class:5
+  }.as_count()
+  /
+  sum:envoy.cluster.upstream_rq_completed{
+    kube_namespace:{{ .Namespace }},
+    pod_name:{{ .CandidateName }}*,
+    !pod_name:{{ .ReleaseName }}-primary*,
+    envoy_cluster:local_app
+  }.as_count()
+  * 100
+)
+`
+
+const KubernetesEnvoyRequestDuration = `
+max:envoy.cluster.upstream_rq_time.99percentile{
+  kube_namespace:{{ .Namespace }},
+  pod_name:{{ .CandidateName }}*,
+  !pod_name:{{ .ReleaseName }}-primary*,
+  envoy_cluster:local_app
+}
+`
+
+const NomadEnvoyRequestSuccess = `
+100 - (
+  sum:envoy.cluster.upstream_rq_xx{
+    nomad_job_name:{{ .CandidateName }},
+    !nomad_job_name:{{ .ReleaseName }}-primary,
+    envoy_cluster:local_app,
+    envoy_response_code_class:5
+  }.as_count()
+  /
+  sum:envoy.cluster.upstream_rq_completed{
+    nomad_job_name:{{ .CandidateName }},
+    !nomad_job_name:{{ .ReleaseName }}-primary,
+    envoy_cluster:local_app
+  }.as_count()
+  * 100
+)
+`
+
+const NomadEnvoyRequestDuration = `
+max:envoy.cluster.upstream_rq_time.99percentile{
+  nomad_job_name:{{ .CandidateName }},
+  !nomad_job_name:{{ .ReleaseName }}-primary,
+  envoy_cluster:local_app
+}
+`
@@ -2,6 +2,7 @@ package discord
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"text/template"
@@ -80,7 +81,7 @@ func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfa
 	return nil
 }
 
-func (p *Plugin) Send(message interfaces.WebhookMessage) error {
+func (p *Plugin) Send(ctx context.Context, message interfaces.WebhookMessage) error {
 	// only send if current status is in our list of status
 	if len(p.config.Status) > 0 {
 		progress := false
@@ -120,7 +121,7 @@ func (p *Plugin) Send(message interfaces.WebhookMessage) error {
 			SetTitle(message.Title).
 			SetDescription(out.String()).
 			Build(),
-	})
+	}, rest.WithCtx(ctx))
 
 	if err != nil {
 		return fmt.Errorf("unable to make Webhook call to Discord: %s", err)
@@ -1,6 +1,7 @@
 package discord
 
 import (
+	"context"
 	"testing"
 
 	"github.com/DisgoOrg/disgo/discord"
@@ -60,7 +61,7 @@ func TestConfiguresWithoutError(t *testing.T) {
 func TestSendsMessageWithDefaultContent(t *testing.T) {
 	p, mc := setupTests(t, validConfig)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
@@ -81,7 +82,7 @@ func TestSendsMessageWithDefaultContent(t *testing.T) {
 func TestSendsMessageWithCustomContent(t *testing.T) {
 	p, mc := setupTests(t, validConfigWithTemplate)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
@@ -102,7 +103,7 @@ func TestSendsMessageWithCustomContent(t *testing.T) {
 func TestSendsMessageWithDefaultContentError(t *testing.T) {
 	p, mc := setupTests(t, validConfig)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
@@ -123,7 +124,7 @@ func TestSendsMessageWithDefaultContentError(t *testing.T) {
 func TestDoesNotSendWhenStatusNotMatching(t *testing.T) {
 	p, mc := setupTests(t, validConfigWithStatus)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
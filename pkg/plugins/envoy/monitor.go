@@ -0,0 +1,401 @@
+package envoy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// PresetRequestSuccess is the percentage of requests handled by the candidate that did not
+	// result in a HTTP 5xx response
+	PresetRequestSuccess = "envoy-request-success"
+	// PresetRequestDuration is the 99th percentile duration in milliseconds of the requests handled
+	// by the candidate
+	PresetRequestDuration = "envoy-request-duration"
+)
+
+const (
+	upstreamRequestMetric  = "envoy_cluster_upstream_rq_xx"
+	upstreamDurationMetric = "envoy_cluster_upstream_rq_time"
+	clusterLabel           = "envoy_cluster_name"
+	responseClassLabel     = "envoy_response_code_class"
+	localCluster           = "local_app"
+)
+
+type Plugin struct {
+	log          hclog.Logger
+	config       *PluginConfig
+	store        interfaces.PluginStateStore
+	consulClient clients.Consul
+	httpClient   *http.Client
+	name         string
+	filter       string
+
+	// stats holds the stats from the previous check for each Envoy, keyed by the address of the stats
+	// listener
+	stats     map[string]*envoyStats
+	statsLock sync.Mutex
+}
+
+type PluginConfig struct {
+	// ConsulService is the name of the Consul service for the release, defaults to the name of the release
+	ConsulService string `json:"consul_service"`
+
+	// Port of the Envoy stats listener on the address of the sidecar proxy, defaults to the port of the
+	// envoy_prometheus_bind_addr in the proxy config
+	Port int `json:"port" validate:"gte=0,lte=65535"`
+
+	// Path of the Prometheus formatted stats, defaults to /metrics
+	Path string `json:"path"`
+
+	Queries []Query `json:"queries" validate:"dive"`
+}
+
+// Query config
+type Query struct {
+	// Name of the query
+	Name string `json:"name"`
+
+	// Preset is the metric to check, either envoy-request-success or envoy-request-duration
+	Preset string `json:"preset" validate:"oneof=envoy-request-success envoy-request-duration"`
+
+	// Minimum value for success, optional when Max specified
+	Min *float64 `json:"min,omitempty"` // default 0
+
+	// Maximum value for success, optional when Min specified
+	Max *float64 `json:"max,omitempty"` // default 0
+}
+
+// envoyStats are the cumulative counters for the local_app cluster of a single Envoy
+type envoyStats struct {
+	requests float64
+	errors   float64
+	// buckets are the cumulative request duration histogram keyed by upper bound in milliseconds
+	buckets map[float64]float64
+}
+
+// New creates a new Envoy monitor, the filter selects the candidate instances from the Consul catalog
+func New(name, filter string, l hclog.Logger) (*Plugin, error) {
+	return &Plugin{
+		log:        l,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		name:       name,
+		filter:     filter,
+		stats:      map[string]*envoyStats{},
+	}, nil
+}
+
+var ErrInvalidPort = fmt.Errorf("Port must be a valid port number, please specify the port of the Envoy stats listener e.g. (20200)")
+var ErrInvalidPreset = fmt.Errorf("Preset must be one of envoy-request-success, envoy-request-duration, custom queries are not supported by the Envoy monitor")
+
+func (s *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	s.log = log
+	s.store = store
+	s.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, s.config)
+	if err != nil {
+		return fmt.Errorf("unable to decode Monitoring config: %s", err)
+	}
+
+	validate := validator.New()
+	err = validate.Struct(s.config)
+
+	if err != nil {
+		errorMessage := ""
+		for _, err := range err.(validator.ValidationErrors) {
+			switch {
+			case err.Namespace() == "PluginConfig.Port":
+				errorMessage += ErrInvalidPort.Error() + "\n"
+			case err.StructField() == "Preset":
+				errorMessage += ErrInvalidPreset.Error() + "\n"
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	if s.config.ConsulService == "" {
+		s.config.ConsulService = s.name
+	}
+
+	if s.config.Path == "" {
+		s.config.Path = "/metrics"
+	}
+
+	cc, err := clients.NewConsul(nil)
+	if err != nil {
+		return err
+	}
+
+	s.consulClient = cc
+
+	return nil
+}
+
+// Check scrapes the stats from the Envoy sidecars of the candidate instances and returns an error if
+// any of the queries are not within the defined min and max thresholds. Values are calculated from the
+// requests made since the previous check, the first check uses the requests since each Envoy started
+func (s *Plugin) Check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	proxies, err := s.consulClient.SidecarProxies(s.config.ConsulService, s.filter)
+	if err != nil {
+		return interfaces.CheckError, fmt.Errorf("unable to find candidate instances: %s", err)
+	}
+
+	if len(proxies) == 0 {
+		return interfaces.CheckNoMetrics, fmt.Errorf("no candidate instances found for service %s", s.config.ConsulService)
+	}
+
+	addresses := []string{}
+	for _, p := range proxies {
+		a, err := s.statsAddress(p)
+		if err != nil {
+			return interfaces.CheckError, err
+		}
+
+		addresses = append(addresses, a)
+	}
+
+	s.statsLock.Lock()
+	defer s.statsLock.Unlock()
+
+	current := map[string]*envoyStats{}
+	delta := &envoyStats{buckets: map[float64]float64{}}
+
+	for _, a := range addresses {
+		st, err := s.scrape(ctx, a)
+		if err != nil {
+			s.log.Error("unable to scrape envoy stats", "address", a, "error", err)
+
+			return interfaces.CheckError, err
+		}
+
+		current[a] = st
+		delta.add(st.since(s.stats[a]))
+	}
+
+	// instances that no longer exist are removed so the stats do not grow
+	s.stats = current
+
+	for _, q := range s.config.Queries {
+		var value float64
+
+		switch q.Preset {
+		case PresetRequestSuccess:
+			if delta.requests == 0 {
+				return interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, no requests since the last check", q.Name, q.Preset)
+			}
+
+			value = (delta.requests - delta.errors) / delta.requests * 100
+		case PresetRequestDuration:
+			var ok bool
+			value, ok = quantile(0.99, delta.buckets)
+			if !ok {
+				return interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, no requests since the last check", q.Name, q.Preset)
+			}
+		}
+
+		s.log.Debug("query value returned", "name", q.Name, "preset", q.Preset, "value", value)
+
+		checkFail := false
+
+		if q.Min != nil && value < *q.Min {
+			s.log.Debug("query value less than min", "name", q.Name, "preset", q.Preset, "value", value)
+			checkFail = true
+		}
+
+		if q.Max != nil && value > *q.Max {
+			s.log.Debug("query value greater than max", "name", q.Name, "preset", q.Preset, "value", value)
+			checkFail = true
+		}
+
+		if checkFail {
+			return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, got value %g", q.Name, q.Preset, value)
+		}
+	}
+
+	return interfaces.CheckSuccess, nil
+}
+
+// statsAddress returns the host and port of the stats listener for the sidecar proxy, the Envoy admin API
+// is bound to the loopback address so the stats are read from the envoy_prometheus_bind_addr listener
+func (s *Plugin) statsAddress(p clients.SidecarProxy) (string, error) {
+	if s.config.Port != 0 {
+		return net.JoinHostPort(p.Address, strconv.Itoa(s.config.Port)), nil
+	}
+
+	if p.PrometheusBindAddr == "" {
+		return "", fmt.Errorf("sidecar proxy at %s does not set envoy_prometheus_bind_addr in the proxy config, please set the bind address or specify the port of the Envoy stats listener", p.Address)
+	}
+
+	host, port, err := net.SplitHostPort(p.PrometheusBindAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid envoy_prometheus_bind_addr %s for sidecar proxy at %s: %s", p.PrometheusBindAddr, p.Address, err)
+	}
+
+	// listeners bound to all interfaces are reached using the address of the proxy
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = p.Address
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// scrape fetches and parses the Prometheus formatted stats from the Envoy stats listener at the given
+// host and port
+func (s *Plugin) scrape(ctx context.Context, address string) (*envoyStats, error) {
+	url := fmt.Sprintf("http://%s%s", address, s.config.Path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch envoy stats from %s: %s", url, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch envoy stats from %s, expected status 200, got %d", url, resp.StatusCode)
+	}
+
+	tp := expfmt.TextParser{}
+	families, err := tp.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse envoy stats from %s: %s", url, err)
+	}
+
+	st := &envoyStats{buckets: map[float64]float64{}}
+
+	if mf, ok := families[upstreamRequestMetric]; ok {
+		for _, m := range mf.GetMetric() {
+			if label(m, clusterLabel) != localCluster {
+				continue
+			}
+
+			v := m.GetCounter().GetValue()
+			st.requests += v
+
+			if label(m, responseClassLabel) == "5" {
+				st.errors += v
+			}
+		}
+	}
+
+	if mf, ok := families[upstreamDurationMetric]; ok {
+		for _, m := range mf.GetMetric() {
+			if label(m, clusterLabel) != localCluster {
+				continue
+			}
+
+			for _, b := range m.GetHistogram().GetBucket() {
+				st.buckets[b.GetUpperBound()] += float64(b.GetCumulativeCount())
+			}
+		}
+	}
+
+	return st, nil
+}
+
+// since returns the stats recorded after the previous stats, counters that are lower than the
+// previous value have been reset by an Envoy restart
+func (e *envoyStats) since(prev *envoyStats) *envoyStats {
+	if prev == nil || e.requests < prev.requests {
+		return e
+	}
+
+	d := &envoyStats{
+		requests: e.requests - prev.requests,
+		errors:   e.errors - prev.errors,
+		buckets:  map[float64]float64{},
+	}
+
+	for k, v := range e.buckets {
+		d.buckets[k] = v - prev.buckets[k]
+	}
+
+	return d
+}
+
+func (e *envoyStats) add(o *envoyStats) {
+	e.requests += o.requests
+	e.errors += o.errors
+
+	for k, v := range o.buckets {
+		e.buckets[k] += v
+	}
+}
+
+// quantile calculates the quantile from the cumulative histogram buckets using linear interpolation
+// within the bucket, the same method as the Prometheus histogram_quantile function
+func quantile(q float64, buckets map[float64]float64) (float64, bool) {
+	bounds := []float64{}
+	for k := range buckets {
+		bounds = append(bounds, k)
+	}
+
+	sort.Float64s(bounds)
+
+	if len(bounds) == 0 || !math.IsInf(bounds[len(bounds)-1], 1) {
+		return 0, false
+	}
+
+	total := buckets[bounds[len(bounds)-1]]
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := q * total
+
+	for i, b := range bounds {
+		if buckets[b] < rank {
+			continue
+		}
+
+		// the quantile is in the +Inf bucket, return the highest finite bound
+		if math.IsInf(b, 1) {
+			if i == 0 {
+				return 0, true
+			}
+
+			return bounds[i-1], true
+		}
+
+		lower, count := 0.0, 0.0
+		if i > 0 {
+			lower, count = bounds[i-1], buckets[bounds[i-1]]
+		}
+
+		return lower + (b-lower)*((rank-count)/(buckets[b]-count)), true
+	}
+
+	return bounds[len(bounds)-1], true
+}
+
+func label(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+
+	return ""
+}
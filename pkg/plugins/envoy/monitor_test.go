@@ -0,0 +1,221 @@
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type envoyServer struct {
+	host  string
+	port  string
+	paths []string
+	stats string
+}
+
+func setupPlugin(t *testing.T, config string) (*Plugin, *clients.ConsulMock, *envoyServer) {
+	l := hclog.NewNullLogger()
+	p, _ := New("api", `Service.ID not contains "primary"`, l)
+
+	es := &envoyServer{stats: envoyStatsResponse(100, 0, 90, 100, 100)}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		es.paths = append(es.paths, r.URL.Path)
+		rw.Write([]byte(es.stats))
+	}))
+
+	t.Cleanup(ts.Close)
+
+	es.host, es.port, _ = net.SplitHostPort(ts.Listener.Addr().String())
+
+	err := p.Configure([]byte(config), l, &mocks.StoreMock{})
+	require.NoError(t, err)
+
+	// the stats listener is bound to all interfaces and is reached using the address of the proxy
+	cm := &clients.ConsulMock{}
+	cm.On("SidecarProxies", mock.Anything, mock.Anything).Return([]clients.SidecarProxy{{Address: es.host, PrometheusBindAddr: "0.0.0.0:" + es.port}}, nil)
+
+	p.consulClient = cm
+
+	return p, cm, es
+}
+
+// envoyStatsResponse returns Prometheus formatted Envoy stats for the local_app cluster with the given number
+// of 2xx and 5xx requests, and a duration histogram with the given cumulative counts for the 10ms, 100ms, and
+// +Inf buckets
+func envoyStatsResponse(ok, failed int, b10, b100, inf int) string {
+	return fmt.Sprintf(`# TYPE envoy_cluster_upstream_rq_xx counter
+envoy_cluster_upstream_rq_xx{envoy_response_code_class="2",envoy_cluster_name="local_app"} %d
+envoy_cluster_upstream_rq_xx{envoy_response_code_class="5",envoy_cluster_name="local_app"} %d
+envoy_cluster_upstream_rq_xx{envoy_response_code_class="5",envoy_cluster_name="payments"} 1000
+# TYPE envoy_cluster_upstream_rq_time histogram
+envoy_cluster_upstream_rq_time_bucket{envoy_cluster_name="local_app",le="10"} %d
+envoy_cluster_upstream_rq_time_bucket{envoy_cluster_name="local_app",le="100"} %d
+envoy_cluster_upstream_rq_time_bucket{envoy_cluster_name="local_app",le="+Inf"} %d
+envoy_cluster_upstream_rq_time_sum{envoy_cluster_name="local_app"} 1234
+envoy_cluster_upstream_rq_time_count{envoy_cluster_name="local_app"} %d
+`, ok, failed, b10, b100, inf, inf)
+}
+
+func TestConfigureReturnsErrorWhenPresetInvalid(t *testing.T) {
+	p, _ := New("api", "", hclog.NewNullLogger())
+	err := p.Configure([]byte(`{"queries": [{"name": "custom", "query": "sum(envoy_cluster_upstream_rq)"}]}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidPreset.Error())
+}
+
+func TestConfigureSetsDefaults(t *testing.T) {
+	p, _ := New("api", "", hclog.NewNullLogger())
+	err := p.Configure([]byte(`{"queries": []}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.NoError(t, err)
+	require.Equal(t, "api", p.config.ConsulService)
+	require.Equal(t, 0, p.config.Port)
+	require.Equal(t, "/metrics", p.config.Path)
+}
+
+func TestCheckFindsCandidateInstancesWithFilter(t *testing.T) {
+	p, cm, es := setupPlugin(t, twoQueries)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+
+	cm.AssertCalled(t, "SidecarProxies", "api", `Service.ID not contains "primary"`)
+	require.Equal(t, []string{"/metrics"}, es.paths)
+}
+
+func TestCheckUsesPortAndPathWhenSpecified(t *testing.T) {
+	p, cm, es := setupPlugin(t, twoQueries)
+
+	err := p.Configure([]byte(`{"port": `+es.port+`, "path": "/stats/prometheus"}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.NoError(t, err)
+	p.consulClient = cm
+
+	testutils.ClearMockCall(&cm.Mock, "SidecarProxies")
+	cm.On("SidecarProxies", mock.Anything, mock.Anything).Return([]clients.SidecarProxy{{Address: es.host}}, nil)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+	require.Equal(t, []string{"/stats/prometheus"}, es.paths)
+}
+
+func TestCheckReturnsErrorWhenProxyDoesNotExposeStats(t *testing.T) {
+	p, cm, es := setupPlugin(t, twoQueries)
+
+	testutils.ClearMockCall(&cm.Mock, "SidecarProxies")
+	cm.On("SidecarProxies", mock.Anything, mock.Anything).Return([]clients.SidecarProxy{{Address: es.host}}, nil)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not set envoy_prometheus_bind_addr")
+	require.Equal(t, interfaces.CheckError, result)
+}
+
+func TestStatsAddressUsesBindAddressHostWhenSpecified(t *testing.T) {
+	p, _, _ := setupPlugin(t, twoQueries)
+
+	a, err := p.statsAddress(clients.SidecarProxy{Address: "10.0.0.1", PrometheusBindAddr: "10.0.1.1:20200"})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.1.1:20200", a)
+
+	a, err = p.statsAddress(clients.SidecarProxy{Address: "10.0.0.1", PrometheusBindAddr: ":20200"})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1:20200", a)
+}
+
+func TestCheckReturnsNoMetricsWhenNoInstances(t *testing.T) {
+	p, cm, _ := setupPlugin(t, twoQueries)
+
+	testutils.ClearMockCall(&cm.Mock, "SidecarProxies")
+	cm.On("SidecarProxies", mock.Anything, mock.Anything).Return([]clients.SidecarProxy{}, nil)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestCheckReturnsFailedWhenSuccessLessThanMin(t *testing.T) {
+	p, _, es := setupPlugin(t, twoQueries)
+	es.stats = envoyStatsResponse(90, 10, 10, 90, 100)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got value 90")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckReturnsFailedWhenDurationGreaterThanMax(t *testing.T) {
+	p, _, es := setupPlugin(t, twoQueries)
+	es.stats = envoyStatsResponse(100, 0, 0, 10, 100)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got value 100")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckUsesRequestsSinceLastCheck(t *testing.T) {
+	p, _, es := setupPlugin(t, twoQueries)
+	es.stats = envoyStatsResponse(50, 50, 10, 90, 100)
+
+	result, _ := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Equal(t, interfaces.CheckFailed, result)
+
+	// all of the new requests were successful
+	es.stats = envoyStatsResponse(150, 50, 110, 190, 200)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestCheckReturnsNoMetricsWhenNoNewRequests(t *testing.T) {
+	p, _, _ := setupPlugin(t, twoQueries)
+
+	_, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestQuantileInterpolatesWithinBucket(t *testing.T) {
+	v, ok := quantile(0.5, map[float64]float64{10: 0, 20: 100, math.Inf(1): 100})
+
+	require.True(t, ok)
+	require.Equal(t, 15.0, v)
+}
+
+const twoQueries = `
+{
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "preset": "envoy-request-success",
+	    "min": 99
+	  },
+	  {
+	    "name": "request-duration",
+	    "preset": "envoy-request-duration",
+	    "max": 95
+	  }
+	]
+}
+`
@@ -0,0 +1,120 @@
+package httpgate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Plugin is a gate that asks an external approval service for a decision, the request
+// is posted to the service every time the gate is checked until the service returns
+// approved or rejected
+type Plugin struct {
+	log    hclog.Logger
+	store  interfaces.PluginStateStore
+	config *PluginConfig
+	client httpClient
+}
+
+type PluginConfig struct {
+	// URL of the approval service
+	URL string `json:"url" validate:"required,url"`
+	// Optional headers added to the request e.g. Authorization
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func New() (*Plugin, error) {
+	return &Plugin{}, nil
+}
+
+var ErrInvalidURL = fmt.Errorf("URL is a required field when configuring HTTP gates, please specify a valid URL e.g. (https://approvals.example.com/releases)")
+
+func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	p.log = log
+	p.store = store
+	p.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, p.config)
+	if err != nil {
+		return fmt.Errorf("unable to decode Gate config: %s", err)
+	}
+
+	validate := validator.New()
+	err = validate.Struct(p.config)
+
+	if err != nil {
+		errorMessage := ""
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.URL":
+				errorMessage += ErrInvalidURL.Error() + "\n"
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	p.client = &http.Client{Timeout: 10 * time.Second}
+
+	return nil
+}
+
+// Check posts the request to the approval service and returns the decision from the response
+func (p *Plugin) Check(ctx context.Context, request interfaces.GateRequest) (interfaces.GateResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return interfaces.GateResponse{}, fmt.Errorf("unable to encode gate request: %s", err)
+	}
+
+	p.log.Debug("Checking gate", "url", p.config.URL, "request", request)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return interfaces.GateResponse{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range p.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return interfaces.GateResponse{}, fmt.Errorf("unable to call approval service %s: %s", p.config.URL, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return interfaces.GateResponse{}, fmt.Errorf("unable to call approval service %s, expected status 2xx, got %d", p.config.URL, resp.StatusCode)
+	}
+
+	gr := interfaces.GateResponse{}
+
+	err = json.NewDecoder(resp.Body).Decode(&gr)
+	if err != nil {
+		return interfaces.GateResponse{}, fmt.Errorf("unable to decode response from approval service %s: %s", p.config.URL, err)
+	}
+
+	switch gr.Result {
+	case interfaces.GateResultPending, interfaces.GateResultApproved, interfaces.GateResultRejected:
+	default:
+		return interfaces.GateResponse{}, fmt.Errorf("approval service %s returned an invalid result %s", p.config.URL, gr.Result)
+	}
+
+	p.log.Debug("Gate checked", "url", p.config.URL, "result", gr.Result, "reason", gr.Reason)
+
+	return gr, nil
+}
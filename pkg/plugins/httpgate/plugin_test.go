@@ -0,0 +1,96 @@
+package httpgate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+var gateRequest = interfaces.GateRequest{
+	Gate:             "cab",
+	Release:          "api",
+	Namespace:        "default",
+	Candidate:        "api-deployment",
+	Stage:            "before_promote",
+	CandidateTraffic: 90,
+}
+
+func setupTests(t *testing.T, status int, response string) (*Plugin, *interfaces.GateRequest, *http.Header) {
+	received := &interfaces.GateRequest{}
+	header := &http.Header{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		*header = r.Header
+		json.NewDecoder(r.Body).Decode(received)
+
+		rw.WriteHeader(status)
+		rw.Write([]byte(response))
+	}))
+
+	t.Cleanup(ts.Close)
+
+	p, _ := New()
+	err := p.Configure([]byte(fmt.Sprintf(validConfig, ts.URL)), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.NoError(t, err)
+
+	return p, received, header
+}
+
+func TestValidatesURL(t *testing.T) {
+	p, _ := New()
+	err := p.Configure([]byte(`{"url": "not a url"}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidURL.Error())
+}
+
+func TestCheckPostsRequestWithHeaders(t *testing.T) {
+	p, received, header := setupTests(t, http.StatusOK, `{"result": "pending"}`)
+
+	_, err := p.Check(context.Background(), gateRequest)
+	require.NoError(t, err)
+
+	require.Equal(t, gateRequest, *received)
+	require.Equal(t, "Bearer abc", header.Get("Authorization"))
+}
+
+func TestCheckReturnsDecision(t *testing.T) {
+	p, _, _ := setupTests(t, http.StatusOK, `{"result": "rejected", "reason": "change freeze"}`)
+
+	gr, err := p.Check(context.Background(), gateRequest)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.GateResultRejected, gr.Result)
+	require.Equal(t, "change freeze", gr.Reason)
+}
+
+func TestCheckReturnsErrorWhenResultInvalid(t *testing.T) {
+	p, _, _ := setupTests(t, http.StatusOK, `{"result": "maybe"}`)
+
+	_, err := p.Check(context.Background(), gateRequest)
+	require.Error(t, err)
+}
+
+func TestCheckReturnsErrorWhenStatusNotOK(t *testing.T) {
+	p, _, _ := setupTests(t, http.StatusInternalServerError, ``)
+
+	_, err := p.Check(context.Background(), gateRequest)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got 500")
+}
+
+var validConfig = `
+{
+	"url": "%s",
+	"headers": {
+		"Authorization": "Bearer abc"
+	}
+}
+`
@@ -19,6 +19,7 @@ type Plugin struct {
 	store      interfaces.PluginStateStore
 	config     *PluginConfig
 	monitoring interfaces.Monitor
+	clock      interfaces.Clock
 
 	name      string
 	namespace string
@@ -55,13 +56,13 @@ var ErrInvalidInterval = fmt.Errorf("Interval is not a valid duration, please sp
 var ErrInvalidTimeout = fmt.Errorf("Timeout is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
 var ErrInvalidTestPasses = fmt.Errorf("RequiredTestPasses is not valid, please specify a value greater than 0")
 
-func New(name, namespace, runtime string, m interfaces.Monitor) (*Plugin, error) {
+func New(name, namespace, runtime string, m interfaces.Monitor, c interfaces.Clock) (*Plugin, error) {
 	// if there is no namespaces set, then use the convention for default to ensure the upstream routing works
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	return &Plugin{monitoring: m, name: name, namespace: namespace, runtime: runtime}, nil
+	return &Plugin{monitoring: m, clock: c, name: name, namespace: namespace, runtime: runtime}, nil
 }
 
 // Configure the plugin with the given json
@@ -115,8 +116,7 @@ func (p *Plugin) Execute(ctx context.Context, candidateName string) error {
 		return fmt.Errorf("unable to parse interval as duration: %s", err)
 	}
 	successCount := 0
-	timeout, cancel := context.WithTimeout(ctx, timeoutDuration)
-	defer cancel()
+	deadline := p.clock.Now().Add(timeoutDuration)
 
 	for {
 		// Make a call to the external service to an instance of Envoy proxy that exposes the different services using HOST header on the same port
@@ -156,13 +156,17 @@ func (p *Plugin) Execute(ctx context.Context, candidateName string) error {
 		}
 
 		switch {
-		case timeout.Err() != nil:
+		case !p.clock.Now().Before(deadline):
 			p.log.Error("Post deployment test failed, test timeout", "successCount", successCount)
 			return fmt.Errorf("post deployment test failed, timeout waiting for successful tests")
 		case successCount >= p.config.RequiredTestPasses:
 			return nil
 		}
 
-		time.Sleep(interval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.clock.After(interval):
+		}
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
 	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
@@ -29,7 +30,7 @@ func setupPlugin(t *testing.T) (*Plugin, *mocks.MonitorMock, *[]*httpRequest) {
 	mm := &mocks.MonitorMock{}
 	mm.On("Check", mock.Anything, mock.Anything, 30*time.Second).Return(interfaces.CheckSuccess, nil)
 
-	p, err := New("test", "testnamespace", "kubernetes", mm)
+	p, err := New("test", "testnamespace", "kubernetes", mm, clock.NewReal())
 	require.NoError(t, err)
 
 	reqs := []*httpRequest{}
@@ -0,0 +1,223 @@
+package httpwebhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/sethvargo/go-retry"
+)
+
+// SignatureHeader is the header containing the hex encoded HMAC-SHA256 signature of the
+// request body when a secret is configured, the value has the format sha256=<signature>
+const SignatureHeader = "X-Release-Controller-Signature"
+
+const (
+	defaultRetries       = 3
+	defaultRetryInterval = "1s"
+	defaultTimeout       = "10s"
+)
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Plugin struct {
+	log    hclog.Logger
+	store  interfaces.PluginStateStore
+	config *PluginConfig
+	client httpClient
+}
+
+type PluginConfig struct {
+	// URL the message is posted to
+	URL string `json:"url" validate:"required,url"`
+	// Optional headers added to the request e.g. Authorization
+	Headers map[string]string `json:"headers,omitempty"`
+	// Optional shared secret used to sign the request body
+	Secret string `json:"secret,omitempty"`
+	// Number of times a failed request is retried, defaults to 3, set to 0 to disable retries
+	Retries *int `json:"retries,omitempty" validate:"omitempty,gte=0"`
+	// Interval before the first retry, the interval doubles for each subsequent retry, defaults to 1s
+	RetryInterval string `json:"retry_interval,omitempty" validate:"omitempty,duration"`
+	// Timeout for each request, defaults to 10s
+	Timeout string `json:"timeout,omitempty" validate:"omitempty,duration"`
+	// List of status to which the webhook applies, if empty all status are used
+	Status []string `json:"status,omitempty"`
+}
+
+func New() (*Plugin, error) {
+	return &Plugin{}, nil
+}
+
+var ErrInvalidURL = fmt.Errorf("URL is a required field when configuring HTTP webhooks, please specify a valid URL e.g. (https://deploys.example.com/events)")
+var ErrInvalidRetries = fmt.Errorf("Retries is not valid, please specify a value greater than or equal to 0")
+var ErrInvalidRetryInterval = fmt.Errorf("RetryInterval is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
+var ErrInvalidTimeout = fmt.Errorf("Timeout is not a valid duration, please specify using Go duration format e.g (30s, 30ms, 60m)")
+
+func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	p.log = log
+	p.store = store
+	p.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, p.config)
+	if err != nil {
+		return fmt.Errorf("unable to decode Webhook config: %s", err)
+	}
+
+	validate := validator.New()
+	validate.RegisterValidation("duration", interfaces.ValidateDuration)
+	err = validate.Struct(p.config)
+
+	if err != nil {
+		errorMessage := ""
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.URL":
+				errorMessage += ErrInvalidURL.Error() + "\n"
+			case "PluginConfig.Retries":
+				errorMessage += ErrInvalidRetries.Error() + "\n"
+			case "PluginConfig.RetryInterval":
+				errorMessage += ErrInvalidRetryInterval.Error() + "\n"
+			case "PluginConfig.Timeout":
+				errorMessage += ErrInvalidTimeout.Error() + "\n"
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	if p.config.Retries == nil {
+		retries := defaultRetries
+		p.config.Retries = &retries
+	}
+
+	if p.config.RetryInterval == "" {
+		p.config.RetryInterval = defaultRetryInterval
+	}
+
+	if p.config.Timeout == "" {
+		p.config.Timeout = defaultTimeout
+	}
+
+	timeout, _ := time.ParseDuration(p.config.Timeout)
+	p.client = &http.Client{Timeout: timeout}
+
+	return nil
+}
+
+// Send posts the message to the configured URL, failed requests are retried until the number of
+// retries is reached or the context is cancelled
+func (p *Plugin) Send(ctx context.Context, message interfaces.WebhookMessage) error {
+	// only send if current status is in our list of status
+	if len(p.config.Status) > 0 {
+		progress := false
+		for _, s := range p.config.Status {
+			if s == message.State {
+				progress = true
+			}
+		}
+
+		// status not in our list
+		if !progress {
+			p.log.Debug("Ignoring HTTP message", "url", p.config.URL, "message", message, "status", message.State, "statuses", p.config.Status)
+			return nil
+		}
+	}
+
+	p.log.Debug("Sending message to HTTP endpoint", "url", p.config.URL, "message", message)
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("unable to encode message for Webhook plugin: %s", err)
+	}
+
+	interval, _ := time.ParseDuration(p.config.RetryInterval)
+	backoff, err := retry.NewExponential(interval)
+	if err != nil {
+		return fmt.Errorf("invalid retry interval for Webhook plugin: %s", err)
+	}
+
+	attempt := 0
+	err = retry.Do(ctx, retry.WithMaxRetries(uint64(*p.config.Retries), backoff), func(ctx context.Context) error {
+		attempt++
+
+		err := p.post(ctx, body)
+		if err != nil {
+			p.log.Debug("Unable to call HTTP webhook", "url", p.config.URL, "attempt", attempt, "error", err)
+		}
+
+		return err
+	})
+
+	if err != nil {
+		// remove the retryable wrapper from the last error
+		if e := errors.Unwrap(err); e != nil {
+			err = e
+		}
+
+		return fmt.Errorf("unable to call HTTP webhook %s after %d attempts: %s", p.config.URL, attempt, err)
+	}
+
+	return nil
+}
+
+// post sends the body to the configured URL, errors that could succeed on a subsequent
+// attempt are returned as retryable errors
+func (p *Plugin) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range p.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if p.config.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(p.config.Secret, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return retry.RetryableError(err)
+	}
+
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err = fmt.Errorf("expected status 2xx, got %d", resp.StatusCode)
+
+	// client errors other than rate limiting will not succeed when retried
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return retry.RetryableError(err)
+	}
+
+	return err
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the body using the secret,
+// receivers can use this to verify the request was sent by the controller
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,201 @@
+package httpwebhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+func setupTests(t *testing.T, config string, statusCodes ...int) (*Plugin, *[]request) {
+	requests := []request{}
+	mu := sync.Mutex{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{header: r.Header, body: b})
+
+		code := http.StatusOK
+		if len(requests) <= len(statusCodes) {
+			code = statusCodes[len(requests)-1]
+		}
+
+		rw.WriteHeader(code)
+	}))
+
+	t.Cleanup(ts.Close)
+
+	p, _ := New()
+	err := p.Configure([]byte(fmt.Sprintf(config, ts.URL)), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.NoError(t, err)
+
+	return p, &requests
+}
+
+var message = interfaces.WebhookMessage{
+	Title:            "testtitle",
+	Name:             "testname",
+	Namespace:        "testnamespace",
+	State:            "state_monitor",
+	Outcome:          "event_healthy",
+	PrimaryTraffic:   90,
+	CandidateTraffic: 10,
+}
+
+func TestValidatesURL(t *testing.T) {
+	p, _ := New()
+	err := p.Configure([]byte(`{"url": "not a url"}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidURL.Error())
+}
+
+func TestValidatesRetryInterval(t *testing.T) {
+	p, _ := New()
+	err := p.Configure([]byte(`{"url": "http://localhost", "retry_interval": "1 minute"}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidRetryInterval.Error())
+}
+
+func TestValidatesRetries(t *testing.T) {
+	p, _ := New()
+	err := p.Configure([]byte(`{"url": "http://localhost", "retries": -1}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidRetries.Error())
+}
+
+func TestConfiguresDefaults(t *testing.T) {
+	p, _ := New()
+	err := p.Configure([]byte(`{"url": "http://localhost"}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	require.NoError(t, err)
+	require.Equal(t, defaultRetries, *p.config.Retries)
+	require.Equal(t, defaultRetryInterval, p.config.RetryInterval)
+	require.Equal(t, defaultTimeout, p.config.Timeout)
+}
+
+func TestSendsMessageAsJSONWithHeaders(t *testing.T) {
+	p, reqs := setupTests(t, validConfig)
+
+	err := p.Send(context.Background(), message)
+	require.NoError(t, err)
+	require.Len(t, *reqs, 1)
+
+	r := (*reqs)[0]
+	require.Equal(t, "application/json", r.header.Get("Content-Type"))
+	require.Equal(t, "Bearer abc", r.header.Get("Authorization"))
+
+	m := interfaces.WebhookMessage{}
+	require.NoError(t, json.Unmarshal(r.body, &m))
+	require.Equal(t, message, m)
+}
+
+func TestSendsMessageWithSignature(t *testing.T) {
+	p, reqs := setupTests(t, validConfigWithSecret)
+
+	err := p.Send(context.Background(), message)
+	require.NoError(t, err)
+	require.Len(t, *reqs, 1)
+
+	r := (*reqs)[0]
+	require.Equal(t, "sha256="+Sign("topsecret", r.body), r.header.Get(SignatureHeader))
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	p, reqs := setupTests(t, validConfig, http.StatusBadGateway, http.StatusTooManyRequests)
+
+	err := p.Send(context.Background(), message)
+	require.NoError(t, err)
+	require.Len(t, *reqs, 3)
+}
+
+func TestReturnsErrorWhenRetriesExhausted(t *testing.T) {
+	p, reqs := setupTests(t, validConfig, 500, 500, 500)
+
+	err := p.Send(context.Background(), message)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "after 3 attempts")
+	require.Len(t, *reqs, 3)
+}
+
+func TestDoesNotRetryWhenRetriesZero(t *testing.T) {
+	p, reqs := setupTests(t, `{"url": "%s", "retries": 0}`, 500)
+
+	err := p.Send(context.Background(), message)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "after 1 attempts")
+	require.Len(t, *reqs, 1)
+}
+
+func TestStopsRetryingWhenContextCancelled(t *testing.T) {
+	p, reqs := setupTests(t, `{"url": "%s", "retry_interval": "1h"}`, 500)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	err := p.Send(ctx, message)
+	require.Error(t, err)
+	require.Len(t, *reqs, 1)
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	p, reqs := setupTests(t, validConfig, http.StatusUnauthorized)
+
+	err := p.Send(context.Background(), message)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got 401")
+	require.Len(t, *reqs, 1)
+}
+
+func TestDoesNotSendMessageWhenStatusNotInList(t *testing.T) {
+	p, reqs := setupTests(t, validConfigWithStatus)
+
+	err := p.Send(context.Background(), message)
+	require.NoError(t, err)
+	require.Len(t, *reqs, 0)
+}
+
+var validConfig = `
+{
+	"url": "%s",
+	"headers": {
+		"Authorization": "Bearer abc"
+	},
+	"retries": 2,
+	"retry_interval": "1ms"
+}
+`
+
+var validConfigWithSecret = `
+{
+	"url": "%s",
+	"secret": "topsecret"
+}
+`
+
+var validConfigWithStatus = `
+{
+	"url": "%s",
+	"status": ["state_destroy"]
+}
+`
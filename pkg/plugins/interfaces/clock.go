@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time for the statemachine and plugins, replacing the real clock
+// allows a release to be simulated without waiting for the configured durations
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time on the
+	// returned channel
+	After(d time.Duration) <-chan time.Time
+
+	// WithTimeout returns a copy of the context that is cancelled when the duration
+	// elapses on the clock
+	WithTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc)
+}
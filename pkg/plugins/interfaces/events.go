@@ -0,0 +1,49 @@
+package interfaces
+
+import "time"
+
+const (
+	ReleaseEventState   = "state"   // published when the release enters a new state
+	ReleaseEventTraffic = "traffic" // published when the traffic sent to the candidate changes
+	ReleaseEventCheck   = "check"   // published when a monitor check completes
+	ReleaseEventWebhook = "webhook" // published when the webhooks for the release are called
+)
+
+// ReleaseEvent describes a change to a release
+type ReleaseEvent struct {
+	// Type of the event e.g. state, traffic, check, webhook
+	Type string `json:"type"`
+
+	Release string    `json:"release"`
+	Time    time.Time `json:"time"`
+
+	// State of the release when the event was published
+	State string `json:"state"`
+
+	// CandidateTraffic is the percentage of traffic sent to the candidate
+	CandidateTraffic int `json:"candidate_traffic"`
+
+	// Event is the statemachine event that caused a state change
+	Event string `json:"event,omitempty"`
+
+	// Result is the result of a monitor check, or the outcome sent to webhooks
+	Result string `json:"result,omitempty"`
+
+	// Monitor is the name of the monitor for a check made by one of multiple monitors
+	Monitor string `json:"monitor,omitempty"`
+
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Events distributes release events to subscribers
+type Events interface {
+	// Publish sends the event to all subscribers for the release, Publish does not block
+	// when a subscriber is not receiving events, events for the subscriber are dropped
+	Publish(e ReleaseEvent)
+
+	// Subscribe returns a channel that receives the events for the named release, when name
+	// is empty the channel receives the events for all releases. The returned function must be
+	// called to remove the subscription.
+	Subscribe(name string) (<-chan ReleaseEvent, func())
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"context"
+)
+
+// GateResult is the outcome of evaluating a gate
+type GateResult string
+
+const (
+	GateResultPending  GateResult = "pending"  // the decision has not been made, the gate is checked again after the interval
+	GateResultApproved GateResult = "approved" // the release can continue
+	GateResultRejected GateResult = "rejected" // the release is rolled back
+)
+
+// GateRequest contains the details of the release that is waiting for the gate
+type GateRequest struct {
+	Gate             string `json:"gate"`
+	Release          string `json:"release"`
+	Namespace        string `json:"namespace"`
+	Candidate        string `json:"candidate"`
+	Stage            string `json:"stage"`
+	CandidateTraffic int    `json:"candidate_traffic"`
+}
+
+// GateResponse is the decision for a gate, it is returned by a Gate plugin or sent to the
+// API by an external approval service
+type GateResponse struct {
+	Result GateResult `json:"result"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+// Gate defines a plugin that approves a release before traffic is sent to the candidate or
+// before the candidate is promoted
+type Gate interface {
+	Configurable
+
+	// Check returns the decision for the request, GateResultPending is returned when the
+	// decision has not been made
+	Check(ctx context.Context, request GateRequest) (GateResponse, error)
+}
@@ -14,6 +14,22 @@ const (
 	CheckError
 )
 
+// String returns the name of the check result
+func (c CheckResult) String() string {
+	switch c {
+	case CheckSuccess:
+		return "success"
+	case CheckFailed:
+		return "failed"
+	case CheckNoMetrics:
+		return "no_metrics"
+	case CheckError:
+		return "error"
+	}
+
+	return "unknown"
+}
+
 // Monitor defines an interface that all Monitoring platforms like Prometheus must implement
 type Monitor interface {
 	Configurable
@@ -27,6 +27,9 @@ type Provider interface {
 	// CreatePostDeploymentTest returns a PostDeploymentTest plugin that corresponds to the given name
 	CreatePostDeploymentTest(pluginName, deploymentName, namespace, runtime string, mp Monitor) (PostDeploymentTest, error)
 
+	// CreateGate returns a Gate plugin that corresponds to the given name
+	CreateGate(pluginName string) (Gate, error)
+
 	// GetRuntimeClient gets a client for interacting with runtime deployments
 	GetRuntimeClient(runtimeName string) (RuntimeClient, error)
 
@@ -39,6 +42,12 @@ type Provider interface {
 	// Gets an instance of the data store plugin
 	GetDataStore() Store
 
+	// Gets the clock used by the statemachine and plugins
+	GetClock() Clock
+
+	// Gets the events that the statemachine publishes release changes to
+	GetEvents() Events
+
 	// Gets the statemachine for the given release
 	// either creates a new or returns an existing statemachine
 	GetStateMachine(release *models.Release) (StateMachine, error)
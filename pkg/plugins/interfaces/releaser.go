@@ -12,6 +12,25 @@ const (
 	Candidate ServiceVariant = 2
 )
 
+const (
+	RouteMatchTypeHeader = "header"
+	RouteMatchTypeCookie = "cookie"
+	RouteMatchTypeQuery  = "query"
+)
+
+// RouteMatch defines a rule that matches a request using a header, cookie, or query parameter
+type RouteMatch struct {
+	// Type of the request attribute to match, header, cookie, or query
+	Type string `hcl:"type" json:"type" validate:"required,oneof=header cookie query"`
+	// Name of the header, cookie, or query parameter
+	Name string `hcl:"name" json:"name" validate:"required"`
+	// Exact value to match
+	Exact string `hcl:"exact,optional" json:"exact,omitempty"`
+	// Regex to match the value against, when neither Exact or Regex are set the
+	// rule matches any request where the attribute is present
+	Regex string `hcl:"regex,optional" json:"regex,omitempty"`
+}
+
 type ReleaserBaseConfig struct {
 	ConsulService string `json:"consul_service" validate:"required"`
 	Namespace     string `json:"namespace"`
@@ -28,6 +47,11 @@ type Releaser interface {
 
 	// Setup the necessary configuration for the service mesh
 	// Returning an error from this function will fail the deployment
+	//
+	// Setup, Scale, and Route block until the service mesh has applied the configuration, this
+	// ensures that requests are not sent to service instances that are about to be removed.
+	// When the plugin can not confirm the configuration has been applied before its timeout
+	// the method returns without error.
 	Setup(ctx context.Context, primarySubsetFilter, candidateSubsetFilter string) error
 
 	// Scale sets the percentage of traffic that is distributed to the canary instance
@@ -36,6 +60,12 @@ type Releaser interface {
 	// the Primary would be 10%
 	Scale(ctx context.Context, value int) error
 
+	// Route sends any request that matches one of the given rules to the candidate, requests
+	// that do not match are distributed using the traffic split set by Scale.
+	//
+	// Calling Route with no rules removes any routes to the candidate
+	Route(ctx context.Context, matches []RouteMatch) error
+
 	// Destroy removes any configuration that was created with the Configure method
 	Destroy(ctx context.Context) error
 
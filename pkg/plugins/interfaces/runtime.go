@@ -33,6 +33,8 @@ type RuntimeBaseConfig struct {
 type RuntimeBaseState struct {
 	// CandidateName is the full name of the active candidate deployment
 	CandidateName string `hcl:"candidate_name" json:"candidate_name"`
+	// CandidateVersion is the version of the candidate deployment that was admitted
+	CandidateVersion string `hcl:"candidate_version,optional" json:"candidate_version,omitempty"`
 	// PrimaryName is the full name of the active primary deployment
 	PrimaryName string `hcl:"primary_name" json:"primary_name"`
 }
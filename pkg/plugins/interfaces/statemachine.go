@@ -1,44 +1,74 @@
 package interfaces
 
 const (
-	EventDeploy     = "event_deploy"     // triggers a new deployment
-	EventDeployed   = "event_deployed"   // fired when a new deployment has completed successfully
-	EventConfigure  = "event_configure"  // triggers the configuration of a new release
-	EventConfigured = "event_configured" // fired when the release has been successfully configured
-	EventHealthy    = "event_healthy"    // fired when a new deployment is healthy based on configured metrics
-	EventUnhealthy  = "event_unhealthy"  // fired when a new deployment is unhealthy based on configured metrics
-	EventScaled     = "event_scaled"     // fired when the release traffic has been scaled
-	EventPromoted   = "event_promoted"   // fired when the new deployment has been promoted to active deployment
-	EventComplete   = "event_complete"   // fired when all release traffic points at the new deployment
-	EventFail       = "event_fail"       // fired when any state returns an error
-	EventDestroy    = "event_destroy"    // triggers the destruction of a release
-	EventNull       = "event_null"       // null event
-
-	StateStart     = "state_start"     // initial state for a new release
-	StateConfigure = "state_configure" // state when the release is currently configuring
-	StateIdle      = "state_idle"      // state when the release is configured but inactive
-	StateDeploy    = "state_deploy"    // state when the a new deployment is being created
-	StateMonitor   = "state_monitor"   // state when the new deployment is being monitored for correctness
-	StateScale     = "state_scale"     // state when the new deployment traffic is being scaled
-	StatePromote   = "state_promote"   // state when the latest deployment is being promoted to active deployment
-	StateRollback  = "state_rollback"  // state when the latest deployment is being removed
-	StateFail      = "state_fail"      // state when the latest operation has failed
-	StateDestroy   = "state_destroy"   // state when the release is being destroyed
+	EventDeploy         = "event_deploy"          // triggers a new deployment
+	EventPending        = "event_pending"         // triggers a new deployment that waits for the releases it depends on
+	EventDeployed       = "event_deployed"        // fired when a new deployment has completed successfully
+	EventConfigure      = "event_configure"       // triggers the configuration of a new release
+	EventConfigured     = "event_configured"      // fired when the release has been successfully configured
+	EventHealthy        = "event_healthy"         // fired when a new deployment is healthy based on configured metrics
+	EventUnhealthy      = "event_unhealthy"       // fired when a new deployment is unhealthy based on configured metrics
+	EventScaled         = "event_scaled"          // fired when the release traffic has been scaled
+	EventPromoted       = "event_promoted"        // fired when the new deployment has been promoted to active deployment
+	EventComplete       = "event_complete"        // fired when all release traffic points at the new deployment
+	EventAwaitPromotion = "event_await_promotion" // fired when the strategy is complete but the release requires manual promotion
+	EventPromote        = "event_promote"         // triggers the promotion of a release that is awaiting manual promotion
+	EventPause          = "event_pause"           // triggers the pausing of an in-flight release
+	EventResume         = "event_resume"          // triggers the resumption of a paused release
+	EventAbort          = "event_abort"           // triggers the rollback of an in-flight release
+	EventFail           = "event_fail"            // fired when any state returns an error
+	EventTimeout        = "event_timeout"         // fired when any state does not complete before its timeout
+	EventDestroy        = "event_destroy"         // triggers the destruction of a release
+	EventWaiting        = "event_waiting"         // sent to webhooks when the release is waiting for its schedule or dependencies
+	EventNull           = "event_null"            // null event
+
+	StateStart          = "state_start"           // initial state for a new release
+	StateConfigure      = "state_configure"       // state when the release is currently configuring
+	StateIdle           = "state_idle"            // state when the release is configured but inactive
+	StatePending        = "state_pending"         // state when a new deployment is waiting for the releases it depends on
+	StateDeploy         = "state_deploy"          // state when the a new deployment is being created
+	StateMonitor        = "state_monitor"         // state when the new deployment is being monitored for correctness
+	StateScale          = "state_scale"           // state when the new deployment traffic is being scaled
+	StatePromote        = "state_promote"         // state when the latest deployment is being promoted to active deployment
+	StateAwaitPromotion = "state_await_promotion" // state when the latest deployment is waiting for manual promotion
+	StatePaused         = "state_paused"          // state when the latest deployment has been paused and traffic is frozen
+	StateRollback       = "state_rollback"        // state when the latest deployment is being removed
+	StateFail           = "state_fail"            // state when the latest operation has failed
+	StateDestroy        = "state_destroy"         // state when the release is being destroyed
 )
 
 type StateMachine interface {
 	// Configure triggers the EventConfigure state
 	Configure() error
 
-	// Deploy triggers the EventDeploy state
+	// Deploy triggers the EventDeploy state, or the EventPending state when the release
+	// depends on other releases
 	Deploy() error
 
 	// Destroy triggers the event Destroy state
 	Destroy() error
 
+	// Promote triggers the EventPromote state for a release that is awaiting manual promotion
+	Promote() error
+
+	// Pause triggers the EventPause state, freezing the traffic for an in-flight release
+	Pause() error
+
+	// Abort triggers the EventAbort state, rolling back an in-flight release
+	Abort() error
+
+	// DecideGate records the decision for the named gate of a release that is waiting for the
+	// gate, the decision is used instead of checking the gate plugin
+	DecideGate(gate string, decision GateResponse) error
+
+	// Stop cancels any in-flight work for the current state without changing the state,
+	// it is called when the controller shuts down
+	Stop()
+
 	// CurrentState returns the current state
 	CurrentState() string
 
-	// Resume the statemachine from the current state
+	// Resume the statemachine from the current state, a paused release continues
+	// monitoring from the point it was paused
 	Resume() error
 }
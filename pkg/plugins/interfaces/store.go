@@ -18,6 +18,7 @@ type ListOptions struct {
 type Store interface {
 	ReleaseStore
 	PluginStateStore
+	DeploymentStore
 }
 
 type ReleaseStore interface {
@@ -41,6 +42,16 @@ type ReleaseStore interface {
 	CreatePluginStateStore(r *models.Release, pluginName string) PluginStateStore
 }
 
+type DeploymentStore interface {
+	// AppendDeployment adds the deployment record to the history for the named release, the oldest
+	// records are removed when the history has more than models.MaxDeployments records
+	AppendDeployment(releaseName string, d *models.Deployment) error
+
+	// ListDeployments returns the deployment history for the named release ordered from the
+	// oldest to the newest deployment, an empty list is returned when the release has no history
+	ListDeployments(releaseName string) ([]*models.Deployment, error)
+}
+
 type PluginStateStore interface {
 	// UpsertState creates a new state for the plugin if it does not already exist, or updates and existing plugin state
 	UpsertState(data []byte) error
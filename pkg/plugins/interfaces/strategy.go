@@ -1,16 +1,20 @@
 package interfaces
 
 import (
+	"time"
+
 	"golang.org/x/net/context"
 )
 
 type StrategyStatus string
 
 const (
-	StrategyStatusSuccess  = "strategy_status_progressing"
-	StrategyStatusFailing  = "strategy_status_failing"
-	StrategyStatusFailed   = "strategy_status_failed"
-	StrategyStatusComplete = "strategy_status_complete"
+	StrategyStatusSuccess           = "strategy_status_progressing"
+	StrategyStatusFailing           = "strategy_status_failing"
+	StrategyStatusFailed            = "strategy_status_failed"
+	StrategyStatusComplete          = "strategy_status_complete"
+	StrategyStatusAwaitingPromotion = "strategy_status_awaiting_promotion"
+	StrategyStatusPaused            = "strategy_status_paused"
 )
 
 // Strategy defines the interface for a roll out strategy like a Canary or a Blue/Green
@@ -19,10 +23,35 @@ type Strategy interface {
 
 	// Execute the strategy and return the StrategyStatus on a successful check
 	// when StrategyStatusSuccess is returned the new traffic amount to be sent to the service is returned
+	// when StrategyStatusAwaitingPromotion is returned the traffic amount to hold until the release is manually promoted is returned
+	// when StrategyStatusPaused is returned the current traffic and the error that caused the strategy to pause is returned,
+	// the release is paused until it is resumed manually
+	// when the context is cancelled Execute returns the context error without changing the progress of the strategy
 	Execute(ctx context.Context, candidateName string) (status StrategyStatus, traffic int, err error)
 
 	// GetPrimaryTraffic returns the percentage of traffic distributed to the primary instance
 	GetPrimaryTraffic() int
 	// GetCandidateTraffic returns the percentage of traffic distributed to the candidate instance
 	GetCandidateTraffic() int
+
+	// Reset clears any progress so that the next call to Execute starts a new roll out
+	Reset()
+}
+
+// WindowStrategy is implemented by strategies that monitor the candidate for a fixed window in a
+// single call to Execute, the window must complete before the monitor timeout of the release
+type WindowStrategy interface {
+	Strategy
+
+	// GetWindow returns the longest time that a single call to Execute monitors the candidate
+	GetWindow() time.Duration
+}
+
+// RoutingStrategy is implemented by strategies that send requests to the candidate based on
+// request attributes rather than a percentage of traffic
+type RoutingStrategy interface {
+	Strategy
+
+	// GetCandidateRoutes returns the rules that select the requests sent to the candidate
+	GetCandidateRoutes() []RouteMatch
 }
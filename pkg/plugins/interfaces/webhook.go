@@ -1,19 +1,32 @@
 package interfaces
 
+import "context"
+
 type WebhookMessage struct {
-	Title            string
-	Name             string
-	Namespace        string
-	State            string
-	Outcome          string
-	PrimaryTraffic   int
-	CandidateTraffic int
-	Error            string
+	Title            string `json:"title"`
+	Name             string `json:"name"`
+	Namespace        string `json:"namespace"`
+	State            string `json:"state"`
+	Outcome          string `json:"outcome"`
+	PrimaryTraffic   int    `json:"primary_traffic"`
+	CandidateTraffic int    `json:"candidate_traffic"`
+	Error            string `json:"error,omitempty"`
+
+	// Monitors contains the result of the latest check for each monitor when the release has
+	// multiple monitors
+	Monitors []MonitorResult `json:"monitors,omitempty"`
+}
+
+// MonitorResult is the result of a check made by one of multiple monitors
+type MonitorResult struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
 }
 
 type Webhook interface {
 	Configurable
 
-	// Send makes an outbound webhook call
-	Send(message WebhookMessage) error
+	// Send makes an outbound webhook call, the call should be abandoned when the context is cancelled
+	Send(ctx context.Context, message WebhookMessage) error
 }
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/nicholasjackson/consul-release-controller/pkg/models"
@@ -9,12 +10,27 @@ import (
 )
 
 type Store struct {
-	m        sync.Mutex
-	releases map[string]*models.Release
+	m           *sync.Mutex
+	releases    map[string]*models.Release
+	deployments map[string][]*models.Deployment
+	state       map[string][]byte
+
+	// used by pluginStateStore
+	releaseName string
+	pluginName  string
 }
 
 func NewStore() *Store {
-	return &Store{m: sync.Mutex{}, releases: map[string]*models.Release{}}
+	return &Store{
+		m:           &sync.Mutex{},
+		releases:    map[string]*models.Release{},
+		deployments: map[string][]*models.Deployment{},
+		state:       map[string][]byte{},
+	}
+}
+
+func (m *Store) CreatePluginStateStore(r *models.Release, pluginName string) interfaces.PluginStateStore {
+	return &Store{m.m, m.releases, m.deployments, m.state, r.Name, pluginName}
 }
 
 func (m *Store) UpsertRelease(d *models.Release) error {
@@ -64,6 +80,14 @@ func (m *Store) DeleteRelease(name string) error {
 	}
 
 	delete(m.releases, r.Name)
+	delete(m.deployments, r.Name)
+
+	// remove the plugin state for the release
+	for k := range m.state {
+		if strings.HasPrefix(k, r.Name+"/") {
+			delete(m.state, k)
+		}
+	}
 
 	return nil
 }
@@ -80,3 +104,56 @@ func (m *Store) GetRelease(name string) (*models.Release, error) {
 
 	return nil, interfaces.ReleaseNotFound
 }
+
+func (m *Store) AppendDeployment(releaseName string, d *models.Deployment) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	deps := append(m.deployments[releaseName], d)
+	if len(deps) > models.MaxDeployments {
+		deps = deps[len(deps)-models.MaxDeployments:]
+	}
+
+	m.deployments[releaseName] = deps
+
+	return nil
+}
+
+func (m *Store) ListDeployments(releaseName string) ([]*models.Deployment, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	ret := []*models.Deployment{}
+	ret = append(ret, m.deployments[releaseName]...)
+
+	return ret, nil
+}
+
+func (m *Store) UpsertState(data []byte) error {
+	if m.pluginName == "" || m.releaseName == "" {
+		return fmt.Errorf("storage incorrectly configured, no pluginName or releaseName")
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	m.state[m.releaseName+"/"+m.pluginName] = data
+
+	return nil
+}
+
+func (m *Store) GetState() ([]byte, error) {
+	if m.pluginName == "" || m.releaseName == "" {
+		return nil, fmt.Errorf("storage incorrectly configured, no pluginName or releaseName")
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	d, ok := m.state[m.releaseName+"/"+m.pluginName]
+	if !ok {
+		return nil, interfaces.PluginStateNotFound
+	}
+
+	return d, nil
+}
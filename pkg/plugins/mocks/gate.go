@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/stretchr/testify/mock"
+)
+
+type GateMock struct {
+	mock.Mock
+}
+
+func (m *GateMock) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	args := m.Called(data, log, store)
+
+	return args.Error(0)
+}
+
+func (m *GateMock) Check(ctx context.Context, request interfaces.GateRequest) (interfaces.GateResponse, error) {
+	args := m.Called(ctx, request)
+
+	return args.Get(0).(interfaces.GateResponse), args.Error(1)
+}
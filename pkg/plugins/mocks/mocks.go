@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
+	"github.com/nicholasjackson/consul-release-controller/pkg/events"
 	"github.com/nicholasjackson/consul-release-controller/pkg/models"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/stretchr/testify/mock"
@@ -20,6 +22,7 @@ type Mocks struct {
 	StoreMock          *StoreMock
 	StateMachineMock   *StateMachineMock
 	WebhookMock        *WebhookMock
+	GateMock           *GateMock
 	LogBuffer          *bytes.Buffer
 }
 
@@ -31,6 +34,7 @@ func BuildMocks(t *testing.T) (*ProviderMock, *Mocks) {
 	relMock.On("BaseConfig").Return(nil)
 	relMock.On("Setup", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	relMock.On("Scale", mock.Anything, mock.Anything).Return(nil)
+	relMock.On("Route", mock.Anything, mock.Anything).Return(nil)
 	relMock.On("Destroy", mock.Anything, mock.Anything).Return(nil)
 	relMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Return(nil)
 
@@ -55,6 +59,7 @@ func BuildMocks(t *testing.T) (*ProviderMock, *Mocks) {
 	stratMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusSuccess, 10, nil)
 	stratMock.On("GetPrimaryTraffic", mock.Anything).Return(40)
 	stratMock.On("GetCandidateTraffic", mock.Anything).Return(60)
+	stratMock.On("Reset").Return()
 
 	metricsMock := &MetricsMock{}
 	metricsMock.On("ServiceStarting")
@@ -65,6 +70,12 @@ func BuildMocks(t *testing.T) (*ProviderMock, *Mocks) {
 	stateMock.On("Configure").Return(nil)
 	stateMock.On("Deploy").Return(nil)
 	stateMock.On("Destroy").Return(nil)
+	stateMock.On("Promote").Return(nil)
+	stateMock.On("Pause").Return(nil)
+	stateMock.On("Resume").Return(nil)
+	stateMock.On("Abort").Return(nil)
+	stateMock.On("DecideGate", mock.Anything, mock.Anything).Return(nil)
+	stateMock.On("Stop").Return()
 	stateMock.On("CurrentState").Return(interfaces.StateStart)
 
 	storeMock := &StoreMock{}
@@ -75,6 +86,8 @@ func BuildMocks(t *testing.T) (*ProviderMock, *Mocks) {
 	storeMock.On("CreatePluginStateStore", mock.Anything, mock.Anything).Return(storeMock)
 	storeMock.On("UpsertState", mock.Anything).Return(nil)
 	storeMock.On("GetState").Return(nil, nil)
+	storeMock.On("AppendDeployment", mock.Anything, mock.Anything).Return(nil)
+	storeMock.On("ListDeployments", mock.Anything).Return(nil, nil)
 
 	webhookMock := &WebhookMock{}
 	webhookMock.On("Configure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
@@ -84,6 +97,10 @@ func BuildMocks(t *testing.T) (*ProviderMock, *Mocks) {
 	postDeploymentMock.On("Configure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	postDeploymentMock.On("Execute", mock.Anything, mock.Anything).Return(nil)
 
+	gateMock := &GateMock{}
+	gateMock.On("Configure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	gateMock.On("Check", mock.Anything, mock.Anything).Return(interfaces.GateResponse{Result: interfaces.GateResultApproved}, nil)
+
 	provMock := &ProviderMock{}
 
 	provMock.On("CreateReleaser", mock.Anything).Return(relMock, nil)
@@ -92,6 +109,7 @@ func BuildMocks(t *testing.T) (*ProviderMock, *Mocks) {
 	provMock.On("CreateStrategy", mock.Anything).Return(stratMock, nil)
 	provMock.On("CreateWebhook", mock.Anything).Return(webhookMock, nil)
 	provMock.On("CreatePostDeploymentTest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(postDeploymentMock, nil)
+	provMock.On("CreateGate", mock.Anything).Return(gateMock, nil)
 
 	logBuffer := bytes.NewBufferString("")
 
@@ -105,10 +123,12 @@ func BuildMocks(t *testing.T) (*ProviderMock, *Mocks) {
 	provMock.On("GetLogger", mock.Anything).Return(logger)
 	provMock.On("GetMetrics").Return(metricsMock)
 	provMock.On("GetDataStore").Return(storeMock)
+	provMock.On("GetClock").Return(clock.NewReal())
+	provMock.On("GetEvents").Return(events.NewBroker())
 	provMock.On("GetStateMachine", mock.Anything).Return(stateMock, nil)
 	provMock.On("DeleteStateMachine", mock.Anything).Return(nil)
 
-	return provMock, &Mocks{relMock, runMock, monMock, stratMock, postDeploymentMock, metricsMock, storeMock, stateMock, webhookMock, logBuffer}
+	return provMock, &Mocks{relMock, runMock, monMock, stratMock, postDeploymentMock, metricsMock, storeMock, stateMock, webhookMock, gateMock, logBuffer}
 }
 
 // ProviderMock is a mock implementation of the provider that can be used for testing
@@ -152,6 +172,12 @@ func (p *ProviderMock) CreatePostDeploymentTest(pluginName, deploymentName, name
 	return args.Get(0).(interfaces.PostDeploymentTest), args.Error(1)
 }
 
+func (p *ProviderMock) CreateGate(pluginName string) (interfaces.Gate, error) {
+	args := p.Called(pluginName)
+
+	return args.Get(0).(interfaces.Gate), args.Error(1)
+}
+
 func (p *ProviderMock) GetRuntimeClient(runtime string) (interfaces.RuntimeClient, error) {
 	args := p.Called(runtime)
 
@@ -177,6 +203,16 @@ func (p *ProviderMock) GetDataStore() interfaces.Store {
 	return args.Get(0).(interfaces.Store)
 }
 
+func (p *ProviderMock) GetClock() interfaces.Clock {
+	args := p.Called()
+	return args.Get(0).(interfaces.Clock)
+}
+
+func (p *ProviderMock) GetEvents() interfaces.Events {
+	args := p.Called()
+	return args.Get(0).(interfaces.Events)
+}
+
 func (p *ProviderMock) GetStateMachine(release *models.Release) (interfaces.StateMachine, error) {
 	args := p.Called(release)
 
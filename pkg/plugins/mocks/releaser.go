@@ -54,6 +54,12 @@ func (s *ReleaserMock) Scale(ctx context.Context, value int) error {
 	return nil
 }
 
+func (s *ReleaserMock) Route(ctx context.Context, matches []interfaces.RouteMatch) error {
+	args := s.Called(ctx, matches)
+
+	return args.Error(0)
+}
+
 func (s *ReleaserMock) Destroy(ctx context.Context) error {
 	args := s.Called(ctx)
 
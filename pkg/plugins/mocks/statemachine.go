@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -29,6 +30,27 @@ func (sm *StateMachineMock) Destroy() error {
 	return args.Error(0)
 }
 
+// Promote triggers the event Promote state
+func (sm *StateMachineMock) Promote() error {
+	args := sm.Called()
+
+	return args.Error(0)
+}
+
+// Pause triggers the event Pause state
+func (sm *StateMachineMock) Pause() error {
+	args := sm.Called()
+
+	return args.Error(0)
+}
+
+// Abort triggers the event Abort state
+func (sm *StateMachineMock) Abort() error {
+	args := sm.Called()
+
+	return args.Error(0)
+}
+
 // Resume triggers the event Resume state
 func (sm *StateMachineMock) Resume() error {
 	args := sm.Called()
@@ -36,6 +58,18 @@ func (sm *StateMachineMock) Resume() error {
 	return args.Error(0)
 }
 
+// DecideGate records the decision for the named gate
+func (sm *StateMachineMock) DecideGate(gate string, decision interfaces.GateResponse) error {
+	args := sm.Called(gate, decision)
+
+	return args.Error(0)
+}
+
+// Stop cancels any in-flight work for the current state
+func (sm *StateMachineMock) Stop() {
+	sm.Called()
+}
+
 // CurrentState returns the current state
 func (sm *StateMachineMock) CurrentState() string {
 	args := sm.Called()
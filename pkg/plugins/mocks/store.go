@@ -40,6 +40,21 @@ func (m *StoreMock) GetRelease(name string) (*models.Release, error) {
 	return nil, args.Error(1)
 }
 
+func (m *StoreMock) AppendDeployment(releaseName string, d *models.Deployment) error {
+	args := m.Called(releaseName, d)
+	return args.Error(0)
+}
+
+func (m *StoreMock) ListDeployments(releaseName string) ([]*models.Deployment, error) {
+	args := m.Called(releaseName)
+
+	if d, ok := args.Get(0).([]*models.Deployment); ok {
+		return d, args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
 func (m *StoreMock) CreatePluginStateStore(r *models.Release, pluginName string) interfaces.PluginStateStore {
 	m.Called(r, pluginName)
 	return m
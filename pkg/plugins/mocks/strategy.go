@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
@@ -32,3 +33,29 @@ func (p *StrategyMock) GetPrimaryTraffic() int {
 func (p *StrategyMock) GetCandidateTraffic() int {
 	return p.Called().Int(0)
 }
+
+func (p *StrategyMock) Reset() {
+	p.Called()
+}
+
+type WindowStrategyMock struct {
+	StrategyMock
+}
+
+func (p *WindowStrategyMock) GetWindow() time.Duration {
+	return p.Called().Get(0).(time.Duration)
+}
+
+type RoutingStrategyMock struct {
+	StrategyMock
+}
+
+func (p *RoutingStrategyMock) GetCandidateRoutes() []interfaces.RouteMatch {
+	args := p.Called()
+
+	if r, ok := args.Get(0).([]interfaces.RouteMatch); ok {
+		return r
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/hashicorp/go-hclog"
@@ -18,8 +19,8 @@ func (m *WebhookMock) Configure(data json.RawMessage, log hclog.Logger, store in
 	return args.Error(0)
 }
 
-func (m *WebhookMock) Send(msg interfaces.WebhookMessage) error {
-	args := m.Called(msg)
+func (m *WebhookMock) Send(ctx context.Context, msg interfaces.WebhookMessage) error {
+	args := m.Called(ctx, msg)
 
 	return args.Error(0)
 }
@@ -0,0 +1,210 @@
+package podhealth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	reasonOOMKilled        = "OOMKilled"
+	reasonCrashLoopBackOff = "CrashLoopBackOff"
+)
+
+type Plugin struct {
+	log       hclog.Logger
+	config    *PluginConfig
+	store     interfaces.PluginStateStore
+	client    clients.Kubernetes
+	namespace string
+
+	// readiness is the readiness of each candidate pod at the previous check, keyed by pod name
+	readiness     map[string]*podReadiness
+	readinessLock sync.Mutex
+}
+
+type PluginConfig struct {
+	// MaxRestarts is the maximum number of times any container in a candidate pod can restart, defaults to 3
+	MaxRestarts *int `json:"max_restarts,omitempty" validate:"omitempty,gte=0"`
+
+	// MaxReadinessChanges is the maximum number of times the readiness of any candidate pod can change
+	// after it is first checked, defaults to 4
+	MaxReadinessChanges *int `json:"max_readiness_changes,omitempty" validate:"omitempty,gte=0"`
+
+	// IgnoreOOMKilled does not fail the check when a container has been terminated because it ran out of memory
+	IgnoreOOMKilled bool `json:"ignore_oom_killed,omitempty"`
+
+	// IgnoreCrashLoopBackOff does not fail the check when a container is waiting to restart after crashing
+	IgnoreCrashLoopBackOff bool `json:"ignore_crash_loop_back_off,omitempty"`
+}
+
+// podReadiness is the last observed readiness of a pod and the number of times it has changed
+type podReadiness struct {
+	ready          bool
+	lastTransition time.Time
+	changes        int
+}
+
+// New creates a new pod health monitor for the candidate deployment in the given namespace
+func New(namespace string, client clients.Kubernetes, l hclog.Logger) (*Plugin, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return &Plugin{
+		log:       l,
+		client:    client,
+		namespace: namespace,
+		readiness: map[string]*podReadiness{},
+	}, nil
+}
+
+var ErrInvalidMaxRestarts = fmt.Errorf("MaxRestarts must be greater than or equal to 0")
+var ErrInvalidMaxReadinessChanges = fmt.Errorf("MaxReadinessChanges must be greater than or equal to 0")
+
+func (s *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	s.log = log
+	s.store = store
+	s.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, s.config)
+	if err != nil {
+		return fmt.Errorf("unable to decode Monitoring config: %s", err)
+	}
+
+	validate := validator.New()
+	err = validate.Struct(s.config)
+
+	if err != nil {
+		errorMessage := ""
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.MaxRestarts":
+				errorMessage += ErrInvalidMaxRestarts.Error() + "\n"
+			case "PluginConfig.MaxReadinessChanges":
+				errorMessage += ErrInvalidMaxReadinessChanges.Error() + "\n"
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	if s.config.MaxRestarts == nil {
+		three := 3
+		s.config.MaxRestarts = &three
+	}
+
+	if s.config.MaxReadinessChanges == nil {
+		four := 4
+		s.config.MaxReadinessChanges = &four
+	}
+
+	return nil
+}
+
+// Check inspects the pods of the candidate deployment and returns CheckFailed when a container has restarted
+// more than the allowed number of times, has been OOMKilled, is in CrashLoopBackOff, or the readiness of a pod
+// has changed more than the allowed number of times
+func (s *Plugin) Check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	pods, err := s.client.GetKubernetesDeploymentPods(ctx, candidateName, s.namespace)
+	if err != nil {
+		s.log.Error("unable to get candidate pods", "name", candidateName, "namespace", s.namespace, "error", err)
+
+		return interfaces.CheckError, fmt.Errorf("unable to get pods for candidate %s: %s", candidateName, err)
+	}
+
+	if len(pods) == 0 {
+		return interfaces.CheckNoMetrics, fmt.Errorf("no pods found for candidate %s in namespace %s", candidateName, s.namespace)
+	}
+
+	problems := []string{}
+
+	s.readinessLock.Lock()
+	defer s.readinessLock.Unlock()
+
+	current := map[string]*podReadiness{}
+
+	for _, p := range pods {
+		for _, cs := range p.Status.ContainerStatuses {
+			problems = append(problems, s.checkContainer(p.Name, cs)...)
+		}
+
+		r := s.updateReadiness(p)
+		current[p.Name] = r
+
+		if r.changes > *s.config.MaxReadinessChanges {
+			problems = append(problems, fmt.Sprintf("pod %s readiness changed %d times, more than the limit %d", p.Name, r.changes, *s.config.MaxReadinessChanges))
+		}
+	}
+
+	// pods that no longer exist are removed so the readiness does not grow
+	s.readiness = current
+
+	s.log.Debug("checked candidate pods", "name", candidateName, "namespace", s.namespace, "pods", len(pods), "problems", len(problems))
+
+	if len(problems) > 0 {
+		return interfaces.CheckFailed, fmt.Errorf("check failed for candidate %s, %s", candidateName, strings.Join(problems, ", "))
+	}
+
+	return interfaces.CheckSuccess, nil
+}
+
+// checkContainer returns the problems found with the container
+func (s *Plugin) checkContainer(pod string, cs corev1.ContainerStatus) []string {
+	problems := []string{}
+
+	if int(cs.RestartCount) > *s.config.MaxRestarts {
+		problems = append(problems, fmt.Sprintf("pod %s container %s restarted %d times, more than the limit %d", pod, cs.Name, cs.RestartCount, *s.config.MaxRestarts))
+	}
+
+	if !s.config.IgnoreOOMKilled {
+		if (cs.State.Terminated != nil && cs.State.Terminated.Reason == reasonOOMKilled) ||
+			(cs.LastTerminationState.Terminated != nil && cs.LastTerminationState.Terminated.Reason == reasonOOMKilled) {
+			problems = append(problems, fmt.Sprintf("pod %s container %s was OOMKilled", pod, cs.Name))
+		}
+	}
+
+	if !s.config.IgnoreCrashLoopBackOff && cs.State.Waiting != nil && cs.State.Waiting.Reason == reasonCrashLoopBackOff {
+		problems = append(problems, fmt.Sprintf("pod %s container %s is in CrashLoopBackOff", pod, cs.Name))
+	}
+
+	return problems
+}
+
+// updateReadiness compares the readiness of the pod with the previous check, a pod that has the same readiness
+// but a different transition time has changed at least twice since the previous check
+func (s *Plugin) updateReadiness(p corev1.Pod) *podReadiness {
+	current := &podReadiness{}
+
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			current.ready = c.Status == corev1.ConditionTrue
+			current.lastTransition = c.LastTransitionTime.Time
+		}
+	}
+
+	prev, ok := s.readiness[p.Name]
+	if !ok {
+		return current
+	}
+
+	current.changes = prev.changes
+
+	switch {
+	case current.ready != prev.ready:
+		current.changes++
+	case !current.lastTransition.Equal(prev.lastTransition):
+		current.changes += 2
+	}
+
+	return current
+}
@@ -0,0 +1,226 @@
+package podhealth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func setupPlugin(t *testing.T, config string) (*Plugin, *fake.Clientset) {
+	l := hclog.NewNullLogger()
+
+	labels := map[string]string{"app": "api"}
+
+	cs := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-deployment", Namespace: "default", Annotations: revision("2")},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		},
+		replicaSet("api-deployment-2", "api-deployment", "2", labels),
+		replicaSet("api-deployment-1", "api-deployment", "1", labels),
+		replicaSet("api-deployment-primary-1", "api-deployment-primary", "1", labels),
+		pod("api-deployment-2-abc", "api-deployment-2", labels),
+		pod("api-deployment-2-def", "api-deployment-2", labels),
+		// the previous revision and the primary are crash looping, they should not be checked
+		crashLoop(pod("api-deployment-1-abc", "api-deployment-1", labels)),
+		crashLoop(pod("api-deployment-primary-1-abc", "api-deployment-primary-1", labels)),
+	)
+
+	p, _ := New("", clients.NewKubernetesWithClientset(cs, time.Second, time.Millisecond, l), l)
+
+	err := p.Configure([]byte(config), l, &mocks.StoreMock{})
+	require.NoError(t, err)
+
+	return p, cs
+}
+
+func revision(r string) map[string]string {
+	return map[string]string{"deployment.kubernetes.io/revision": r}
+}
+
+func replicaSet(name, deployment, rev string, labels map[string]string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Labels:          labels,
+			Annotations:     revision(rev),
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: deployment}},
+		},
+	}
+}
+
+func pod(name, replicaSet string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: replicaSet}},
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(time.Now().Add(-1 * time.Minute))},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "api", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+}
+
+func crashLoop(p *corev1.Pod) *corev1.Pod {
+	p.Status.ContainerStatuses[0].RestartCount = 10
+	p.Status.ContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+
+	return p
+}
+
+func updatePod(t *testing.T, cs *fake.Clientset, name string, f func(p *corev1.Pod)) {
+	p, err := cs.CoreV1().Pods("default").Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	f(p)
+
+	_, err = cs.CoreV1().Pods("default").Update(context.Background(), p, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+func TestConfigureSetsDefaults(t *testing.T) {
+	p, _ := setupPlugin(t, `{}`)
+
+	require.Equal(t, 3, *p.config.MaxRestarts)
+	require.Equal(t, 4, *p.config.MaxReadinessChanges)
+	require.Equal(t, "default", p.namespace)
+}
+
+func TestConfigureReturnsErrorWhenLimitsInvalid(t *testing.T) {
+	p, _ := New("default", nil, hclog.NewNullLogger())
+
+	err := p.Configure([]byte(`{"max_restarts": -1}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrInvalidMaxRestarts.Error())
+}
+
+func TestCheckReturnsSuccessWhenCandidatePodsHealthy(t *testing.T) {
+	p, _ := setupPlugin(t, `{}`)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestCheckReturnsNoMetricsWhenNoPods(t *testing.T) {
+	p, cs := setupPlugin(t, `{}`)
+
+	cs.CoreV1().Pods("default").Delete(context.Background(), "api-deployment-2-abc", metav1.DeleteOptions{})
+	cs.CoreV1().Pods("default").Delete(context.Background(), "api-deployment-2-def", metav1.DeleteOptions{})
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestCheckReturnsFailedWhenRestartsGreaterThanMax(t *testing.T) {
+	p, cs := setupPlugin(t, `{"max_restarts": 1}`)
+
+	updatePod(t, cs, "api-deployment-2-def", func(p *corev1.Pod) {
+		p.Status.ContainerStatuses[0].RestartCount = 2
+	})
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pod api-deployment-2-def container api restarted 2 times, more than the limit 1")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckReturnsFailedWhenOOMKilled(t *testing.T) {
+	p, cs := setupPlugin(t, `{}`)
+
+	updatePod(t, cs, "api-deployment-2-abc", func(p *corev1.Pod) {
+		p.Status.ContainerStatuses[0].LastTerminationState = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}}
+	})
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pod api-deployment-2-abc container api was OOMKilled")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckIgnoresOOMKilledWhenConfigured(t *testing.T) {
+	p, cs := setupPlugin(t, `{"ignore_oom_killed": true}`)
+
+	updatePod(t, cs, "api-deployment-2-abc", func(p *corev1.Pod) {
+		p.Status.ContainerStatuses[0].LastTerminationState = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}}
+	})
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestCheckReturnsFailedWhenCrashLoopBackOff(t *testing.T) {
+	p, cs := setupPlugin(t, `{"max_restarts": 20}`)
+
+	updatePod(t, cs, "api-deployment-2-abc", func(p *corev1.Pod) { crashLoop(p) })
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pod api-deployment-2-abc container api is in CrashLoopBackOff")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckReturnsFailedWhenReadinessFlaps(t *testing.T) {
+	p, cs := setupPlugin(t, `{"max_readiness_changes": 2}`)
+
+	ready := corev1.ConditionFalse
+
+	for i := 0; i < 3; i++ {
+		result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+		require.NoError(t, err)
+		require.Equal(t, interfaces.CheckSuccess, result)
+
+		updatePod(t, cs, "api-deployment-2-abc", func(p *corev1.Pod) {
+			p.Status.Conditions[0].Status = ready
+			p.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(time.Duration(i) * time.Second))
+		})
+
+		if ready == corev1.ConditionFalse {
+			ready = corev1.ConditionTrue
+		} else {
+			ready = corev1.ConditionFalse
+		}
+	}
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pod api-deployment-2-abc readiness changed 3 times, more than the limit 2")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestCheckCountsReadinessChangesBetweenChecks(t *testing.T) {
+	p, cs := setupPlugin(t, `{"max_readiness_changes": 1}`)
+
+	_, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+
+	// the pod became not ready and then ready again between checks
+	updatePod(t, cs, "api-deployment-2-abc", func(p *corev1.Pod) {
+		p.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now())
+	})
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "readiness changed 2 times")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
@@ -0,0 +1,83 @@
+package presets
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"sigs.k8s.io/yaml"
+)
+
+// Library is the location of the preset queries that operators define for a monitor, presets are
+// keyed by runtime and preset name
+type Library struct {
+	// File is the path of a YAML or JSON document containing the presets, not read when empty
+	File string
+
+	// ConsulKVPath is the Consul KV key containing the presets in the same format as the file,
+	// not read when empty
+	ConsulKVPath string
+}
+
+// Load returns the built in presets for the runtime merged with the presets from the library file and
+// Consul KV path, presets in the Consul KV key replace presets with the same name in the file, and both
+// replace the built in presets
+func Load(builtIn map[string]map[string]string, runtime string, lib Library, cc clients.Consul, l hclog.Logger) (map[string]string, error) {
+	p := map[string]string{}
+	for k, v := range builtIn[runtime] {
+		p[k] = v
+	}
+
+	if lib.File != "" {
+		d, err := ioutil.ReadFile(lib.File)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read presets file %s: %s", lib.File, err)
+		}
+
+		err = merge(p, d, runtime, l)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse presets file %s: %s", lib.File, err)
+		}
+	}
+
+	if lib.ConsulKVPath != "" {
+		d, err := cc.GetKV(lib.ConsulKVPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read presets from Consul KV: %s", err)
+		}
+
+		if d == nil {
+			l.Warn("no presets found in Consul KV", "path", lib.ConsulKVPath)
+			return p, nil
+		}
+
+		err = merge(p, d, runtime, l)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse presets from Consul KV path %s: %s", lib.ConsulKVPath, err)
+		}
+	}
+
+	return p, nil
+}
+
+// merge adds the presets for the runtime from the YAML or JSON document to p, the document is a
+// map of runtime to a map of preset name and query
+func merge(p map[string]string, data []byte, runtime string, l hclog.Logger) error {
+	library := map[string]map[string]string{}
+
+	err := yaml.Unmarshal(data, &library)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range library[runtime] {
+		if _, ok := p[k]; ok {
+			l.Debug("replacing preset", "name", k, "runtime", runtime)
+		}
+
+		p[k] = v
+	}
+
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strings"
 	"text/template"
 	"time"
 
@@ -17,21 +20,72 @@ import (
 )
 
 type Plugin struct {
-	log       hclog.Logger
-	config    *PluginConfig
-	store     interfaces.PluginStateStore
-	client    clients.Prometheus
-	runtime   string
-	name      string
-	namespace string
+	log          hclog.Logger
+	config       *PluginConfig
+	store        interfaces.PluginStateStore
+	client       clients.Prometheus
+	consulClient clients.Consul
+	runtime      string
+	name         string
+	namespace    string
+
+	// presets are the preset queries for the runtime, keyed by name
+	presets map[string]string
 }
 
 type PluginConfig struct {
 	// Address of the prometheus server
-	Address string  `json:"address"`
+	Address string `json:"address"`
+
+	// Auth is the optional bearer token or basic auth credentials for the prometheus server
+	Auth *Auth `json:"auth,omitempty"`
+
+	// Headers are added to every request to the prometheus server, e.g. X-Scope-OrgID
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// TLS is the optional CA bundle and client certificate for the prometheus server
+	TLS *TLS `json:"tls,omitempty"`
+
 	Queries []Query `json:"queries"`
 }
 
+// Auth config, secrets are read from a file or an environment variable and can not be
+// specified inline
+type Auth struct {
+	// BearerTokenFile is the path of a file containing the bearer token
+	BearerTokenFile string `json:"bearer_token_file,omitempty"`
+
+	// BearerTokenEnv is the name of an environment variable containing the bearer token
+	BearerTokenEnv string `json:"bearer_token_env,omitempty"`
+
+	// Username for basic auth
+	Username string `json:"username,omitempty"`
+
+	// PasswordFile is the path of a file containing the basic auth password
+	PasswordFile string `json:"password_file,omitempty"`
+
+	// PasswordEnv is the name of an environment variable containing the basic auth password
+	PasswordEnv string `json:"password_env,omitempty"`
+}
+
+// TLS config
+type TLS struct {
+	// CAFile is the path of the PEM encoded CA bundle used to verify the server certificate
+	CAFile string `json:"ca_file,omitempty"`
+
+	// CertFile is the path of the PEM encoded client certificate
+	CertFile string `json:"cert_file,omitempty"`
+
+	// KeyFile is the path of the PEM encoded client key
+	KeyFile string `json:"key_file,omitempty"`
+
+	// ServerName overrides the name used to verify the server certificate
+	ServerName string `json:"server_name,omitempty"`
+
+	// InsecureSkipVerify disables verification of the server certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
 // Query config
 type Query struct {
 	// Name of the query
@@ -40,24 +94,167 @@ type Query struct {
 	// Preset is an optional default metric query
 	Preset string `json:"preset"`
 
+	// Quantile used by the request duration presets and available to custom queries as
+	// {{ .Quantile }}, defaults to 0.99
+	Quantile *float64 `json:"quantile,omitempty"`
+
 	// Query is an optional query when the preset is not specified
 	Query string `json:"query"`
 
 	// Minimum value for success, optional when Max specified
-	Min *int `json:"min,omitempty"` // default 0
+	Min *float64 `json:"min,omitempty"` // default 0
 
 	// Maximum value for success, optional when Min specified
-	Max *int `json:"max,omitempty"` // default 0
+	Max *float64 `json:"max,omitempty"` // default 0
+
+	// Evaluation defines how the series returned by the query are checked, either first, worst, mean,
+	// or all, defaults to first
+	Evaluation string `json:"evaluation,omitempty"`
+
+	// Compare evaluates the query for the primary and the candidate, the check fails when the
+	// candidate is worse than the primary by more than the allowed margin
+	Compare *Comparison `json:"compare,omitempty"`
+}
+
+const (
+	// DegradationIncrease is used for queries where a higher value is worse e.g. request duration
+	DegradationIncrease = "increase"
+	// DegradationDecrease is used for queries where a lower value is worse e.g. request success
+	DegradationDecrease = "decrease"
+)
+
+const (
+	// EvaluationFirst checks the value of the first series returned by the query
+	EvaluationFirst = "first"
+	// EvaluationWorst checks every series that has a value and reports the series furthest outside the
+	// min and max values
+	EvaluationWorst = "worst"
+	// EvaluationMean checks the mean value of all the series returned by the query
+	EvaluationMean = "mean"
+	// EvaluationAll requires every series to have a value within the min and max values, series without
+	// a value (NaN) fail the check, all the failed series are reported
+	EvaluationAll = "all"
+)
+
+// Comparison defines the margin that the candidate can be worse than the primary
+type Comparison struct {
+	// Degradation is the direction of change that is worse, either increase or decrease, defaults
+	// to decrease for the envoy-request-success preset and increase for all other queries
+	Degradation string `json:"degradation,omitempty"`
+
+	// MaxAbsolute is the maximum difference between the candidate and the primary values
+	MaxAbsolute *float64 `json:"max_absolute,omitempty"`
+
+	// MaxRelative is the maximum difference between the candidate and the primary values as a
+	// percentage of the primary value
+	MaxRelative *float64 `json:"max_relative,omitempty"`
+}
+
+// getDegradation returns the direction of change that is worse for the query
+func (q *Query) getDegradation() string {
+	if q.Compare != nil && q.Compare.Degradation != "" {
+		return q.Compare.Degradation
+	}
+
+	if q.Preset == "envoy-request-success" || q.Preset == "envoy-grpc-request-success" {
+		return DegradationDecrease
+	}
+
+	return DegradationIncrease
+}
+
+// getQuantile returns the quantile for the query
+func (q *Query) getQuantile() float64 {
+	if q.Quantile == nil {
+		return 0.99
+	}
+
+	return *q.Quantile
+}
+
+// getEvaluation returns the evaluation mode for the query
+func (q *Query) getEvaluation() string {
+	if q.Evaluation == "" {
+		return EvaluationFirst
+	}
+
+	return q.Evaluation
+}
+
+// samples returns the samples that are checked for the evaluation mode, series without a value for the
+// interval (NaN) are only returned for the all evaluation which fails the check
+func (q *Query) samples(v model.Vector) model.Vector {
+	switch q.getEvaluation() {
+	case EvaluationAll:
+		return v
+	case EvaluationFirst:
+		v = v[:1]
+	}
+
+	samples := model.Vector{}
+	for _, s := range v {
+		if !math.IsNaN(float64(s.Value)) {
+			samples = append(samples, s)
+		}
+	}
+
+	return samples
+}
+
+// value returns the single value for the series using the evaluation mode, the worst and all modes
+// return the worst value in the direction of degradation, series without a value are ignored
+func (q *Query) value(v model.Vector) float64 {
+	switch q.getEvaluation() {
+	case EvaluationMean:
+		total := 0.0
+		for _, s := range v {
+			total += float64(s.Value)
+		}
+
+		return total / float64(len(v))
+	case EvaluationWorst, EvaluationAll:
+		worst := math.NaN()
+		for _, s := range v {
+			sv := float64(s.Value)
+			if math.IsNaN(sv) {
+				continue
+			}
+
+			if math.IsNaN(worst) || (q.getDegradation() == DegradationDecrease && sv < worst) || (q.getDegradation() == DegradationIncrease && sv > worst) {
+				worst = sv
+			}
+		}
+
+		return worst
+	}
+
+	return float64(v[0].Value)
+}
+
+// outside returns how far the value is outside the min and max values, zero when the value is within range
+func (q *Query) outside(value float64) float64 {
+	if q.Min != nil && value < *q.Min {
+		return *q.Min - value
+	}
+
+	if q.Max != nil && value > *q.Max {
+		return value - *q.Max
+	}
+
+	return 0
 }
 
 func New(name, namespace, runtime string, l hclog.Logger) (*Plugin, error) {
-	c, _ := clients.NewPrometheus()
+	c, _ := clients.NewPrometheus(nil)
+	cc, _ := clients.NewConsul(nil)
+
 	return &Plugin{
-		log:       l,
-		client:    c,
-		runtime:   runtime,
-		name:      name,
-		namespace: namespace,
+		log:          l,
+		client:       c,
+		consulClient: cc,
+		runtime:      runtime,
+		name:         name,
+		namespace:    namespace,
 	}, nil
 }
 
@@ -71,6 +268,103 @@ func (s *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfa
 		return fmt.Errorf("unable to decode Monitoring config: %s", err)
 	}
 
+	err = s.configureClient()
+	if err != nil {
+		return err
+	}
+
+	s.presets, err = s.loadPresets()
+	if err != nil {
+		return err
+	}
+
+	for _, q := range s.config.Queries {
+		switch q.getEvaluation() {
+		case EvaluationFirst, EvaluationWorst, EvaluationMean, EvaluationAll:
+		default:
+			return fmt.Errorf("query %s has an invalid evaluation %s, please specify one of %s, %s, %s, %s", q.Name, q.Evaluation, EvaluationFirst, EvaluationWorst, EvaluationMean, EvaluationAll)
+		}
+
+		if q.getQuantile() <= 0 || q.getQuantile() > 1 {
+			return fmt.Errorf("query %s has an invalid quantile %g, please specify a value greater than 0 and less than or equal to 1", q.Name, q.getQuantile())
+		}
+
+		if q.Compare == nil {
+			continue
+		}
+
+		if q.Compare.MaxAbsolute == nil && q.Compare.MaxRelative == nil {
+			return fmt.Errorf("query %s compares the candidate with the primary, please specify max_absolute or max_relative", q.Name)
+		}
+
+		d := q.getDegradation()
+		if d != DegradationIncrease && d != DegradationDecrease {
+			return fmt.Errorf("query %s has an invalid degradation %s, please specify one of %s, %s", q.Name, d, DegradationIncrease, DegradationDecrease)
+		}
+	}
+
+	return nil
+}
+
+// configureClient validates the auth and TLS config and creates a client that uses it, the default
+// client is used when neither auth, headers, or TLS are configured
+func (s *Plugin) configureClient() error {
+	if s.config.Auth == nil && s.config.Headers == nil && s.config.TLS == nil {
+		return nil
+	}
+
+	opts := &clients.PrometheusOptions{Headers: s.config.Headers}
+
+	if a := s.config.Auth; a != nil {
+		if a.BearerTokenFile != "" && a.BearerTokenEnv != "" {
+			return fmt.Errorf("auth has both bearer_token_file and bearer_token_env, please specify only one")
+		}
+
+		if a.PasswordFile != "" && a.PasswordEnv != "" {
+			return fmt.Errorf("auth has both password_file and password_env, please specify only one")
+		}
+
+		hasToken := a.BearerTokenFile != "" || a.BearerTokenEnv != ""
+		hasPassword := a.PasswordFile != "" || a.PasswordEnv != ""
+
+		if hasToken && a.Username != "" {
+			return fmt.Errorf("auth has both a bearer token and basic auth, please specify only one")
+		}
+
+		if a.Username != "" && !hasPassword {
+			return fmt.Errorf("auth has a username but no password, please specify password_file or password_env")
+		}
+
+		if a.Username == "" && hasPassword {
+			return fmt.Errorf("auth has a password but no username, please specify username")
+		}
+
+		opts.BearerTokenFile = a.BearerTokenFile
+		opts.BearerTokenEnv = a.BearerTokenEnv
+		opts.Username = a.Username
+		opts.PasswordFile = a.PasswordFile
+		opts.PasswordEnv = a.PasswordEnv
+	}
+
+	if t := s.config.TLS; t != nil {
+		if (t.CertFile == "") != (t.KeyFile == "") {
+			return fmt.Errorf("tls client certificates require both cert_file and key_file")
+		}
+
+		opts.CAFile = t.CAFile
+		opts.CertFile = t.CertFile
+		opts.KeyFile = t.KeyFile
+		opts.ServerName = t.ServerName
+		opts.InsecureSkipVerify = t.InsecureSkipVerify
+	}
+
+	c, err := clients.NewPrometheus(opts)
+	if err != nil {
+		return fmt.Errorf("unable to create Prometheus client: %s", err)
+	}
+
+	s.client = c
+
 	return nil
 }
 
@@ -83,19 +377,12 @@ func (s *Plugin) Check(ctx context.Context, candidateName string, interval time.
 	for _, q := range s.config.Queries {
 		if q.Preset != "" {
 			// use a preset if present
-			preset := fmt.Sprintf("%s-%s", s.runtime, q.Preset)
-			switch preset {
-			case "kubernetes-envoy-request-success":
-				querySQL = append(querySQL, KubernetesEnvoyRequestSuccess)
-			case "kubernetes-envoy-request-duration":
-				querySQL = append(querySQL, KubernetesEnvoyRequestDuration)
-			case "nomad-envoy-request-success":
-				querySQL = append(querySQL, NomadEnvoyRequestSuccess)
-			case "nomad-envoy-request-duration":
-				querySQL = append(querySQL, NomadEnvoyRequestDuration)
-			default:
-				return interfaces.CheckError, fmt.Errorf("preset query %s, does not exist", preset)
+			preset, ok := s.presets[q.Preset]
+			if !ok {
+				return interfaces.CheckError, fmt.Errorf("preset query %s-%s, does not exist", s.runtime, q.Preset)
 			}
+
+			querySQL = append(querySQL, preset)
 		} else {
 			// use the custom query
 			querySQL = append(querySQL, q.Query)
@@ -111,66 +398,180 @@ func (s *Plugin) Check(ctx context.Context, candidateName string, interval time.
 			return interfaces.CheckError, fmt.Errorf("query %s is empty, please specify a valid Prometheus query", query.Name)
 		}
 
-		// add the interpolation for the queries
-		tmpl, err := template.New("query").Parse(q)
+		v, result, err := s.query(ctx, query, q, candidateName, false, interval)
 		if err != nil {
-			return interfaces.CheckError, fmt.Errorf("unable to process query template: %s", err)
+			return result, err
 		}
 
-		context := struct {
-			ReleaseName   string
-			CandidateName string
-			Namespace     string
-			Interval      string
-		}{
-			s.name,
-			candidateName,
-			s.namespace,
-			interval.String(),
+		result, err = s.checkThresholds(query, v)
+		if err != nil {
+			return result, err
 		}
 
-		out := bytes.NewBufferString("")
-		err = tmpl.Execute(out, context)
-		if err != nil {
-			return interfaces.CheckError, fmt.Errorf("unable to process query template: %s", err)
+		if query.Compare != nil {
+			result, err := s.compare(ctx, query, q, query.value(v), interval)
+			if err != nil {
+				return result, err
+			}
 		}
+	}
 
-		s.log.Debug("querying prometheus", "address", s.config.Address, "name", query.Name, "query", out)
+	return interfaces.CheckSuccess, nil
+}
 
-		val, warn, err := s.client.Query(ctx, s.config.Address, out.String(), time.Now())
-		if err != nil {
-			s.log.Error("unable to query prometheus", "error", err)
+// checkThresholds returns CheckFailed when the series returned by the query are not within the min
+// and max values, the series that are checked and reported depend on the evaluation mode
+func (s *Plugin) checkThresholds(query Query, v model.Vector) (interfaces.CheckResult, error) {
+	var failed model.Vector
 
-			return interfaces.CheckError, fmt.Errorf("unable to query prometheus: %s", err)
+	switch query.getEvaluation() {
+	case EvaluationFirst:
+		if query.outside(float64(v[0].Value)) > 0 {
+			failed = v[:1]
+		}
+	case EvaluationMean:
+		mean := query.value(v)
+		if query.outside(mean) > 0 {
+			return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, got mean value %g for %d series", query.Name, query.Preset, mean, len(v))
+		}
+	default:
+		for _, sample := range v {
+			value := float64(sample.Value)
+
+			// a series has no value when there is no data for the interval, e.g. a request success
+			// ratio for a pod that has not received requests
+			if query.outside(value) > 0 || (query.getEvaluation() == EvaluationAll && math.IsNaN(value)) {
+				failed = append(failed, sample)
+			}
 		}
 
-		s.log.Debug("query value returned", "name", query.Name, "preset", query.Preset, "value", val, "value_type", reflect.TypeOf(val), "warnings", warn)
+		// report only the series furthest outside the min and max values
+		if query.getEvaluation() == EvaluationWorst && len(failed) > 1 {
+			sort.SliceStable(failed, func(i, j int) bool {
+				return query.outside(float64(failed[i].Value)) > query.outside(float64(failed[j].Value))
+			})
 
-		if v, ok := val.(model.Vector); ok {
-			checkFail := false
+			failed = failed[:1]
+		}
+	}
 
-			if len(v) == 0 {
-				return interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, null value returned by query: %v", query.Name, query.Preset, val)
-			}
+	if len(failed) == 0 {
+		return interfaces.CheckSuccess, nil
+	}
+
+	series := []string{}
+	for _, sample := range failed {
+		s.log.Debug("query value outside min and max", "name", query.Name, "preset", query.Preset, "series", sample.Metric, "value", sample.Value)
+		series = append(series, fmt.Sprintf("%s=%g", sample.Metric, float64(sample.Value)))
+	}
 
-			value := int(v[0].Value)
+	if query.getEvaluation() == EvaluationFirst {
+		return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, got value %g", query.Name, query.Preset, float64(failed[0].Value))
+	}
 
-			if query.Min != nil && value < *query.Min {
-				s.log.Debug("query value less than min", "name", query.Name, "preset", query.Preset, "value", value)
-				checkFail = true
-			}
+	return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, got values outside min and max for series %s", query.Name, query.Preset, strings.Join(series, ", "))
+}
 
-			if query.Max != nil && int(v[0].Value) > *query.Max {
-				s.log.Debug("query value greater than max", "name", query.Name, "preset", query.Preset, "value", value)
-				checkFail = true
-			}
+// query executes the templated query for the candidate, or for the primary when primary is true,
+// and returns the samples
+func (s *Plugin) query(ctx context.Context, query Query, q, candidateName string, primary bool, interval time.Duration) (model.Vector, interfaces.CheckResult, error) {
+	// add the interpolation for the queries
+	tmpl, err := template.New("query").Parse(q)
+	if err != nil {
+		return nil, interfaces.CheckError, fmt.Errorf("unable to process query template: %s", err)
+	}
 
-			if checkFail {
-				return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, got value %d", query.Name, query.Preset, value)
-			}
-		} else {
-			s.log.Error("invalid value returned from query", "name", query.Name, "preset", query.Preset, "value", val)
-			return interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, got value %v", query.Name, query.Preset, val)
+	context := struct {
+		ReleaseName   string
+		CandidateName string
+		Namespace     string
+		Interval      string
+		Primary       bool
+		Quantile      float64
+	}{
+		s.name,
+		candidateName,
+		s.namespace,
+		interval.String(),
+		primary,
+		query.getQuantile(),
+	}
+
+	// the primary is selected using the same query with the name of the primary deployment
+	if primary {
+		context.CandidateName = fmt.Sprintf("%s-primary", s.name)
+	}
+
+	out := bytes.NewBufferString("")
+	err = tmpl.Execute(out, context)
+	if err != nil {
+		return nil, interfaces.CheckError, fmt.Errorf("unable to process query template: %s", err)
+	}
+
+	s.log.Debug("querying prometheus", "address", s.config.Address, "name", query.Name, "primary", primary, "query", out)
+
+	val, warn, err := s.client.Query(ctx, s.config.Address, out.String(), time.Now())
+	if err != nil {
+		s.log.Error("unable to query prometheus", "error", err)
+
+		return nil, interfaces.CheckError, fmt.Errorf("unable to query prometheus: %s", err)
+	}
+
+	s.log.Debug("query value returned", "name", query.Name, "preset", query.Preset, "primary", primary, "value", val, "value_type", reflect.TypeOf(val), "warnings", warn)
+
+	v, ok := val.(model.Vector)
+	if !ok {
+		s.log.Error("invalid value returned from query", "name", query.Name, "preset", query.Preset, "value", val)
+		return nil, interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, got value %v", query.Name, query.Preset, val)
+	}
+
+	if len(v) == 0 {
+		return nil, interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, null value returned by query: %v", query.Name, query.Preset, val)
+	}
+
+	// a series has no value (NaN) when there is no data for the interval, e.g. a request success ratio
+	// when the candidate has not received requests
+	v = query.samples(v)
+	if len(v) == 0 {
+		return nil, interfaces.CheckNoMetrics, fmt.Errorf("check failed for query %s using preset %s, no value for the interval returned by query: %v", query.Name, query.Preset, val)
+	}
+
+	return v, interfaces.CheckSuccess, nil
+}
+
+// compare evaluates the query for the primary and returns CheckFailed when the candidate value is
+// worse than the primary value by more than the allowed margin
+func (s *Plugin) compare(ctx context.Context, query Query, q string, candidate float64, interval time.Duration) (interfaces.CheckResult, error) {
+	v, result, err := s.query(ctx, query, q, "", true, interval)
+	if err != nil {
+		return result, fmt.Errorf("unable to compare candidate with primary: %s", err)
+	}
+
+	primary := query.value(v)
+	if math.IsNaN(primary) {
+		return interfaces.CheckNoMetrics, fmt.Errorf("unable to compare candidate with primary, no value for the interval returned for the primary")
+	}
+
+	degradation := candidate - primary
+	if query.getDegradation() == DegradationDecrease {
+		degradation = primary - candidate
+	}
+
+	s.log.Debug("compared candidate with primary", "name", query.Name, "preset", query.Preset, "candidate", candidate, "primary", primary, "degradation", degradation)
+
+	if query.Compare.MaxAbsolute != nil && degradation > *query.Compare.MaxAbsolute {
+		return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, candidate value %g is worse than primary value %g by %g, more than the allowed %g", query.Name, query.Preset, candidate, primary, degradation, *query.Compare.MaxAbsolute)
+	}
+
+	if query.Compare.MaxRelative != nil && degradation > 0 {
+		// any degradation is more than the allowed percentage when the primary value is zero
+		relative := math.Inf(1)
+		if primary != 0 {
+			relative = degradation / math.Abs(primary) * 100
+		}
+
+		if relative > *query.Compare.MaxRelative {
+			return interfaces.CheckFailed, fmt.Errorf("check failed for query %s using preset %s, candidate value %g is worse than primary value %g by %.2f%%, more than the allowed %g%%", query.Name, query.Preset, candidate, primary, relative, *query.Compare.MaxRelative)
 		}
 	}
 
@@ -2,6 +2,14 @@ package prometheus
 
 import (
 	"context"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -171,6 +179,367 @@ func TestPluginExecutesCustomQueriesAndChecksValue(t *testing.T) {
 	require.Contains(t, call2Args, `[30s]`)
 }
 
+func setupCompareQueries(t *testing.T, config string, candidate, primary float64) (*Plugin, *clients.PrometheusMock) {
+	p, pm := setupPlugin(t, config)
+
+	isPrimary := func(q string) bool {
+		return strings.Contains(q, "api-deployment-primary") && !strings.Contains(q, "pod!~")
+	}
+
+	testutils.ClearMockCall(&pm.Mock, "Query")
+	pm.On("Query", mock.Anything, mock.MatchedBy(isPrimary), mock.Anything).Return(
+		model.Vector{&model.Sample{Value: model.SampleValue(primary)}},
+		v1.Warnings{},
+		nil,
+	)
+
+	pm.On("Query", mock.Anything, mock.MatchedBy(func(q string) bool { return !isPrimary(q) }), mock.Anything).Return(
+		model.Vector{&model.Sample{Value: model.SampleValue(candidate)}},
+		v1.Warnings{},
+		nil,
+	)
+
+	return p, pm
+}
+
+func TestConfigureReturnsErrorWhenCompareHasNoMargin(t *testing.T) {
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+	err := p.Configure([]byte(`{"queries": [{"name": "success", "preset": "envoy-request-success", "compare": {}}]}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.Error(t, err)
+}
+
+func TestPluginComparesCandidateWithPrimary(t *testing.T) {
+	p, pm := setupCompareQueries(t, compareAbsoluteQuery, 98, 98.5)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+
+	pm.AssertNumberOfCalls(t, "Query", 2)
+
+	// check that the primary query selects the primary pods
+	primaryQuery := pm.Calls[1].Arguments[1]
+	require.Contains(t, primaryQuery, `pod=~"api-deployment-primary.*"`)
+	require.NotContains(t, primaryQuery, `pod!~`)
+}
+
+func TestPluginReturnsFailedWhenCandidateWorseThanPrimaryByAbsoluteMargin(t *testing.T) {
+	p, _ := setupCompareQueries(t, compareAbsoluteQuery, 97, 99.5)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "candidate value 97")
+	require.Contains(t, err.Error(), "primary value 99.5")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestPluginReturnsFailedWhenCandidateWorseThanPrimaryByRelativeMargin(t *testing.T) {
+	p, _ := setupCompareQueries(t, compareRelativeQuery, 120, 100)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "20.00%")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestPluginIgnoresImprovementWhenComparing(t *testing.T) {
+	p, _ := setupCompareQueries(t, compareRelativeQuery, 50, 100)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func setupSeriesQuery(t *testing.T, config string, values ...float64) (*Plugin, *clients.PrometheusMock) {
+	p, pm := setupPlugin(t, config)
+
+	v := model.Vector{}
+	for i, value := range values {
+		v = append(v, &model.Sample{
+			Metric: model.Metric{"pod": model.LabelValue(fmt.Sprintf("api-deployment-%d", i))},
+			Value:  model.SampleValue(value),
+		})
+	}
+
+	testutils.ClearMockCall(&pm.Mock, "Query")
+	pm.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(v, v1.Warnings{}, nil)
+
+	return p, pm
+}
+
+func TestConfigureReturnsErrorWhenEvaluationInvalid(t *testing.T) {
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+	err := p.Configure([]byte(`{"queries": [{"name": "success", "preset": "envoy-request-success", "evaluation": "median"}]}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid evaluation median")
+}
+
+func TestPluginChecksFractionalThresholds(t *testing.T) {
+	p, _ := setupSeriesQuery(t, seriesQuery, 99.4)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got value 99.4")
+	require.Equal(t, interfaces.CheckFailed, result)
+
+	p, _ = setupSeriesQuery(t, seriesQuery, 99.6)
+
+	result, err = p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestPluginChecksOnlyFirstSeriesByDefault(t *testing.T) {
+	p, _ := setupSeriesQuery(t, seriesQuery, 100, 50)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestPluginEvaluationAllReportsEverySeriesOutsideThresholds(t *testing.T) {
+	p, _ := setupSeriesQuery(t, strings.Replace(seriesQuery, "first", "all", 1), 100, 50, 90)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `{pod="api-deployment-1"}=50`)
+	require.Contains(t, err.Error(), `{pod="api-deployment-2"}=90`)
+	require.NotContains(t, err.Error(), `api-deployment-0`)
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestPluginEvaluationAllFailsWhenSeriesHasNoValue(t *testing.T) {
+	p, _ := setupSeriesQuery(t, strings.Replace(seriesQuery, "first", "all", 1), 100, math.NaN())
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `{pod="api-deployment-1"}=NaN`)
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestPluginEvaluationFirstReturnsNoMetricsWhenSeriesHasNoValue(t *testing.T) {
+	p, _ := setupSeriesQuery(t, seriesQuery, math.NaN(), 100)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no value for the interval")
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestPluginEvaluationMeanIgnoresSeriesWithNoValue(t *testing.T) {
+	p, _ := setupSeriesQuery(t, strings.Replace(seriesQuery, "first", "mean", 1), 100, math.NaN(), 98)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got mean value 99 for 2 series")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestPluginEvaluationWorstReturnsNoMetricsWhenNoSeriesHasValue(t *testing.T) {
+	p, _ := setupSeriesQuery(t, strings.Replace(seriesQuery, "first", "worst", 1), math.NaN(), math.NaN())
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestQueryWorstValueIgnoresSeriesWithNoValue(t *testing.T) {
+	q := Query{Preset: "envoy-request-success", Evaluation: EvaluationWorst}
+
+	v := model.Vector{
+		&model.Sample{Value: model.SampleValue(math.NaN())},
+		&model.Sample{Value: 99},
+		&model.Sample{Value: 98},
+	}
+
+	require.Equal(t, 98.0, q.value(v))
+}
+
+func TestPluginReturnsNoMetricsWhenPrimaryHasNoValue(t *testing.T) {
+	p, _ := setupCompareQueries(t, compareAbsoluteQuery, 98, math.NaN())
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to compare candidate with primary")
+	require.Equal(t, interfaces.CheckNoMetrics, result)
+}
+
+func TestPluginEvaluationWorstIgnoresSeriesWithNoValue(t *testing.T) {
+	p, _ := setupSeriesQuery(t, strings.Replace(seriesQuery, "first", "worst", 1), 100, math.NaN())
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestPluginEvaluationWorstReportsWorstSeries(t *testing.T) {
+	p, _ := setupSeriesQuery(t, strings.Replace(seriesQuery, "first", "worst", 1), 100, 90, 50)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `{pod="api-deployment-2"}=50`)
+	require.NotContains(t, err.Error(), `api-deployment-1`)
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestPluginEvaluationMeanChecksMeanOfSeries(t *testing.T) {
+	p, _ := setupSeriesQuery(t, strings.Replace(seriesQuery, "first", "mean", 1), 100, 98)
+
+	result, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "got mean value 99 for 2 series")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func setupPrometheusServer(t *testing.T, tlsServer bool) (*httptest.Server, *http.Request) {
+	received := &http.Request{}
+
+	h := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		*received = *r.Clone(context.Background())
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1652000000,"100"]}]}}`))
+	})
+
+	ts := httptest.NewUnstartedServer(h)
+	if tlsServer {
+		ts.StartTLS()
+	} else {
+		ts.Start()
+	}
+
+	t.Cleanup(ts.Close)
+
+	return ts, received
+}
+
+func checkWithConfig(t *testing.T, config string) (interfaces.CheckResult, error) {
+	l := hclog.NewNullLogger()
+	p, _ := New("api-deployment", "default", "kubernetes", l)
+
+	err := p.Configure([]byte(config), l, &mocks.StoreMock{})
+	require.NoError(t, err)
+
+	return p.Check(context.Background(), "api-deployment", 30*time.Second)
+}
+
+func TestConfigureReturnsErrorWhenAuthInvalid(t *testing.T) {
+	tests := map[string]string{
+		"token file and env":     `{"auth": {"bearer_token_file": "/token", "bearer_token_env": "TOKEN"}}`,
+		"token and basic auth":   `{"auth": {"bearer_token_env": "TOKEN", "username": "admin", "password_env": "PASSWORD"}}`,
+		"username no password":   `{"auth": {"username": "admin"}}`,
+		"password no username":   `{"auth": {"password_env": "PASSWORD"}}`,
+		"cert no key":            `{"tls": {"cert_file": "/cert.pem"}}`,
+		"ca file does not exist": `{"tls": {"ca_file": "/does/not/exist.pem"}}`,
+		"key does not exist":     `{"tls": {"cert_file": "/does/not/exist.pem", "key_file": "/does/not/exist.key"}}`,
+	}
+
+	for name, config := range tests {
+		p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+		err := p.Configure([]byte(config), hclog.NewNullLogger(), &mocks.StoreMock{})
+		require.Error(t, err, name)
+	}
+}
+
+func TestPluginSendsBearerTokenAndHeaders(t *testing.T) {
+	ts, received := setupPrometheusServer(t, false)
+	t.Setenv("PROMETHEUS_TOKEN", "abc123")
+
+	result, err := checkWithConfig(t, fmt.Sprintf(authQuery, ts.URL, `"auth": {"bearer_token_env": "PROMETHEUS_TOKEN"}, "headers": {"X-Scope-OrgID": "tenant-1"},`))
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+
+	require.Equal(t, "Bearer abc123", received.Header.Get("Authorization"))
+	require.Equal(t, "tenant-1", received.Header.Get("X-Scope-OrgID"))
+}
+
+func TestPluginSendsBasicAuthWithPasswordFromFile(t *testing.T) {
+	ts, received := setupPrometheusServer(t, false)
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(passwordFile, []byte("secret\n"), 0600)
+
+	result, err := checkWithConfig(t, fmt.Sprintf(authQuery, ts.URL, fmt.Sprintf(`"auth": {"username": "admin", "password_file": %q},`, passwordFile)))
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+
+	user, pass, ok := received.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "admin", user)
+	require.Equal(t, "secret", pass)
+}
+
+func TestPluginVerifiesServerWithCAFile(t *testing.T) {
+	ts, _ := setupPrometheusServer(t, true)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600)
+
+	result, err := checkWithConfig(t, fmt.Sprintf(authQuery, ts.URL, fmt.Sprintf(`"tls": {"ca_file": %q},`, caFile)))
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+const authQuery = `
+{
+	"address": %q,
+	%s
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "query": "sum(envoy_cluster_upstream_rq)",
+	    "min": 99
+	  }
+	]
+}
+`
+
+const seriesQuery = `
+{
+	"address": "http://prometheus-kube-prometheus-prometheus.monitoring.svc:9090",
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "preset": "envoy-request-success",
+	    "evaluation": "first",
+	    "min": 99.5
+	  }
+	]
+}
+`
+
+const compareAbsoluteQuery = `
+{
+	"address": "http://prometheus-kube-prometheus-prometheus.monitoring.svc:9090",
+	"queries": [
+	  {
+	    "name": "request-success",
+	    "preset": "envoy-request-success",
+	    "compare": {
+	      "max_absolute": 1
+	    }
+	  }
+	]
+}
+`
+
+const compareRelativeQuery = `
+{
+	"address": "http://prometheus-kube-prometheus-prometheus.monitoring.svc:9090",
+	"queries": [
+	  {
+	    "name": "request-duration",
+	    "preset": "envoy-request-duration",
+	    "compare": {
+	      "max_relative": 10
+	    }
+	  }
+	]
+}
+`
+
 const twoDefaultQueries = `
 {
 	"address": "http://prometheus-kube-prometheus-prometheus.monitoring.svc:9090",
@@ -0,0 +1,34 @@
+package prometheus
+
+import (
+	"github.com/nicholasjackson/consul-release-controller/pkg/config"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/presets"
+)
+
+// builtInPresets are the built in preset queries keyed by runtime and preset name
+var builtInPresets = map[string]map[string]string{
+	"kubernetes": {
+		"envoy-request-success":         KubernetesEnvoyRequestSuccess,
+		"envoy-request-duration":        KubernetesEnvoyRequestDuration,
+		"envoy-grpc-request-success":    KubernetesEnvoyGRPCRequestSuccess,
+		"envoy-tcp-connection-failures": KubernetesEnvoyTCPConnectionFailures,
+	},
+	"nomad": {
+		"envoy-request-success":         NomadEnvoyRequestSuccess,
+		"envoy-request-duration":        NomadEnvoyRequestDuration,
+		"envoy-grpc-request-success":    NomadEnvoyGRPCRequestSuccess,
+		"envoy-tcp-connection-failures": NomadEnvoyTCPConnectionFailures,
+	},
+}
+
+// loadPresets returns the built in presets for the runtime merged with the presets from the controller's
+// presets file and Consul KV path, presets defined by the controller replace built in presets with the
+// same name
+func (s *Plugin) loadPresets() (map[string]string, error) {
+	lib := presets.Library{
+		File:         config.PrometheusPresetsFile(),
+		ConsulKVPath: config.PrometheusPresetsConsulKVPath(),
+	}
+
+	return presets.Load(builtInPresets, s.runtime, lib, s.consulClient, s.log)
+}
@@ -0,0 +1,110 @@
+package prometheus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPresetsFile(t *testing.T, data string) {
+	f := filepath.Join(t.TempDir(), "presets.yaml")
+	os.WriteFile(f, []byte(data), 0600)
+
+	t.Setenv("PROMETHEUS_PRESETS_FILE", f)
+}
+
+func TestLoadPresetsReturnsBuiltInPresetsForRuntime(t *testing.T) {
+	p, _ := New("api-deployment", "default", "nomad", hclog.NewNullLogger())
+
+	presets, err := p.loadPresets()
+	require.NoError(t, err)
+
+	require.Equal(t, NomadEnvoyRequestSuccess, presets["envoy-request-success"])
+	require.Equal(t, NomadEnvoyGRPCRequestSuccess, presets["envoy-grpc-request-success"])
+	require.Equal(t, NomadEnvoyTCPConnectionFailures, presets["envoy-tcp-connection-failures"])
+}
+
+func TestLoadPresetsAddsPresetsFromFile(t *testing.T) {
+	setupPresetsFile(t, presetsLibrary)
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+	presets, err := p.loadPresets()
+	require.NoError(t, err)
+
+	require.Equal(t, KubernetesEnvoyRequestSuccess, presets["envoy-request-success"])
+	require.Equal(t, "sum(http_requests{pod=~\"{{ .CandidateName }}.*\"})\n", presets["http-requests"])
+	require.Equal(t, "histogram_quantile({{ .Quantile }}, sum(http_duration_bucket) by (le))", presets["envoy-request-duration"])
+	require.NotContains(t, presets, "nomad-only")
+}
+
+func TestLoadPresetsReturnsErrorWhenFileInvalid(t *testing.T) {
+	setupPresetsFile(t, "kubernetes: [")
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+	_, err := p.loadPresets()
+	require.Error(t, err)
+}
+
+func TestConfigureReturnsErrorWhenPresetsFileInvalid(t *testing.T) {
+	setupPresetsFile(t, "kubernetes: [")
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+	err := p.Configure([]byte(`{"queries": [{"name": "requests", "preset": "http-requests", "min": 1}]}`), hclog.NewNullLogger(), &mocks.StoreMock{})
+	require.Error(t, err)
+}
+
+func TestLoadPresetsAddsPresetsFromConsulKV(t *testing.T) {
+	t.Setenv("PROMETHEUS_PRESETS_CONSUL_KV_PATH", "release-controller/presets")
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+	cm := &clients.ConsulMock{}
+	cm.On("GetKV", "release-controller/presets").Return([]byte(`{"kubernetes": {"http-requests": "sum(http_requests)"}}`), nil)
+	p.consulClient = cm
+
+	presets, err := p.loadPresets()
+	require.NoError(t, err)
+
+	require.Equal(t, "sum(http_requests)", presets["http-requests"])
+}
+
+func TestPluginExecutesPresetFromLibrary(t *testing.T) {
+	setupPresetsFile(t, presetsLibrary)
+	p, pm := setupPlugin(t, `{"queries": [{"name": "requests", "preset": "http-requests", "min": 1}]}`)
+
+	_, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+
+	require.Equal(t, "sum(http_requests{pod=~\"api-deployment.*\"})\n", pm.Calls[0].Arguments[1])
+}
+
+func TestPluginAddsQuantileToDurationPreset(t *testing.T) {
+	p, pm := setupPlugin(t, `{"queries": [{"name": "duration", "preset": "envoy-request-duration", "quantile": 0.95, "max": 200}]}`)
+
+	_, err := p.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+
+	require.Contains(t, pm.Calls[0].Arguments[1], "0.95,")
+}
+
+func TestConfigureReturnsErrorWhenQuantileInvalid(t *testing.T) {
+	p, _ := New("api-deployment", "default", "kubernetes", hclog.NewNullLogger())
+
+	err := p.Configure([]byte(`{"queries": [{"name": "duration", "preset": "envoy-request-duration", "quantile": 99}]}`), hclog.NewNullLogger(), nil)
+	require.Error(t, err)
+}
+
+const presetsLibrary = `
+kubernetes:
+  http-requests: |
+    sum(http_requests{pod=~"{{ .CandidateName }}.*"})
+  envoy-request-duration: "histogram_quantile({{ .Quantile }}, sum(http_duration_bucket) by (le))"
+nomad:
+  nomad-only: "sum(http_requests)"
+`
@@ -5,7 +5,7 @@ sum(
 	rate(
     envoy_cluster_upstream_rq{
       namespace="{{ .Namespace }}",
-      pod!~"{{ .ReleaseName }}-primary.*",
+      {{ if not .Primary }}pod!~"{{ .ReleaseName }}-primary.*",{{ end }}
       pod=~"{{ .CandidateName }}.*",
       envoy_cluster_name="local_app",
       envoy_response_code!~"5.*"
@@ -18,7 +18,7 @@ sum(
     envoy_cluster_upstream_rq{
       namespace="{{ .Namespace }}",
       envoy_cluster_name="local_app",
-      pod!~"{{ .ReleaseName }}-primary.*",
+      {{ if not .Primary }}pod!~"{{ .ReleaseName }}-primary.*",{{ end }}
       pod=~"{{ .CandidateName }}.*",
     }[{{ .Interval }}]
   )
@@ -28,13 +28,13 @@ sum(
 
 const KubernetesEnvoyRequestDuration = `
 histogram_quantile(
-  0.99,
+  {{ .Quantile }},
   sum(
     rate(
       envoy_cluster_upstream_rq_time_bucket{
         namespace="{{ .Namespace }}",
         envoy_cluster_name="local_app",
-      	pod!~"{{ .ReleaseName }}-primary.*",
+      	{{ if not .Primary }}pod!~"{{ .ReleaseName }}-primary.*",{{ end }}
       	pod=~"{{ .CandidateName }}.*",
       }[{{ .Interval }}]
     )
@@ -45,7 +45,7 @@ const NomadEnvoyRequestSuccess = `
 sum(
 	rate(
     envoy_cluster_upstream_rq{
-      job!~"{{ .ReleaseName }}-primary",
+      {{ if not .Primary }}job!~"{{ .ReleaseName }}-primary",{{ end }}
       job=~"{{ .CandidateName }}",
       envoy_cluster_name="local_app",
       envoy_response_code!~"5.*"
@@ -57,7 +57,7 @@ sum(
   rate(
     envoy_cluster_upstream_rq{
       envoy_cluster_name="local_app",
-      job!~"{{ .ReleaseName }}-primary",
+      {{ if not .Primary }}job!~"{{ .ReleaseName }}-primary",{{ end }}
       job=~"{{ .CandidateName }}",
     }[{{ .Interval }}]
   )
@@ -67,15 +67,88 @@ sum(
 
 const NomadEnvoyRequestDuration = `
 histogram_quantile(
-  0.99,
+  {{ .Quantile }},
   sum(
     rate(
       envoy_cluster_upstream_rq_time_bucket{
         envoy_cluster_name="local_app",
-      	job!~"{{ .ReleaseName }}-primary",
+      	{{ if not .Primary }}job!~"{{ .ReleaseName }}-primary",{{ end }}
       	job=~"{{ .CandidateName }}",
       }[{{ .Interval }}]
     )
   ) by (le)
 )
 `
+
+const KubernetesEnvoyGRPCRequestSuccess = `
+sum(
+  rate(
+    envoy_cluster_grpc_success{
+      namespace="{{ .Namespace }}",
+      envoy_cluster_name="local_app",
+      {{ if not .Primary }}pod!~"{{ .ReleaseName }}-primary.*",{{ end }}
+      pod=~"{{ .CandidateName }}.*",
+    }[{{ .Interval }}]
+  )
+)
+/
+sum(
+  rate(
+    envoy_cluster_grpc_total{
+      namespace="{{ .Namespace }}",
+      envoy_cluster_name="local_app",
+      {{ if not .Primary }}pod!~"{{ .ReleaseName }}-primary.*",{{ end }}
+      pod=~"{{ .CandidateName }}.*",
+    }[{{ .Interval }}]
+  )
+)
+* 100
+`
+
+const KubernetesEnvoyTCPConnectionFailures = `
+sum(
+  increase(
+    envoy_cluster_upstream_cx_connect_fail{
+      namespace="{{ .Namespace }}",
+      envoy_cluster_name="local_app",
+      {{ if not .Primary }}pod!~"{{ .ReleaseName }}-primary.*",{{ end }}
+      pod=~"{{ .CandidateName }}.*",
+    }[{{ .Interval }}]
+  )
+)
+`
+
+const NomadEnvoyGRPCRequestSuccess = `
+sum(
+  rate(
+    envoy_cluster_grpc_success{
+      envoy_cluster_name="local_app",
+      {{ if not .Primary }}job!~"{{ .ReleaseName }}-primary",{{ end }}
+      job=~"{{ .CandidateName }}",
+    }[{{ .Interval }}]
+  )
+)
+/
+sum(
+  rate(
+    envoy_cluster_grpc_total{
+      envoy_cluster_name="local_app",
+      {{ if not .Primary }}job!~"{{ .ReleaseName }}-primary",{{ end }}
+      job=~"{{ .CandidateName }}",
+    }[{{ .Interval }}]
+  )
+)
+* 100
+`
+
+const NomadEnvoyTCPConnectionFailures = `
+sum(
+  increase(
+    envoy_cluster_upstream_cx_connect_fail{
+      envoy_cluster_name="local_app",
+      {{ if not .Primary }}job!~"{{ .ReleaseName }}-primary",{{ end }}
+      job=~"{{ .CandidateName }}",
+    }[{{ .Interval }}]
+  )
+)
+`
@@ -7,16 +7,27 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/nicholasjackson/consul-release-controller/pkg/clients"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
+	"github.com/nicholasjackson/consul-release-controller/pkg/events"
 	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/abtest"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/allochealth"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/bluegreen"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/canary"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/consul"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/datadog"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/discord"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/envoy"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/httpgate"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/httptest"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/httpwebhook"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/podhealth"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/prometheus"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/runtime"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/slack"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/statemachine"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/teams"
 )
 
 var prov interfaces.Provider
@@ -35,7 +46,7 @@ var statemachines map[string]interfaces.StateMachine
 func GetProvider(log hclog.Logger, metrics interfaces.Metrics, store interfaces.Store) interfaces.Provider {
 	if prov == nil {
 		statemachines = map[string]interfaces.StateMachine{}
-		prov = &ProviderImpl{log, metrics, store}
+		prov = &ProviderImpl{log, metrics, store, clock.NewReal(), events.NewBroker()}
 	}
 
 	return prov
@@ -46,6 +57,8 @@ type ProviderImpl struct {
 	log     hclog.Logger
 	metrics interfaces.Metrics
 	store   interfaces.Store
+	clock   interfaces.Clock
+	events  interfaces.Events
 }
 
 func (p *ProviderImpl) CreateReleaser(pluginName string) (interfaces.Releaser, error) {
@@ -69,16 +82,54 @@ func (p *ProviderImpl) CreateRuntime(pluginName string) (interfaces.Runtime, err
 }
 
 func (p *ProviderImpl) CreateMonitor(pluginName, name, namespace, runtime string) (interfaces.Monitor, error) {
-	if pluginName == PluginMonitorTypePrometheus {
+	switch pluginName {
+	case PluginMonitorTypePrometheus:
 		return prometheus.New(name, namespace, runtime, p.log.Named("monitor-plugin-prometheus"))
+	case PluginMonitorTypeDatadog:
+		return datadog.New(name, namespace, runtime, p.log.Named("monitor-plugin-datadog"))
+	case PluginMonitorTypeEnvoy:
+		// the candidate Envoy proxies are found using the same filter as the Consul service resolver
+		rc, err := p.GetRuntimeClient(runtime)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create runtime client: %s", err)
+		}
+
+		return envoy.New(name, rc.CandidateSubsetFilter(), p.log.Named("monitor-plugin-envoy"))
+	case PluginMonitorTypePodHealth:
+		if runtime != PluginRuntimeTypeKubernetes {
+			return nil, fmt.Errorf("the %s Monitor plugin only supports the %s runtime", PluginMonitorTypePodHealth, PluginRuntimeTypeKubernetes)
+		}
+
+		kc, err := clients.NewKubernetes(os.Getenv("KUBECONFIG"), retryTimeout, retryInterval, p.GetLogger().ResetNamed("kubernetes-client"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to create Kubernetes client: %s", err)
+		}
+
+		return podhealth.New(namespace, kc, p.log.Named("monitor-plugin-pod-health"))
+	case PluginMonitorTypeAllocHealth:
+		if runtime != PluginRuntimeTypeNomad {
+			return nil, fmt.Errorf("the %s Monitor plugin only supports the %s runtime", PluginMonitorTypeAllocHealth, PluginRuntimeTypeNomad)
+		}
+
+		nc, err := clients.NewNomad(retryInterval, retryTimeout, p.GetLogger().ResetNamed("nomad-client"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to create Nomad client: %s", err)
+		}
+
+		return allochealth.New(namespace, nc, p.log.Named("monitor-plugin-allocation-health"))
 	}
 
 	return nil, fmt.Errorf("invalid Monitor plugin type: %s", pluginName)
 }
 
 func (p *ProviderImpl) CreateStrategy(pluginName string, mp interfaces.Monitor) (interfaces.Strategy, error) {
-	if pluginName == PluginStrategyTypeCanary {
-		return canary.New(mp)
+	switch pluginName {
+	case PluginStrategyTypeCanary:
+		return canary.New(mp, p.clock)
+	case PluginStrategyTypeBlueGreen:
+		return bluegreen.New(mp, p.clock)
+	case PluginStrategyTypeABTest:
+		return abtest.New(mp, p.clock)
 	}
 
 	return nil, fmt.Errorf("invalid Strategy plugin type: %s", pluginName)
@@ -90,6 +141,10 @@ func (p *ProviderImpl) CreateWebhook(pluginName string) (interfaces.Webhook, err
 		return discord.New()
 	case PluginWebhookTypeSlack:
 		return slack.New()
+	case PluginWebhookTypeHTTP:
+		return httpwebhook.New()
+	case PluginWebhookTypeTeams:
+		return teams.New()
 	}
 
 	return nil, fmt.Errorf("invalid Webhook plugin type: %s", pluginName)
@@ -97,12 +152,20 @@ func (p *ProviderImpl) CreateWebhook(pluginName string) (interfaces.Webhook, err
 
 func (p *ProviderImpl) CreatePostDeploymentTest(pluginName, name, namespace, runtime string, mp interfaces.Monitor) (interfaces.PostDeploymentTest, error) {
 	if pluginName == PluginDeploymentTestTypeHTTP {
-		return httptest.New(name, namespace, runtime, mp)
+		return httptest.New(name, namespace, runtime, mp, p.clock)
 	}
 
 	return nil, fmt.Errorf("invalid Post deployment test plugin type: %s", pluginName)
 }
 
+func (p *ProviderImpl) CreateGate(pluginName string) (interfaces.Gate, error) {
+	if pluginName == PluginGateTypeHTTP {
+		return httpgate.New()
+	}
+
+	return nil, fmt.Errorf("invalid Gate plugin type: %s", pluginName)
+}
+
 func (p *ProviderImpl) GetRuntimeClient(runtime string) (interfaces.RuntimeClient, error) {
 	switch runtime {
 	case PluginRuntimeTypeKubernetes:
@@ -136,6 +199,14 @@ func (p *ProviderImpl) GetDataStore() interfaces.Store {
 	return p.store
 }
 
+func (p *ProviderImpl) GetClock() interfaces.Clock {
+	return p.clock
+}
+
+func (p *ProviderImpl) GetEvents() interfaces.Events {
+	return p.events
+}
+
 func (p *ProviderImpl) GetStateMachine(release *models.Release) (interfaces.StateMachine, error) {
 	if r, ok := statemachines[getReleaseKey(release)]; ok {
 		return r, nil
@@ -5,8 +5,17 @@ const (
 	PluginRuntimeTypeKubernetes  = "kubernetes"
 	PluginRuntimeTypeNomad       = "nomad"
 	PluginMonitorTypePrometheus  = "prometheus"
+	PluginMonitorTypeDatadog     = "datadog"
+	PluginMonitorTypeEnvoy       = "envoy"
+	PluginMonitorTypePodHealth   = "kubernetes-pod-health"
+	PluginMonitorTypeAllocHealth = "nomad-allocation-health"
 	PluginStrategyTypeCanary     = "canary"
+	PluginStrategyTypeBlueGreen  = "bluegreen"
+	PluginStrategyTypeABTest     = "abtest"
 	PluginWebhookTypeDiscord     = "discord"
 	PluginWebhookTypeSlack       = "slack"
+	PluginWebhookTypeHTTP        = "http"
+	PluginWebhookTypeTeams       = "teams"
 	PluginDeploymentTestTypeHTTP = "http"
+	PluginGateTypeHTTP           = "http"
 )
@@ -2,6 +2,7 @@ package slack
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"text/template"
@@ -85,7 +86,7 @@ func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfa
 	return nil
 }
 
-func (p *Plugin) Send(message interfaces.WebhookMessage) error {
+func (p *Plugin) Send(ctx context.Context, message interfaces.WebhookMessage) error {
 	// only send if current status is in our list of status
 	if len(p.config.Status) > 0 {
 		progress := false
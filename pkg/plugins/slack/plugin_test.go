@@ -1,6 +1,7 @@
 package slack
 
 import (
+	"context"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
@@ -50,7 +51,7 @@ func TestConfiguresWithoutError(t *testing.T) {
 func TestSendsMessageWithDefaultContentNoError(t *testing.T) {
 	p, mc := setupTests(t, validConfig)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
@@ -71,7 +72,7 @@ func TestSendsMessageWithDefaultContentNoError(t *testing.T) {
 func TestSendsMessageWithDefaultContentError(t *testing.T) {
 	p, mc := setupTests(t, validConfig)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
@@ -92,7 +93,7 @@ func TestSendsMessageWithDefaultContentError(t *testing.T) {
 func TestSendsMessageWithCustomContent(t *testing.T) {
 	p, mc := setupTests(t, validConfigWithTemplate)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
@@ -112,7 +113,7 @@ func TestSendsMessageWithCustomContent(t *testing.T) {
 func TestDoesNotSendWhenStatusNotMatching(t *testing.T) {
 	p, mc := setupTests(t, validConfigWithStatus)
 
-	p.Send(interfaces.WebhookMessage{
+	p.Send(context.Background(), interfaces.WebhookMessage{
 		Name:      "testname",
 		Namespace: "testnamespace",
 		Title:     "testtitle",
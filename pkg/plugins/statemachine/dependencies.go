@@ -0,0 +1,103 @@
+package statemachine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+// CheckDependencies checks the releases that the given release depends on.
+//
+// Returns true when every dependency is idle and its latest deployment was promoted, a dependency
+// without any deployments is ready when it is idle.
+// Returns false and a nil error when a dependency has a deployment in progress.
+// Returns an error when a dependency can not be found, has failed, or its latest deployment
+// was not promoted.
+func CheckDependencies(store interfaces.Store, r *models.Release) (bool, error) {
+	ready := true
+
+	for _, name := range r.DependsOn {
+		dep, err := store.GetRelease(name)
+		if err != nil {
+			return false, fmt.Errorf("unable to find release %s that %s depends on: %s", name, r.Name, err)
+		}
+
+		if dep == nil {
+			return false, fmt.Errorf("unable to find release %s that %s depends on", name, r.Name)
+		}
+
+		switch dep.CurrentState() {
+		case interfaces.StateIdle:
+		case interfaces.StateFail:
+			return false, fmt.Errorf("release %s that %s depends on has failed", name, r.Name)
+		default:
+			// the dependency has a deployment in progress, check the remaining dependencies
+			// so that failures are reported without waiting
+			ready = false
+			continue
+		}
+
+		deps, err := store.ListDeployments(name)
+		if err != nil {
+			return false, fmt.Errorf("unable to list deployments for release %s that %s depends on: %s", name, r.Name, err)
+		}
+
+		if len(deps) == 0 {
+			continue
+		}
+
+		latest := deps[len(deps)-1]
+		if latest.Outcome != models.DeploymentOutcomePromoted {
+			return false, fmt.Errorf("latest deployment for release %s that %s depends on was not promoted, outcome: %s", name, r.Name, latest.Outcome)
+		}
+	}
+
+	return ready, nil
+}
+
+// CheckDependencyCycle returns an error when a release that the given release depends on, directly or
+// through its own dependencies, depends on the given release. Dependencies that can not be found are
+// ignored as they can be created after the release.
+func CheckDependencyCycle(store interfaces.Store, r *models.Release) error {
+	visited := map[string]bool{}
+	path := []string{r.Name}
+
+	var visit func(names []string) error
+	visit = func(names []string) error {
+		for _, name := range names {
+			if name == r.Name {
+				return fmt.Errorf("release %s has a circular dependency %s", r.Name, strings.Join(append(path, name), " -> "))
+			}
+
+			if visited[name] {
+				continue
+			}
+
+			visited[name] = true
+
+			dep, err := store.GetRelease(name)
+			if err != nil && err != interfaces.ReleaseNotFound {
+				return fmt.Errorf("unable to find release %s that %s depends on: %s", name, r.Name, err)
+			}
+
+			if dep == nil {
+				continue
+			}
+
+			path = append(path, name)
+
+			err = visit(dep.DependsOn)
+			if err != nil {
+				return err
+			}
+
+			path = path[:len(path)-1]
+		}
+
+		return nil
+	}
+
+	return visit(r.DependsOn)
+}
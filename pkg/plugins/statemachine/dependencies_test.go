@@ -0,0 +1,106 @@
+package statemachine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDependencies(t *testing.T, states map[string]string) (*memory.Store, *models.Release) {
+	s := memory.NewStore()
+
+	for name, state := range states {
+		r := &models.Release{Name: name}
+		r.UpdateState(state)
+
+		require.NoError(t, s.UpsertRelease(r))
+	}
+
+	return s, &models.Release{Name: "api", DependsOn: []string{"gateway", "payments"}}
+}
+
+func appendDeployment(t *testing.T, s *memory.Store, name, outcome string) {
+	d := models.NewDeployment(name, "1", time.Now())
+	d.End(outcome, nil, time.Now())
+
+	require.NoError(t, s.AppendDeployment(name, d))
+}
+
+func setupDependencyCycle(t *testing.T, dependsOn map[string][]string) *memory.Store {
+	s := memory.NewStore()
+
+	for name, deps := range dependsOn {
+		require.NoError(t, s.UpsertRelease(&models.Release{Name: name, DependsOn: deps}))
+	}
+
+	return s
+}
+
+func TestCheckDependencyCycleReturnsNoErrorWithoutCycle(t *testing.T) {
+	s := setupDependencyCycle(t, map[string][]string{"gateway": {"payments"}, "payments": {"db"}})
+
+	err := CheckDependencyCycle(s, &models.Release{Name: "api", DependsOn: []string{"gateway", "payments"}})
+	require.NoError(t, err)
+}
+
+func TestCheckDependencyCycleIgnoresMissingDependencies(t *testing.T) {
+	s := setupDependencyCycle(t, map[string][]string{})
+
+	err := CheckDependencyCycle(s, &models.Release{Name: "api", DependsOn: []string{"gateway"}})
+	require.NoError(t, err)
+}
+
+func TestCheckDependencyCycleReturnsErrorWithCycle(t *testing.T) {
+	s := setupDependencyCycle(t, map[string][]string{"gateway": {"payments"}, "payments": {"api"}})
+
+	err := CheckDependencyCycle(s, &models.Release{Name: "api", DependsOn: []string{"gateway"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "api -> gateway -> payments -> api")
+}
+
+func TestCheckDependenciesReturnsReadyWhenDependenciesPromoted(t *testing.T) {
+	s, r := setupDependencies(t, map[string]string{"gateway": interfaces.StateIdle, "payments": interfaces.StateIdle})
+	appendDeployment(t, s, "gateway", models.DeploymentOutcomePromoted)
+
+	ready, err := CheckDependencies(s, r)
+	require.NoError(t, err)
+	require.True(t, ready)
+}
+
+func TestCheckDependenciesReturnsNotReadyWhenDependencyInProgress(t *testing.T) {
+	s, r := setupDependencies(t, map[string]string{"gateway": interfaces.StateMonitor, "payments": interfaces.StateIdle})
+
+	ready, err := CheckDependencies(s, r)
+	require.NoError(t, err)
+	require.False(t, ready)
+}
+
+func TestCheckDependenciesReturnsErrorWhenDependencyNotFound(t *testing.T) {
+	s, r := setupDependencies(t, map[string]string{"gateway": interfaces.StateIdle})
+
+	_, err := CheckDependencies(s, r)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "payments")
+}
+
+func TestCheckDependenciesReturnsErrorWhenDependencyFailed(t *testing.T) {
+	s, r := setupDependencies(t, map[string]string{"gateway": interfaces.StateMonitor, "payments": interfaces.StateFail})
+
+	_, err := CheckDependencies(s, r)
+	require.Error(t, err)
+}
+
+func TestCheckDependenciesReturnsErrorWhenDependencyRolledBack(t *testing.T) {
+	s, r := setupDependencies(t, map[string]string{"gateway": interfaces.StateIdle, "payments": interfaces.StateIdle})
+	appendDeployment(t, s, "payments", models.DeploymentOutcomePromoted)
+	appendDeployment(t, s, "payments", models.DeploymentOutcomeRolledBack)
+
+	_, err := CheckDependencies(s, r)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fmt.Sprintf("outcome: %s", models.DeploymentOutcomeRolledBack))
+}
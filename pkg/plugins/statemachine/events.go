@@ -0,0 +1,74 @@
+package statemachine
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+// publish adds the release, time, state, and candidate traffic to the event and sends it to
+// the subscribers for the release
+func (s *StateMachine) publish(e interfaces.ReleaseEvent) {
+	e.Release = s.release.Name
+	e.Time = s.clock.Now()
+
+	s.stateLock.Lock()
+	e.State = s.release.CurrentState()
+	e.CandidateTraffic = s.candidateTraffic
+	s.stateLock.Unlock()
+
+	s.events.Publish(e)
+}
+
+// scale sets the traffic sent to the candidate and publishes the change
+func (s *StateMachine) scale(ctx context.Context, traffic int) error {
+	err := s.releaserPlugin.Scale(ctx, traffic)
+	if err != nil {
+		return err
+	}
+
+	s.stateLock.Lock()
+	changed := s.candidateTraffic != traffic
+	s.candidateTraffic = traffic
+	s.stateLock.Unlock()
+
+	if changed {
+		s.publish(interfaces.ReleaseEvent{Type: interfaces.ReleaseEventTraffic})
+	}
+
+	return nil
+}
+
+func (s *StateMachine) getCandidateTraffic() int {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	return s.candidateTraffic
+}
+
+// monitorEvents wraps the monitor plugin used by the strategy and publishes the result of
+// every check
+type monitorEvents struct {
+	monitor interfaces.Monitor
+	sm      *StateMachine
+}
+
+func (m *monitorEvents) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return m.monitor.Configure(data, log, store)
+}
+
+func (m *monitorEvents) Check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	result, err := m.monitor.Check(ctx, candidateName, interval)
+
+	e := interfaces.ReleaseEvent{Type: interfaces.ReleaseEventCheck, Result: result.String()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	m.sm.publish(e)
+
+	return result, err
+}
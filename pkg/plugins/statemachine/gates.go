@@ -0,0 +1,206 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+// defaultGateInterval is the interval between checks of a pending gate when the gate does
+// not define an interval
+var defaultGateInterval = 30 * time.Second
+
+type releaseGate struct {
+	config   *models.Gate
+	plugin   interfaces.Gate
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// createGates validates the gates for the release and creates the gate plugins
+func (s *StateMachine) createGates(pluginProvider interfaces.Provider) error {
+	names := map[string]bool{}
+
+	for _, g := range s.release.Gates {
+		err := g.Validate()
+		if err != nil {
+			return err
+		}
+
+		if names[g.Name] {
+			return fmt.Errorf("gate %s is defined more than once", g.Name)
+		}
+
+		names[g.Name] = true
+
+		gp, err := pluginProvider.CreateGate(g.PluginName)
+		if err != nil {
+			return err
+		}
+
+		err = gp.Configure(g.Config, s.logger.ResetNamed("gate-plugin"), s.storage.CreatePluginStateStore(s.release, "gate-"+g.Name))
+		if err != nil {
+			return err
+		}
+
+		rg := &releaseGate{config: g, plugin: gp, interval: defaultGateInterval}
+
+		// durations have been validated
+		if g.Interval != "" {
+			rg.interval, _ = time.ParseDuration(g.Interval)
+		}
+
+		if g.Timeout != "" {
+			rg.timeout, _ = time.ParseDuration(g.Timeout)
+		}
+
+		s.gates = append(s.gates, rg)
+	}
+
+	return nil
+}
+
+// DecideGate records the decision for the named gate, a release that is waiting for the gate
+// uses the decision the next time the gate is checked
+func (s *StateMachine) DecideGate(gate string, decision interfaces.GateResponse) error {
+	found := false
+	for _, g := range s.gates {
+		if g.config.Name == gate {
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("release %s does not have the gate %s", s.release.Name, gate)
+	}
+
+	if decision.Result != interfaces.GateResultApproved && decision.Result != interfaces.GateResultRejected {
+		return fmt.Errorf("invalid decision %s for gate %s, please specify one of %s, %s", decision.Result, gate, interfaces.GateResultApproved, interfaces.GateResultRejected)
+	}
+
+	s.stateLock.Lock()
+	s.gateDecisions[gate] = decision
+	s.stateLock.Unlock()
+
+	// wake a release that is waiting for the gate
+	select {
+	case s.gateNotify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// waitForGates waits until every gate for the stage has been approved, returns false when a
+// gate rejects the release or the context is cancelled, the error contains the reason for
+// a rejection and is nil when the context is cancelled
+func (s *StateMachine) waitForGates(ctx context.Context, stage string) (bool, error) {
+	for _, g := range s.gates {
+		if g.config.GetStage() != stage {
+			continue
+		}
+
+		approved, err := s.waitForGate(ctx, g)
+		if !approved {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (s *StateMachine) waitForGate(ctx context.Context, g *releaseGate) (bool, error) {
+	name := g.config.Name
+
+	// the deadline is compared with the clock after every check so that a check which is due at the
+	// same time as the deadline is always made
+	var deadline time.Time
+	if g.timeout > 0 {
+		deadline = s.clock.Now().Add(g.timeout)
+	}
+
+	waiting := false
+
+	for {
+		resp, err := s.checkGate(ctx, g)
+
+		if ctx.Err() != nil {
+			return false, nil
+		}
+
+		switch {
+		// gates that can not be checked are retried, the release is not rejected because the
+		// approval service is unavailable
+		case err != nil:
+			s.logger.Error("Unable to check gate", "name", name, "error", err)
+
+		case resp.Result == interfaces.GateResultApproved:
+			s.logger.Info("Gate approved release", "name", name, "reason", resp.Reason)
+			return true, nil
+
+		case resp.Result == interfaces.GateResultRejected:
+			s.logger.Info("Gate rejected release", "name", name, "reason", resp.Reason)
+			return false, fmt.Errorf("gate %s rejected the release: %s", name, resp.Reason)
+		}
+
+		if !waiting {
+			s.logger.Info("Release waiting for gate approval", "name", name, "stage", g.config.GetStage())
+
+			s.callWebhooks(
+				ctx,
+				s.webhookPlugins,
+				fmt.Sprintf("Release waiting for approval from gate %s", name),
+				interfaces.StateMonitor,
+				interfaces.EventWaiting,
+				100-s.getCandidateTraffic(),
+				s.getCandidateTraffic(),
+				nil,
+			)
+
+			waiting = true
+		}
+
+		wait := g.interval
+		if g.timeout > 0 {
+			remaining := deadline.Sub(s.clock.Now())
+			if remaining <= 0 {
+				return false, fmt.Errorf("gate %s was not approved within %s", name, g.timeout)
+			}
+
+			if remaining < wait {
+				wait = remaining
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-s.gateNotify:
+		case <-s.clock.After(wait):
+		}
+	}
+}
+
+// checkGate returns the decision received for the gate, when no decision has been received
+// the gate plugin is checked
+func (s *StateMachine) checkGate(ctx context.Context, g *releaseGate) (interfaces.GateResponse, error) {
+	s.stateLock.Lock()
+	decision, ok := s.gateDecisions[g.config.Name]
+	s.stateLock.Unlock()
+
+	if ok {
+		return decision, nil
+	}
+
+	return g.plugin.Check(ctx, interfaces.GateRequest{
+		Gate:             g.config.Name,
+		Release:          s.release.Name,
+		Namespace:        s.release.Namespace,
+		Candidate:        s.runtimePlugin.BaseState().CandidateName,
+		Stage:            g.config.GetStage(),
+		CandidateTraffic: s.getCandidateTraffic(),
+	})
+}
@@ -0,0 +1,199 @@
+package statemachine
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGateTests(t *testing.T, stage string, result interfaces.GateResult) (*models.Release, *StateMachine, *mocks.Mocks) {
+	r, sm, pm := setupTests(t)
+
+	g := &models.Gate{Name: "cab", PluginName: "http", Stage: stage}
+	r.Gates = []*models.Gate{g}
+	sm.gates = []*releaseGate{{config: g, plugin: pm.GateMock, interval: 1 * time.Millisecond}}
+
+	testutils.ClearMockCall(&pm.GateMock.Mock, "Check")
+	pm.GateMock.On("Check", mock.Anything, mock.Anything).Return(interfaces.GateResponse{Result: result, Reason: "change board"}, nil)
+
+	return r, sm, pm
+}
+
+func gateCheckedForStage(m *mocks.GateMock, stage string) bool {
+	return calledWith(&m.Mock, "Check", mock.Anything, mock.MatchedBy(func(req interfaces.GateRequest) bool {
+		return req.Stage == stage
+	}))
+}
+
+func TestNewWithInvalidGateReturnsError(t *testing.T) {
+	pp, _ := mocks.BuildMocks(t)
+
+	r := &models.Release{}
+	r.FromJsonBody(ioutil.NopCloser(bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))))
+	r.Gates = []*models.Gate{{Name: "cab", PluginName: "http", Stage: "before_lunch"}}
+
+	_, err := New(r, pp)
+	require.Error(t, err)
+}
+
+func TestEventDeployedWithCompleteAndApprovedGateSetsStatusPromote(t *testing.T) {
+	_, sm, pm := setupGateTests(t, models.GateStagePromote, interfaces.GateResultApproved)
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusComplete, 100, nil)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StatePromote) }, time.Second, time.Millisecond)
+	require.True(t, gateCheckedForStage(pm.GateMock, models.GateStagePromote))
+}
+
+func TestEventDeployedWithCompleteAndRejectedGateSetsStatusRollback(t *testing.T) {
+	_, sm, pm := setupGateTests(t, models.GateStagePromote, interfaces.GateResultRejected)
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusComplete, 100, nil)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateRollback) }, time.Second, time.Millisecond)
+	require.False(t, historyContains(sm, interfaces.StatePromote))
+	require.True(t, webhookSentWithOutcome(pm.WebhookMock, interfaces.EventUnhealthy))
+}
+
+func TestEventDeployedWithPendingGateWaitsForDecision(t *testing.T) {
+	_, sm, pm := setupGateTests(t, models.GateStagePromote, interfaces.GateResultPending)
+	sm.gates[0].interval = 1 * time.Hour
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusComplete, 100, nil)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return webhookSentWithOutcome(pm.WebhookMock, interfaces.EventWaiting) }, time.Second, time.Millisecond)
+	require.Equal(t, interfaces.StateMonitor, sm.CurrentState())
+
+	err := sm.DecideGate("cab", interfaces.GateResponse{Result: interfaces.GateResultApproved})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StatePromote) }, time.Second, time.Millisecond)
+}
+
+func TestEventDeployedWithGateTimeoutRollsBack(t *testing.T) {
+	r, sm, pm := setupGateTests(t, models.GateStagePromote, interfaces.GateResultPending)
+	r.Deployment = models.NewDeployment("api-deployment-v1", "2", time.Now())
+	sm.gates[0].timeout = 5 * time.Millisecond
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusComplete, 100, nil)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return appendedDeployment(pm.StoreMock) != nil }, time.Second, time.Millisecond)
+
+	d := appendedDeployment(pm.StoreMock)
+	require.Equal(t, models.DeploymentOutcomeRolledBack, d.Outcome)
+	require.Contains(t, d.FailureReason, "not approved within")
+}
+
+func TestEventDeployedWithGateTimeoutAndVirtualClockRollsBack(t *testing.T) {
+	r, sm, pm := setupGateTests(t, models.GateStagePromote, interfaces.GateResultPending)
+	r.Deployment = models.NewDeployment("api-deployment-v1", "2", time.Now())
+	sm.clock = clock.NewVirtual(time.Now())
+	sm.gates[0].interval = 1 * time.Minute
+	sm.gates[0].timeout = 90 * time.Minute
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusComplete, 100, nil)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return appendedDeployment(pm.StoreMock) != nil }, time.Second, time.Millisecond)
+
+	d := appendedDeployment(pm.StoreMock)
+	require.Equal(t, models.DeploymentOutcomeRolledBack, d.Outcome)
+	require.Contains(t, d.FailureReason, "not approved within 1h30m0s")
+
+	// the gate is checked once on entry and once after every interval up to and including the deadline
+	pm.GateMock.AssertNumberOfCalls(t, "Check", 91)
+}
+
+func TestEventDeployedWithDecisionReceivedBeforeGateUsesDecision(t *testing.T) {
+	_, sm, pm := setupGateTests(t, models.GateStagePromote, interfaces.GateResultPending)
+	sm.gates[0].interval = 1 * time.Hour
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusComplete, 100, nil)
+
+	err := sm.DecideGate("cab", interfaces.GateResponse{Result: interfaces.GateResultApproved})
+	require.NoError(t, err)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StatePromote) }, time.Second, time.Millisecond)
+	pm.GateMock.AssertNotCalled(t, "Check", mock.Anything, mock.Anything)
+}
+
+func TestStartDeploymentClearsGateDecisions(t *testing.T) {
+	r, sm, _ := setupGateTests(t, models.GateStagePromote, interfaces.GateResultPending)
+	r.Deployment = models.NewDeployment("api-deployment-v1", "2", time.Now())
+
+	err := sm.DecideGate("cab", interfaces.GateResponse{Result: interfaces.GateResultApproved})
+	require.NoError(t, err)
+
+	sm.startDeployment(true)
+	require.Len(t, sm.gateDecisions, 1)
+
+	sm.startDeployment(false)
+	require.Len(t, sm.gateDecisions, 0)
+}
+
+func TestEventDeployedWithGateErrorRetriesCheck(t *testing.T) {
+	_, sm, pm := setupGateTests(t, models.GateStageTraffic, interfaces.GateResultApproved)
+
+	testutils.ClearMockCall(&pm.GateMock.Mock, "Check")
+	pm.GateMock.On("Check", mock.Anything, mock.Anything).Once().Return(interfaces.GateResponse{}, fmt.Errorf("boom"))
+	pm.GateMock.On("Check", mock.Anything, mock.Anything).Return(interfaces.GateResponse{Result: interfaces.GateResultApproved}, nil)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	// the first check fails, the release only scales when the check is retried
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateScale) }, time.Second, time.Millisecond)
+}
+
+func TestEventDeployedWithTrafficGateChecksBeforeFirstScale(t *testing.T) {
+	_, sm, pm := setupGateTests(t, models.GateStageTraffic, interfaces.GateResultApproved)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateScale) }, time.Second, time.Millisecond)
+	require.True(t, gateCheckedForStage(pm.GateMock, models.GateStageTraffic))
+}
+
+func TestDecideGateWithUnknownGateReturnsError(t *testing.T) {
+	_, sm, _ := setupGateTests(t, models.GateStagePromote, interfaces.GateResultPending)
+
+	err := sm.DecideGate("security", interfaces.GateResponse{Result: interfaces.GateResultApproved})
+	require.Error(t, err)
+
+	err = sm.DecideGate("cab", interfaces.GateResponse{Result: interfaces.GateResultPending})
+	require.Error(t, err)
+}
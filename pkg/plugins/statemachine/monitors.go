@@ -0,0 +1,170 @@
+package statemachine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+type releaseMonitor struct {
+	config *models.Monitor
+	plugin interfaces.Monitor
+}
+
+// compositeMonitor checks the candidate with every monitor for the release and combines the
+// results using the monitor policy
+type compositeMonitor struct {
+	sm       *StateMachine
+	monitors []*releaseMonitor
+	policy   *models.MonitorPolicy
+
+	results     []interfaces.MonitorResult
+	resultsLock sync.Mutex
+}
+
+// createMonitors validates the monitors for the release and creates the monitor plugins
+func (s *StateMachine) createMonitors(pluginProvider interfaces.Provider, namespace string) (*compositeMonitor, error) {
+	err := s.release.MonitorPolicy.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &compositeMonitor{sm: s, policy: s.release.MonitorPolicy}
+	names := map[string]bool{}
+
+	for _, m := range s.release.Monitors {
+		err := m.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		if names[m.Name] {
+			return nil, fmt.Errorf("monitor %s is defined more than once", m.Name)
+		}
+
+		names[m.Name] = true
+
+		mp, err := pluginProvider.CreateMonitor(m.PluginName, s.release.Name, namespace, s.release.Runtime.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		err = mp.Configure(m.Config, s.logger.ResetNamed("monitor-plugin").Named(m.Name), s.storage.CreatePluginStateStore(s.release, "monitor-"+m.Name))
+		if err != nil {
+			return nil, fmt.Errorf("unable to configure monitor %s: %s", m.Name, err)
+		}
+
+		cm.monitors = append(cm.monitors, &releaseMonitor{config: m, plugin: mp})
+	}
+
+	return cm, nil
+}
+
+// Configure is not used, the monitors are configured when they are created
+func (c *compositeMonitor) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return nil
+}
+
+// Check checks the candidate with every monitor, returns CheckSuccess when the results satisfy the
+// policy. When the policy is not satisfied the most significant result of the monitors that did
+// not pass is returned, a failed check takes precedence over an error, and an error over no metrics
+func (c *compositeMonitor) Check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	results := []interfaces.MonitorResult{}
+	errors := []string{}
+
+	passed := 0
+	totalWeight := 0
+	passedWeight := 0
+	combined := interfaces.CheckSuccess
+
+	for _, m := range c.monitors {
+		result, err := m.plugin.Check(ctx, candidateName, interval)
+
+		mr := interfaces.MonitorResult{Name: m.config.Name, Result: result.String()}
+		e := interfaces.ReleaseEvent{Type: interfaces.ReleaseEventCheck, Result: result.String(), Monitor: m.config.Name}
+
+		if err != nil {
+			mr.Error = err.Error()
+			e.Error = err.Error()
+			errors = append(errors, fmt.Sprintf("monitor %s %s: %s", m.config.Name, result, err))
+		}
+
+		c.sm.logger.Info("Monitor checked candidate", "name", m.config.Name, "result", result, "error", err)
+		c.sm.publish(e)
+
+		results = append(results, mr)
+		totalWeight += m.config.GetWeight()
+
+		if result == interfaces.CheckSuccess && err == nil {
+			passed++
+			passedWeight += m.config.GetWeight()
+			continue
+		}
+
+		if precedence(result) > precedence(combined) {
+			combined = result
+		}
+	}
+
+	c.resultsLock.Lock()
+	c.results = results
+	c.resultsLock.Unlock()
+
+	// the check was cancelled, the results are not complete
+	if ctx.Err() != nil {
+		return interfaces.CheckError, ctx.Err()
+	}
+
+	switch c.policy.GetType() {
+	case models.MonitorPolicyAny:
+		if passed > 0 {
+			return interfaces.CheckSuccess, nil
+		}
+	case models.MonitorPolicyWeighted:
+		score := passedWeight * 100 / totalWeight
+		if score >= c.policy.Threshold {
+			return interfaces.CheckSuccess, nil
+		}
+
+		errors = append(errors, fmt.Sprintf("weighted score %d is less than the threshold %d", score, c.policy.Threshold))
+	default:
+		if passed == len(c.monitors) {
+			return interfaces.CheckSuccess, nil
+		}
+	}
+
+	// monitors that return an error without a result have failed their check
+	if combined == interfaces.CheckSuccess {
+		combined = interfaces.CheckFailed
+	}
+
+	return combined, fmt.Errorf("%s", strings.Join(errors, ", "))
+}
+
+// Results returns the result of the latest check for each monitor
+func (c *compositeMonitor) Results() []interfaces.MonitorResult {
+	c.resultsLock.Lock()
+	defer c.resultsLock.Unlock()
+
+	return c.results
+}
+
+func precedence(r interfaces.CheckResult) int {
+	switch r {
+	case interfaces.CheckFailed:
+		return 3
+	case interfaces.CheckError:
+		return 2
+	case interfaces.CheckNoMetrics:
+		return 1
+	}
+
+	return 0
+}
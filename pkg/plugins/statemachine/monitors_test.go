@@ -0,0 +1,147 @@
+package statemachine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMonitorTests(t *testing.T, policy *models.MonitorPolicy, latency, kpi interfaces.CheckResult) (*StateMachine, *compositeMonitor, *mocks.Mocks) {
+	_, sm, pm := setupTests(t)
+
+	cm := &compositeMonitor{sm: sm, policy: policy}
+
+	for _, m := range []struct {
+		name   string
+		weight int
+		result interfaces.CheckResult
+	}{{"latency", 3, latency}, {"kpi", 1, kpi}} {
+		mm := &mocks.MonitorMock{}
+
+		var err error
+		if m.result != interfaces.CheckSuccess {
+			err = fmt.Errorf("%s check %s", m.name, m.result)
+		}
+
+		mm.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(m.result, err)
+
+		cm.monitors = append(cm.monitors, &releaseMonitor{config: &models.Monitor{Name: m.name, Weight: m.weight}, plugin: mm})
+	}
+
+	sm.monitors = cm
+
+	return sm, cm, pm
+}
+
+func TestNewWithMonitorsCreatesEachMonitor(t *testing.T) {
+	pp, pm := mocks.BuildMocks(t)
+
+	r := &models.Release{}
+	r.FromJsonBody(ioutil.NopCloser(bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))))
+	r.Monitors = []*models.Monitor{
+		{Name: "latency", PluginName: "prometheus", Config: json.RawMessage(`{"address": "prometheus"}`)},
+		{Name: "kpi", PluginName: "datadog", Config: json.RawMessage(`{"api_key": "abc"}`)},
+	}
+
+	sm, err := New(r, pp)
+	require.NoError(t, err)
+
+	pp.AssertCalled(t, "CreateMonitor", "prometheus", r.Name, mock.Anything, r.Runtime.Name)
+	pp.AssertCalled(t, "CreateMonitor", "datadog", r.Name, mock.Anything, r.Runtime.Name)
+	pm.MonitorMock.AssertCalled(t, "Configure", r.Monitors[0].Config, mock.Anything, mock.Anything)
+	pm.MonitorMock.AssertCalled(t, "Configure", r.Monitors[1].Config, mock.Anything, mock.Anything)
+
+	require.Len(t, sm.monitors.monitors, 2)
+	require.Equal(t, sm.monitors, sm.monitorPlugin)
+}
+
+func TestNewWithInvalidMonitorsReturnsError(t *testing.T) {
+	tests := map[string]func(r *models.Release){
+		"duplicate": func(r *models.Release) {
+			r.Monitors = []*models.Monitor{{Name: "kpi", PluginName: "datadog"}, {Name: "kpi", PluginName: "prometheus"}}
+		},
+		"policy": func(r *models.Release) {
+			r.Monitors = []*models.Monitor{{Name: "kpi", PluginName: "datadog"}}
+			r.MonitorPolicy = &models.MonitorPolicy{Type: models.MonitorPolicyWeighted}
+		},
+	}
+
+	for name, f := range tests {
+		pp, _ := mocks.BuildMocks(t)
+
+		r := &models.Release{}
+		r.FromJsonBody(ioutil.NopCloser(bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))))
+		f(r)
+
+		_, err := New(r, pp)
+		require.Error(t, err, name)
+	}
+}
+
+func TestCompositeMonitorAllPolicyFailsWhenOneMonitorFails(t *testing.T) {
+	_, cm, _ := setupMonitorTests(t, nil, interfaces.CheckSuccess, interfaces.CheckFailed)
+
+	result, err := cm.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "monitor kpi failed")
+	require.Equal(t, interfaces.CheckFailed, result)
+
+	require.Equal(t, []interfaces.MonitorResult{
+		{Name: "latency", Result: "success"},
+		{Name: "kpi", Result: "failed", Error: "kpi check failed"},
+	}, cm.Results())
+}
+
+func TestCompositeMonitorAnyPolicyPassesWhenOneMonitorPasses(t *testing.T) {
+	_, cm, _ := setupMonitorTests(t, &models.MonitorPolicy{Type: models.MonitorPolicyAny}, interfaces.CheckNoMetrics, interfaces.CheckSuccess)
+
+	result, err := cm.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestCompositeMonitorReturnsMostSignificantResult(t *testing.T) {
+	_, cm, _ := setupMonitorTests(t, &models.MonitorPolicy{Type: models.MonitorPolicyAny}, interfaces.CheckNoMetrics, interfaces.CheckError)
+
+	result, err := cm.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Equal(t, interfaces.CheckError, result)
+}
+
+func TestCompositeMonitorWeightedPolicyPassesWhenScoreReachesThreshold(t *testing.T) {
+	_, cm, _ := setupMonitorTests(t, &models.MonitorPolicy{Type: models.MonitorPolicyWeighted, Threshold: 75}, interfaces.CheckSuccess, interfaces.CheckFailed)
+
+	result, err := cm.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, interfaces.CheckSuccess, result)
+}
+
+func TestCompositeMonitorWeightedPolicyFailsWhenScoreLessThanThreshold(t *testing.T) {
+	_, cm, _ := setupMonitorTests(t, &models.MonitorPolicy{Type: models.MonitorPolicyWeighted, Threshold: 50}, interfaces.CheckFailed, interfaces.CheckSuccess)
+
+	result, err := cm.Check(context.Background(), "api-deployment", 30*time.Second)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "weighted score 25 is less than the threshold 50")
+	require.Equal(t, interfaces.CheckFailed, result)
+}
+
+func TestWebhooksContainMonitorResults(t *testing.T) {
+	sm, cm, pm := setupMonitorTests(t, nil, interfaces.CheckSuccess, interfaces.CheckFailed)
+
+	cm.Check(context.Background(), "api-deployment", 30*time.Second)
+	sm.callWebhooks(context.Background(), sm.webhookPlugins, "Monitor failed", interfaces.StateMonitor, interfaces.EventUnhealthy, 100, 0, nil)
+
+	msg := pm.WebhookMock.Calls[len(pm.WebhookMock.Calls)-1].Arguments.Get(1).(interfaces.WebhookMessage)
+	require.Equal(t, cm.Results(), msg.Monitors)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -15,9 +16,18 @@ import (
 // stepDelay is used to set the default delay between events
 var stepDelay = 5 * time.Second
 
-// defaultTimeout is the default time that an event step can take before timing out
+// defaultTimeout is the default time that an event step can take before timing out, the timeout
+// for each state can be configured using the release Timeouts
 var defaultTimeout = 30 * time.Minute
 
+// scheduleInterval is the interval between checks of the release schedule when the release
+// is waiting for the schedule to allow changes
+var scheduleInterval = 1 * time.Minute
+
+// dependencyInterval is the interval between checks of the releases that a pending deployment
+// depends on
+var dependencyInterval = 30 * time.Second
+
 type StateMachine struct {
 	release        *models.Release
 	releaserPlugin interfaces.Releaser
@@ -29,18 +39,79 @@ type StateMachine struct {
 	logger         hclog.Logger
 	metrics        interfaces.Metrics
 	storage        interfaces.Store
+	clock          interfaces.Clock
+	events         interfaces.Events
 
 	metricsDone func(int)
 
+	// stateCancel cancels any in-flight work for the current state, it is called when the
+	// release leaves the state or the statemachine is stopped
+	stateCancel context.CancelFunc
+
+	// stopContext is cancelled when the statemachine is stopped, it bounds work that is not tied
+	// to a state such as the webhooks sent when a paused release resumes
+	stopContext context.Context
+	stopCancel  context.CancelFunc
+
+	// stateLock guards stateCancel, candidateTraffic, and the state history and in progress
+	// deployment of the release, these are changed by the goroutines that do the work for
+	// each state
+	stateLock sync.Mutex
+
+	// candidateTraffic is the traffic sent to the candidate by the last scale, it is used for
+	// the published events
+	candidateTraffic int
+
+	// gates must approve the release before it continues, decisions sent to the API are
+	// stored in gateDecisions and signalled on gateNotify
+	gates         []*releaseGate
+	gateDecisions map[string]interfaces.GateResponse
+	gateNotify    chan struct{}
+
+	// monitors combines the results of the monitors when the release has multiple monitors
+	monitors *compositeMonitor
+
 	*fsm.FSM
 }
 
 func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine, error) {
-	sm := &StateMachine{release: r, webhookPlugins: []interfaces.Webhook{}}
+	err := r.Timeouts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Schedule.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range r.DependsOn {
+		if d == r.Name {
+			return nil, fmt.Errorf("release %s can not depend on itself", r.Name)
+		}
+	}
+
+	sm := &StateMachine{
+		release:        r,
+		webhookPlugins: []interfaces.Webhook{},
+		gateDecisions:  map[string]interfaces.GateResponse{},
+		gateNotify:     make(chan struct{}, 1),
+	}
+
+	sm.stopContext, sm.stopCancel = context.WithCancel(context.Background())
+
 	sm.logger = pluginProvider.GetLogger().Named("statemachine")
 	sm.metrics = pluginProvider.GetMetrics()
+	sm.clock = pluginProvider.GetClock()
+	sm.events = pluginProvider.GetEvents()
 	sm.storage = pluginProvider.GetDataStore()
 
+	// a release that depends on itself through its dependencies would wait forever
+	err = CheckDependencyCycle(sm.storage, r)
+	if err != nil {
+		return nil, err
+	}
+
 	// create the setup plugin
 	relP, err := pluginProvider.CreateReleaser(r.Releaser.Name)
 	if err != nil {
@@ -69,18 +140,35 @@ func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine,
 	// get the runtime config
 	runtimeConfig := runP.BaseConfig()
 
-	// create the monitor plugin
-	monP, err := pluginProvider.CreateMonitor(r.Monitor.Name, r.Name, runtimeConfig.Namespace, r.Runtime.Name)
-	if err != nil {
-		return nil, err
+	var monP interfaces.Monitor
+
+	if len(r.Monitors) > 0 {
+		// combine the results of the monitors using the monitor policy
+		cm, err := sm.createMonitors(pluginProvider, runtimeConfig.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		sm.monitors = cm
+		monP = cm
+	} else {
+		// create the monitor plugin
+		monP, err = pluginProvider.CreateMonitor(r.Monitor.Name, r.Name, runtimeConfig.Namespace, r.Runtime.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		// configure the monitor plugin
+		err = monP.Configure(r.Monitor.Config, sm.logger.ResetNamed("monitor-plugin"), sm.storage.CreatePluginStateStore(r, "monitor"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// configure the monitor plugin
-	monP.Configure(r.Monitor.Config, sm.logger.ResetNamed("monitor-plugin"), sm.storage.CreatePluginStateStore(r, "monitor"))
 	sm.monitorPlugin = monP
 
-	// create the strategy plugin
-	stratP, err := pluginProvider.CreateStrategy(r.Strategy.Name, monP)
+	// create the strategy plugin, the result of every check made by the strategy is published
+	stratP, err := pluginProvider.CreateStrategy(r.Strategy.Name, &monitorEvents{monP, sm})
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +177,12 @@ func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine,
 	stratP.Configure(r.Strategy.Config, sm.logger.ResetNamed("strategy-plugin"), sm.storage.CreatePluginStateStore(r, "strategy"))
 	sm.strategyPlugin = stratP
 
+	// the monitor timeout limits each call to Execute, a window that does not complete before the
+	// timeout would fail the release after all traffic has been sent to the candidate
+	if ws, ok := stratP.(interfaces.WindowStrategy); ok && ws.GetWindow() >= sm.timeout(interfaces.StateMonitor) {
+		return nil, fmt.Errorf("strategy window %s must be shorter than the monitor timeout %s", ws.GetWindow(), sm.timeout(interfaces.StateMonitor))
+	}
+
 	// configure the webhooks
 	for _, w := range r.Webhooks {
 		wp, err := pluginProvider.CreateWebhook(w.Name)
@@ -119,8 +213,19 @@ func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine,
 		sm.testPlugin = testP
 	}
 
+	// configure the gates
+	err = sm.createGates(pluginProvider)
+	if err != nil {
+		return nil, err
+	}
+
 	sm.logger.Debug("Current release state", "state", r.CurrentState())
 
+	// restore the candidate traffic for a release that is rehydrated part way through a deployment
+	if r.Deployment != nil && len(r.Deployment.TrafficSteps) > 0 {
+		sm.candidateTraffic = r.Deployment.TrafficSteps[len(r.Deployment.TrafficSteps)-1].Traffic
+	}
+
 	initialState := interfaces.StateStart
 	if r.CurrentState() != "" {
 		initialState = r.CurrentState()
@@ -131,13 +236,25 @@ func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine,
 		fsm.Events{
 			{Name: interfaces.EventConfigure, Src: []string{interfaces.StateStart, interfaces.StateIdle, interfaces.StateFail}, Dst: interfaces.StateConfigure},
 			{Name: interfaces.EventConfigured, Src: []string{interfaces.StateConfigure}, Dst: interfaces.StateIdle},
-			{Name: interfaces.EventDeploy, Src: []string{interfaces.StateIdle, interfaces.StateFail}, Dst: interfaces.StateDeploy},
+			{Name: interfaces.EventPending, Src: []string{interfaces.StateIdle, interfaces.StateFail}, Dst: interfaces.StatePending},
+			{Name: interfaces.EventDeploy, Src: []string{interfaces.StateIdle, interfaces.StateFail, interfaces.StatePending}, Dst: interfaces.StateDeploy},
 			{Name: interfaces.EventDeployed, Src: []string{interfaces.StateDeploy}, Dst: interfaces.StateMonitor},
 			{Name: interfaces.EventHealthy, Src: []string{interfaces.StateMonitor}, Dst: interfaces.StateScale},
 			{Name: interfaces.EventScaled, Src: []string{interfaces.StateScale}, Dst: interfaces.StateMonitor},
 			{Name: interfaces.EventComplete, Src: []string{interfaces.StateMonitor}, Dst: interfaces.StatePromote},
+			{Name: interfaces.EventAwaitPromotion, Src: []string{interfaces.StateMonitor}, Dst: interfaces.StateAwaitPromotion},
+			{Name: interfaces.EventPromote, Src: []string{interfaces.StateAwaitPromotion}, Dst: interfaces.StatePromote},
 			{Name: interfaces.EventPromoted, Src: []string{interfaces.StatePromote}, Dst: interfaces.StateIdle},
 			{Name: interfaces.EventUnhealthy, Src: []string{interfaces.StateMonitor}, Dst: interfaces.StateRollback},
+			{Name: interfaces.EventPause, Src: []string{interfaces.StateMonitor}, Dst: interfaces.StatePaused},
+			{Name: interfaces.EventResume, Src: []string{interfaces.StatePaused}, Dst: interfaces.StateMonitor},
+			{Name: interfaces.EventAbort, Src: []string{
+				interfaces.StatePending,
+				interfaces.StateMonitor,
+				interfaces.StateScale,
+				interfaces.StatePaused,
+				interfaces.StateAwaitPromotion,
+			}, Dst: interfaces.StateRollback},
 			{Name: interfaces.EventComplete, Src: []string{interfaces.StateDeploy}, Dst: interfaces.StateIdle},
 			{Name: interfaces.EventComplete, Src: []string{interfaces.StateRollback}, Dst: interfaces.StateIdle},
 			{Name: interfaces.EventComplete, Src: []string{interfaces.StateDestroy}, Dst: interfaces.StateIdle},
@@ -145,10 +262,22 @@ func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine,
 				interfaces.StateStart,
 				interfaces.StateConfigure,
 				interfaces.StateIdle,
+				interfaces.StatePending,
+				interfaces.StateDeploy,
+				interfaces.StateMonitor,
+				interfaces.StateScale,
+				interfaces.StatePromote,
+				interfaces.StateAwaitPromotion,
+				interfaces.StateRollback,
+				interfaces.StateDestroy,
+			}, Dst: interfaces.StateFail},
+			{Name: interfaces.EventTimeout, Src: []string{
+				interfaces.StateConfigure,
 				interfaces.StateDeploy,
 				interfaces.StateMonitor,
 				interfaces.StateScale,
 				interfaces.StatePromote,
+				interfaces.StateAwaitPromotion,
 				interfaces.StateRollback,
 				interfaces.StateDestroy,
 			}, Dst: interfaces.StateFail},
@@ -156,24 +285,31 @@ func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine,
 				interfaces.StateFail,
 				interfaces.StateIdle,
 				interfaces.StateConfigure,
+				interfaces.StatePending,
 				interfaces.StateDeploy,
 				interfaces.StateMonitor,
 				interfaces.StateScale,
 				interfaces.StatePromote,
+				interfaces.StateAwaitPromotion,
+				interfaces.StatePaused,
 				interfaces.StateRollback,
 			}, Dst: interfaces.StateDestroy},
 		},
 		fsm.Callbacks{
-			"before_event":                       sm.logEvent(),
-			"enter_" + interfaces.StateConfigure: sm.doConfigure(), // do the necessary work to setup the release
-			"enter_" + interfaces.StateDeploy:    sm.doDeploy(),    // new version of the application has been deployed
-			"enter_" + interfaces.StateMonitor:   sm.doMonitor(),   // start monitoring changes in the applications health
-			"enter_" + interfaces.StateScale:     sm.doScale(),     // scale the release
-			"enter_" + interfaces.StatePromote:   sm.doPromote(),   // promote the release to primary
-			"enter_" + interfaces.StateRollback:  sm.doRollback(),  // rollback the deployment
-			"enter_" + interfaces.StateDestroy:   sm.doDestroy(),   // remove everything and revert to vanilla state
-			"enter_state":                        sm.enterState(),
-			"leave_state":                        sm.leaveState(),
+			"before_event":                            sm.logEvent(),
+			"enter_" + interfaces.StateConfigure:      sm.doConfigure(),      // do the necessary work to setup the release
+			"enter_" + interfaces.StatePending:        sm.doPending(),        // wait for the releases that the release depends on
+			"enter_" + interfaces.StateDeploy:         sm.doDeploy(),         // new version of the application has been deployed
+			"enter_" + interfaces.StateMonitor:        sm.doMonitor(),        // start monitoring changes in the applications health
+			"enter_" + interfaces.StateScale:          sm.doScale(),          // scale the release
+			"enter_" + interfaces.StatePromote:        sm.doPromote(),        // promote the release to primary
+			"enter_" + interfaces.StateAwaitPromotion: sm.doAwaitPromotion(), // hold the release until it is manually promoted
+			"enter_" + interfaces.StatePaused:         sm.doPause(),          // freeze the release at the current traffic
+			"leave_" + interfaces.StatePaused:         sm.doResume(),         // notify that a paused release has resumed
+			"enter_" + interfaces.StateRollback:       sm.doRollback(),       // rollback the deployment
+			"enter_" + interfaces.StateDestroy:        sm.doDestroy(),        // remove everything and revert to vanilla state
+			"enter_state":                             sm.enterState(),
+			"leave_state":                             sm.leaveState(),
 		},
 	)
 
@@ -185,9 +321,19 @@ func New(r *models.Release, pluginProvider interfaces.Provider) (*StateMachine,
 // Resume the state machine
 func (s *StateMachine) Resume() error {
 	switch s.CurrentState() {
+	case interfaces.StatePending:
+		// check the dependencies again
+		s.SetState(interfaces.StateIdle)
+		return s.Deploy()
 	case interfaces.StateMonitor:
 		s.SetState(interfaces.StateDeploy)
 		s.Event(interfaces.EventDeployed)
+	case interfaces.StateAwaitPromotion:
+		// traffic has already been scaled, the release waits until it is manually promoted
+		s.logger.Info("Release is awaiting manual promotion", "name", s.release.Name)
+	case interfaces.StatePaused:
+		// continue monitoring from the point that the release was paused
+		return s.Event(interfaces.EventResume)
 	}
 
 	return nil
@@ -198,8 +344,13 @@ func (s *StateMachine) Configure() error {
 	return s.Event(interfaces.EventConfigure)
 }
 
-// Deploy triggers the EventDeploy state
+// Deploy triggers the EventDeploy state, or the EventPending state when the release
+// depends on other releases
 func (s *StateMachine) Deploy() error {
+	if len(s.release.DependsOn) > 0 {
+		return s.Event(interfaces.EventPending)
+	}
+
 	return s.Event(interfaces.EventDeploy)
 }
 
@@ -208,6 +359,28 @@ func (s *StateMachine) Destroy() error {
 	return s.Event(interfaces.EventDestroy)
 }
 
+// Promote triggers the EventPromote state
+func (s *StateMachine) Promote() error {
+	return s.Event(interfaces.EventPromote)
+}
+
+// Pause triggers the EventPause state
+func (s *StateMachine) Pause() error {
+	return s.Event(interfaces.EventPause)
+}
+
+// Abort triggers the EventAbort state
+func (s *StateMachine) Abort() error {
+	return s.Event(interfaces.EventAbort)
+}
+
+// Stop cancels any in-flight work for the current state and any webhooks that are being sent,
+// the state of the release is not changed
+func (s *StateMachine) Stop() {
+	s.cancelState()
+	s.stopCancel()
+}
+
 // CurrentState returns the current state of the machine
 func (s *StateMachine) CurrentState() string {
 	return s.FSM.Current()
@@ -227,12 +400,17 @@ func (s *StateMachine) enterState() func(e *fsm.Event) {
 		s.metricsDone = s.metrics.StateChanged(s.release.Name, e.FSM.Current(), nil)
 
 		// append the state history
+		s.stateLock.Lock()
 		s.release.UpdateState(e.FSM.Current())
 
 		err := s.storage.UpsertRelease(s.release)
+		s.stateLock.Unlock()
+
 		if err != nil {
 			s.logger.Error("Unable to upsert release", "name", s.release.Name, "error", err)
 		}
+
+		s.publish(interfaces.ReleaseEvent{Type: interfaces.ReleaseEventState, Event: e.Event, Message: fmt.Sprintf("%s -> %s", e.Src, e.Dst)})
 	}
 }
 
@@ -240,6 +418,9 @@ func (s *StateMachine) leaveState() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
 		s.logger.Debug("Log state", "event", e.Event, "state", e.FSM.Current())
 
+		// stop any in-flight work for the state that is being left
+		s.cancelState()
+
 		// when we leave the state call the timing done function
 		if s.metricsDone != nil {
 			if e.Err != nil {
@@ -255,7 +436,7 @@ func (s *StateMachine) leaveState() func(e *fsm.Event) {
 func (s *StateMachine) doConfigure() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
 		s.logger.Debug("Configure", "state", e.FSM.Current())
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := s.stateContext(interfaces.StateConfigure)
 
 		go func() {
 			// clean up resources if we finish before timeout
@@ -266,27 +447,16 @@ func (s *StateMachine) doConfigure() func(e *fsm.Event) {
 			if err != nil {
 				s.logger.Error("Configure completed with error", "error", err)
 
-				s.callWebhooks(s.webhookPlugins, "Configure release failed", interfaces.StateConfigure, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Configure release failed", interfaces.StateConfigure, 0, 100, err)
 				return
 			}
 
-			// updating consul configuration is an asynchronous process, it is possible
-			// that a deployment can be removed before the data plane has updated its
-			// configuration. this can cause issues where requests are sent to service instances
-			// that do not exist.
-			//
-			// since we can not exactly determine when the state has converged in the data plane
-			// wait an arbitrary period of time.
-			time.Sleep(stepDelay)
-
 			// if a deployment already exists copy this to the primary
 			status, err := s.runtimePlugin.InitPrimary(ctx, s.release.Name)
 			if err != nil {
 				s.logger.Error("Configure completed with error", "status", status, "error", err)
 
-				s.callWebhooks(s.webhookPlugins, "Configure release failed", interfaces.StateConfigure, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Configure release failed", interfaces.StateConfigure, 0, 100, err)
 				return
 			}
 
@@ -296,61 +466,111 @@ func (s *StateMachine) doConfigure() func(e *fsm.Event) {
 				if err != nil {
 					s.logger.Error("New Primary deployment not healthy", "error", err)
 
-					s.callWebhooks(s.webhookPlugins, "Configure release failed", interfaces.StateConfigure, interfaces.EventFail, 0, 100, err)
-					e.FSM.Event(interfaces.EventFail)
+					s.fail(ctx, e, "Configure release failed", interfaces.StateConfigure, 0, 100, err)
 					return
 				}
 
-				err = s.releaserPlugin.Scale(ctx, 0)
+				err = s.scale(ctx, 0)
 				if err != nil {
 					s.logger.Error("Configure completed with error", "error", err)
 
-					s.callWebhooks(s.webhookPlugins, "Configure release failed", interfaces.StateConfigure, interfaces.EventFail, 0, 100, err)
-					e.FSM.Event(interfaces.EventFail)
+					s.fail(ctx, e, "Configure release failed", interfaces.StateConfigure, 0, 100, err)
 					return
 				}
 
-				// we can't determine when the configuration is synced to the proxy, wait an arbitrary period of time
-				time.Sleep(stepDelay * 4)
-
 				// remove the candidate
 				err = s.runtimePlugin.RemoveCandidate(ctx)
 				if err != nil {
 					s.logger.Error("Configure completed with error", "error", err)
 
-					s.callWebhooks(s.webhookPlugins, "Configure release failed", interfaces.StateConfigure, interfaces.EventFail, 100, 0, err)
-					e.FSM.Event(interfaces.EventFail)
+					s.fail(ctx, e, "Configure release failed", interfaces.StateConfigure, 100, 0, err)
 					return
 				}
 			}
 
 			s.logger.Debug("Configure completed successfully")
 
-			s.callWebhooks(s.webhookPlugins, "Configure release succeeded", interfaces.StateConfigure, interfaces.EventConfigured, 100, 0, nil)
+			s.callWebhooks(ctx, s.webhookPlugins, "Configure release succeeded", interfaces.StateConfigure, interfaces.EventConfigured, 100, 0, nil)
 			e.FSM.Event(interfaces.EventConfigured)
 		}()
 	}
 }
 
+func (s *StateMachine) doPending() func(e *fsm.Event) {
+	return func(e *fsm.Event) {
+		s.logger.Debug("Pending", "state", e.FSM.Current(), "depends_on", s.release.DependsOn)
+		ctx, cancel := s.cancelContext()
+
+		s.startDeployment(false)
+
+		go func() {
+			// pending deployments do not time out, they wait until the dependencies complete,
+			// the release is aborted, or the release is destroyed
+			defer cancel()
+
+			waiting := false
+
+			for {
+				ready, err := CheckDependencies(s.storage, s.release)
+				if err != nil {
+					s.logger.Error("Pending completed with error", "error", err)
+
+					s.fail(ctx, e, "Release dependencies failed", interfaces.StatePending, 100, 0, err)
+					return
+				}
+
+				if ready {
+					s.logger.Debug("Pending completed, dependencies ready")
+
+					e.FSM.Event(interfaces.EventDeploy)
+					return
+				}
+
+				if !waiting {
+					waiting = true
+					s.logger.Info("Release waiting for dependencies", "name", s.release.Name, "depends_on", s.release.DependsOn)
+
+					s.callWebhooks(ctx, s.webhookPlugins, "Release waiting for dependencies", interfaces.StatePending, interfaces.EventWaiting, 100, 0, nil)
+				}
+
+				select {
+				case <-ctx.Done():
+					s.logger.Debug("Pending cancelled")
+					return
+				case <-s.clock.After(dependencyInterval):
+				}
+			}
+		}()
+	}
+}
+
 func (s *StateMachine) doDeploy() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
 		s.logger.Debug("Deploy", "state", e.FSM.Current())
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := s.stateContext(interfaces.StateDeploy)
 
-		go func() {
-			// wait a few seconds as deploy is called before the new deployment is admitted to the server
-			time.Sleep(stepDelay)
+		// every admitted deployment is recorded in the deployment history, pending deployments
+		// are recorded when the deployment is admitted
+		s.startDeployment(e.Src == interfaces.StatePending)
 
+		go func() {
 			// clean up resources if we finish before timeout
 			defer cancel()
 
+			// wait a few seconds as deploy is called before the new deployment is admitted to the server
+			select {
+			case <-ctx.Done():
+				s.logger.Debug("Deploy cancelled")
+				return
+			case <-s.clock.After(stepDelay):
+			}
+
 			// Create a primary if one does not exist
 			status, err := s.runtimePlugin.InitPrimary(ctx, s.release.Name)
 			if err != nil {
 				s.logger.Error("Deploy completed with error", "error", err)
 
-				s.callWebhooks(s.webhookPlugins, "New Deployment failed", interfaces.StateDeploy, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "New Deployment failed", interfaces.StateDeploy, 100, 0, err)
 				return
 			}
 
@@ -358,19 +578,17 @@ func (s *StateMachine) doDeploy() func(e *fsm.Event) {
 			if err != nil {
 				s.logger.Error("Configure completed with error", "error", err)
 
-				s.callWebhooks(s.webhookPlugins, "New Deployment failed", interfaces.StateDeploy, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "New Deployment failed", interfaces.StateDeploy, 100, 0, err)
 				return
 			}
 
 			// now the primary has been created send 100 of traffic there
-			err = s.releaserPlugin.Scale(ctx, 0)
+			err = s.scale(ctx, 0)
 			// work has failed, raise the failed event
 			if err != nil {
 				s.logger.Error("Deploy completed with error", "error", err)
 
-				s.callWebhooks(s.webhookPlugins, "New Deployment failed", interfaces.StateDeploy, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "New Deployment failed", interfaces.StateDeploy, 100, 0, err)
 				return
 			}
 
@@ -378,33 +596,25 @@ func (s *StateMachine) doDeploy() func(e *fsm.Event) {
 			if status == interfaces.RuntimeDeploymentUpdate {
 				s.logger.Debug("Deploy completed, created primary, waiting for next candidate deployment")
 
-				// updating consul configuration is an asynchronous process, it is possible
-				// that a deployment can be removed before the data plane has updated its
-				// configuration. this can cause issues where requests are sent to service instances
-				// that do not exist.
-				//
-				// since we can not exactly determine when the state has converged in the data plane
-				// wait an arbitrary period of time.
-				time.Sleep(stepDelay * 4)
-
 				// remove the candidate and wait for the next deployment
 				err = s.runtimePlugin.RemoveCandidate(ctx)
 				if err != nil {
 					s.logger.Error("Deploy completed with error", "error", err)
 
-					s.callWebhooks(s.webhookPlugins, "New deployment failed", interfaces.StateDeploy, interfaces.EventFail, 100, 0, err)
-					e.FSM.Event(interfaces.EventFail)
+					s.fail(ctx, e, "New deployment failed", interfaces.StateDeploy, 100, 0, err)
 					return
 				}
 
-				s.callWebhooks(s.webhookPlugins, "New deployment succeeded", interfaces.StateDeploy, interfaces.EventComplete, 100, 0, nil)
+				s.endDeployment(models.DeploymentOutcomePromoted, nil)
+
+				s.callWebhooks(ctx, s.webhookPlugins, "New deployment succeeded", interfaces.StateDeploy, interfaces.EventComplete, 100, 0, nil)
 				e.FSM.Event(interfaces.EventComplete)
 				return
 			}
 
 			// new deployment run the strategy
 			s.logger.Debug("Deploy completed, executing strategy")
-			s.callWebhooks(s.webhookPlugins, "New deployment succeeded, executing strategy", interfaces.StateDeploy, interfaces.EventDeployed, 100, 0, nil)
+			s.callWebhooks(ctx, s.webhookPlugins, "New deployment succeeded, executing strategy", interfaces.StateDeploy, interfaces.EventDeployed, 100, 0, nil)
 			e.FSM.Event(interfaces.EventDeployed)
 		}()
 	}
@@ -413,9 +623,20 @@ func (s *StateMachine) doDeploy() func(e *fsm.Event) {
 func (s *StateMachine) doMonitor() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
 		s.logger.Debug("Monitor", "state", e.FSM.Current())
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		sctx, scancel := s.cancelContext()
 
 		go func() {
+			defer scancel()
+
+			// hold the release until the schedule allows changes, waiting does not count
+			// towards the monitor timeout
+			if !s.waitForSchedule(sctx) {
+				s.logger.Debug("Monitor cancelled while waiting for the release schedule")
+				return
+			}
+
+			ctx, cancel := s.clock.WithTimeout(sctx, s.timeout(interfaces.StateMonitor))
+
 			// clean up resources if we finish before timeout
 			defer cancel()
 
@@ -424,11 +645,19 @@ func (s *StateMachine) doMonitor() func(e *fsm.Event) {
 				s.logger.Debug("Executing post deployment tests")
 				err := s.testPlugin.Execute(ctx, s.runtimePlugin.BaseState().CandidateName)
 
+				// the release was paused or aborted while the tests were running
+				if ctx.Err() == context.Canceled {
+					s.logger.Debug("Post deployment tests cancelled")
+					return
+				}
+
 				if err != nil {
 					// post deployment tests have failed rollback
 					s.logger.Error("Post deployment tests completed with error", "error", err)
+					s.recordFailure(err)
 
 					s.callWebhooks(
+						sctx,
 						s.webhookPlugins,
 						"post deployment tests failed",
 						interfaces.StateMonitor,
@@ -445,21 +674,80 @@ func (s *StateMachine) doMonitor() func(e *fsm.Event) {
 
 			result, traffic, err := s.strategyPlugin.Execute(ctx, s.runtimePlugin.BaseState().CandidateName)
 
+			// the release was paused, aborted, or destroyed while the strategy was executing, the
+			// strategy does not change its progress when cancelled
+			if ctx.Err() == context.Canceled {
+				s.logger.Debug("Monitor cancelled")
+				return
+			}
+
+			// the strategy requires intervention before the release can continue, pause the release
+			// and send the reason with the event so that it can be used in the alert
+			if result == interfaces.StrategyStatusPaused {
+				s.logger.Info("Monitor checks paused, release requires intervention", "error", err)
+
+				e.FSM.Event(interfaces.EventPause, err)
+				return
+			}
+
 			// strategy has failed with an error
 			if err != nil {
 				s.logger.Error("Monitor completed with error", "error", err)
 
-				s.callWebhooks(
-					s.webhookPlugins,
+				s.fail(
+					ctx,
+					e,
 					"Monitoring deployment failed",
 					interfaces.StateMonitor,
-					interfaces.EventFail,
 					s.strategyPlugin.GetPrimaryTraffic(),
 					s.strategyPlugin.GetCandidateTraffic(),
 					err,
 				)
 
-				e.FSM.Event(interfaces.EventFail)
+				return
+			}
+
+			// the schedule may have closed while the strategy was checking the candidate, wait
+			// before changing the candidate traffic
+			if result != interfaces.StrategyStatusFailed && !s.waitForSchedule(sctx) {
+				s.logger.Debug("Monitor cancelled while waiting for the release schedule")
+				return
+			}
+
+			// gates approve the release before the first traffic is sent to the candidate and
+			// before the candidate is promoted, waiting does not count towards the monitor timeout
+			stage := ""
+			switch {
+			case result == interfaces.StrategyStatusSuccess && s.getCandidateTraffic() == 0:
+				stage = models.GateStageTraffic
+			case result == interfaces.StrategyStatusComplete, result == interfaces.StrategyStatusAwaitingPromotion:
+				stage = models.GateStagePromote
+			}
+
+			if stage != "" {
+				approved, err := s.waitForGates(sctx, stage)
+				if err != nil {
+					s.recordFailure(err)
+
+					s.callWebhooks(
+						sctx,
+						s.webhookPlugins,
+						"Release rejected by gate",
+						interfaces.StateMonitor,
+						interfaces.EventUnhealthy,
+						s.strategyPlugin.GetPrimaryTraffic(),
+						s.strategyPlugin.GetCandidateTraffic(),
+						err,
+					)
+
+					e.FSM.Event(interfaces.EventUnhealthy)
+					return
+				}
+
+				if !approved {
+					s.logger.Debug("Monitor cancelled while waiting for gates")
+					return
+				}
 			}
 
 			// strategy returned a response
@@ -477,11 +765,25 @@ func (s *StateMachine) doMonitor() func(e *fsm.Event) {
 
 				e.FSM.Event(interfaces.EventComplete)
 
+			// the strategy has completed the roll out but the release must be promoted manually
+			case interfaces.StrategyStatusAwaitingPromotion:
+				s.logger.Debug("Monitor checks completed, strategy awaiting manual promotion")
+
+				e.FSM.Event(interfaces.EventAwaitPromotion, traffic)
+
 			// the strategy has reported that the deployment is unhealthy, rollback
 			case interfaces.StrategyStatusFailed:
 				s.logger.Debug("Monitor checks completed, candidate unhealthy")
 
+				reason := err
+				if reason == nil {
+					reason = fmt.Errorf("candidate failed the strategy checks")
+				}
+
+				s.recordFailure(reason)
+
 				s.callWebhooks(
+					sctx,
 					s.webhookPlugins,
 					"Monitor deployment failed",
 					interfaces.StateMonitor,
@@ -500,7 +802,7 @@ func (s *StateMachine) doMonitor() func(e *fsm.Event) {
 func (s *StateMachine) doScale() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
 		s.logger.Debug("Scale", "state", e.FSM.Current())
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := s.stateContext(interfaces.StateScale)
 
 		go func() {
 			// clean up resources if we finish before timeout
@@ -516,27 +818,34 @@ func (s *StateMachine) doScale() func(e *fsm.Event) {
 
 			traffic := e.Args[0].(int)
 
-			err := s.releaserPlugin.Scale(ctx, traffic)
+			err := s.scale(ctx, traffic)
+
+			// strategies that route requests based on their attributes need the routes to the candidate
+			if rs, ok := s.strategyPlugin.(interfaces.RoutingStrategy); ok && err == nil {
+				err = s.releaserPlugin.Route(ctx, rs.GetCandidateRoutes())
+			}
+
 			if err != nil {
 				s.logger.Error("Scale completed with error", "error", err)
 
-				s.callWebhooks(
-					s.webhookPlugins,
+				s.fail(
+					ctx,
+					e,
 					"Scaling deployment failed",
 					interfaces.StateMonitor,
-					interfaces.EventFail,
 					s.strategyPlugin.GetPrimaryTraffic(),
 					s.strategyPlugin.GetCandidateTraffic(),
 					err,
 				)
 
-				e.FSM.Event(interfaces.EventFail)
 				return
 			}
 
 			s.logger.Debug("Scale completed successfully")
+			s.recordTraffic(traffic)
 
 			s.callWebhooks(
+				ctx,
 				s.webhookPlugins,
 				"Scaling deployment succeeded",
 				interfaces.StateMonitor,
@@ -551,37 +860,64 @@ func (s *StateMachine) doScale() func(e *fsm.Event) {
 	}
 }
 
+func (s *StateMachine) doAwaitPromotion() func(e *fsm.Event) {
+	return func(e *fsm.Event) {
+		s.logger.Debug("Await promotion", "state", e.FSM.Current())
+		ctx, cancel := s.stateContext(interfaces.StateAwaitPromotion)
+
+		go func() {
+			// clean up resources if we finish before timeout
+			defer cancel()
+
+			// get the traffic from the event
+			if len(e.Args) != 1 {
+				s.logger.Error("Await promotion completed with error", "error", fmt.Errorf("no traffic percentage in event payload"))
+
+				e.FSM.Event(interfaces.EventFail)
+				return
+			}
+
+			traffic := e.Args[0].(int)
+
+			// hold the candidate at the final traffic split until the release is promoted
+			err := s.scale(ctx, traffic)
+			if err != nil {
+				s.logger.Error("Await promotion completed with error", "error", err)
+
+				s.fail(ctx, e, "Scaling deployment failed", interfaces.StateAwaitPromotion, 100-traffic, traffic, err)
+				return
+			}
+
+			s.logger.Info("Release awaiting manual promotion", "name", s.release.Name, "traffic", traffic)
+			s.recordTraffic(traffic)
+
+			s.callWebhooks(ctx, s.webhookPlugins, "Candidate awaiting manual promotion", interfaces.StateAwaitPromotion, interfaces.EventAwaitPromotion, 100-traffic, traffic, nil)
+		}()
+	}
+}
+
 func (s *StateMachine) doPromote() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
 		s.logger.Debug("Promote", "state", e.FSM.Current())
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := s.stateContext(interfaces.StatePromote)
 
 		go func() {
 			// clean up resources if we finish before timeout
 			defer cancel()
 
 			// scale all traffic to the candidate before promoting
-			err := s.releaserPlugin.Scale(ctx, 100)
+			err := s.scale(ctx, 100)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Promoting candidate failed", interfaces.StatePromote, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Promoting candidate failed", interfaces.StatePromote, 0, 100, err)
 				return
 			}
 
-			// updating consul configuration is an asynchronous process, it is possible
-			// that a deployment can be removed before the data plane has updated its
-			// configuration. this can cause issues where requests are sent to service instances
-			// that do not exist.
-			//
-			// since we can not exactly determine when the state has converged in the data plane
-			// wait an arbitrary period of time.
-			time.Sleep(stepDelay)
+			s.recordTraffic(100)
 
 			// promote the candidate to primary
 			_, err = s.runtimePlugin.PromoteCandidate(ctx)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Promoting candidate failed", interfaces.StatePromote, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Promoting candidate failed", interfaces.StatePromote, 0, 100, err)
 				return
 			}
 
@@ -590,30 +926,33 @@ func (s *StateMachine) doPromote() func(e *fsm.Event) {
 			if err != nil {
 				s.logger.Error("Promote completed with error", "error", err)
 
-				s.callWebhooks(s.webhookPlugins, "Promoting candidate failed", interfaces.StatePromote, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Promoting candidate failed", interfaces.StatePromote, 0, 100, err)
 				return
 			}
 
 			// scale all traffic to the primary
-			err = s.releaserPlugin.Scale(ctx, 0)
+			err = s.scale(ctx, 0)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Promoting candidate failed", interfaces.StatePromote, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Promoting candidate failed", interfaces.StatePromote, 0, 100, err)
 				return
 			}
 
-			time.Sleep(stepDelay * 4)
+			err = s.removeCandidateRoutes(ctx)
+			if err != nil {
+				s.fail(ctx, e, "Promoting candidate failed", interfaces.StatePromote, 100, 0, err)
+				return
+			}
 
 			// scale down the canary
 			err = s.runtimePlugin.RemoveCandidate(ctx)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Promoting candidate failed", interfaces.StatePromote, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Promoting candidate failed", interfaces.StatePromote, 100, 0, err)
 				return
 			}
 
-			s.callWebhooks(s.webhookPlugins, "Promoting candidate to primary succeeded", interfaces.StatePromote, interfaces.EventPromoted, 100, 0, err)
+			s.endDeployment(models.DeploymentOutcomePromoted, nil)
+
+			s.callWebhooks(ctx, s.webhookPlugins, "Promoting candidate to primary succeeded", interfaces.StatePromote, interfaces.EventPromoted, 100, 0, err)
 			e.FSM.Event(interfaces.EventPromoted)
 		}()
 	}
@@ -622,21 +961,38 @@ func (s *StateMachine) doPromote() func(e *fsm.Event) {
 func (s *StateMachine) doRollback() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
 		s.logger.Debug("Rollback", "state", e.FSM.Current())
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := s.stateContext(interfaces.StateRollback)
 
 		go func() {
 			// clean up resources if we finish before timeout
 			defer cancel()
-			// scale all traffic to the primary
-			err := s.releaserPlugin.Scale(ctx, 0)
-			if err != nil {
-				e.FSM.Event(interfaces.EventFail)
+
+			if e.Event == interfaces.EventAbort {
+				s.logger.Info("Release aborted", "name", s.release.Name)
 
 				s.callWebhooks(
+					ctx,
 					s.webhookPlugins,
+					"Release aborted, rolling back deployment",
+					interfaces.StateRollback,
+					interfaces.EventAbort,
+					s.strategyPlugin.GetPrimaryTraffic(),
+					s.strategyPlugin.GetCandidateTraffic(),
+					nil,
+				)
+			}
+
+			// ensure the next deployment starts a new roll out
+			s.strategyPlugin.Reset()
+
+			// scale all traffic to the primary
+			err := s.scale(ctx, 0)
+			if err != nil {
+				s.fail(
+					ctx,
+					e,
 					"Rolling back deployment failed",
 					interfaces.StateRollback,
-					interfaces.EventFail,
 					s.strategyPlugin.GetPrimaryTraffic(),
 					s.strategyPlugin.GetCandidateTraffic(),
 					err,
@@ -645,24 +1001,26 @@ func (s *StateMachine) doRollback() func(e *fsm.Event) {
 				return
 			}
 
-			// updating consul configuration is an asynchronous process, it is possible
-			// that a deployment can be removed before the data plane has updated its
-			// configuration. this can cause issues where requests are sent to service instances
-			// that do not exist.
-			//
-			// since we can not exactly determine when the state has converged in the data plane
-			// wait an arbitrary period of time.
-			time.Sleep(stepDelay * 4)
+			err = s.removeCandidateRoutes(ctx)
+			if err != nil {
+				s.fail(ctx, e, "Rolling back deployment failed", interfaces.StateRollback, 100, 0, err)
+				return
+			}
 
 			// scale down the canary
 			err = s.runtimePlugin.RemoveCandidate(ctx)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Rolling back deployment failed", interfaces.StateRollback, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Rolling back deployment failed", interfaces.StateRollback, 100, 0, err)
 				return
 			}
 
-			s.callWebhooks(s.webhookPlugins, "Deployment rolled back", interfaces.StateRollback, interfaces.EventComplete, 100, 0, err)
+			if e.Event == interfaces.EventAbort {
+				s.endDeployment(models.DeploymentOutcomeAborted, nil)
+			} else {
+				s.endDeployment(models.DeploymentOutcomeRolledBack, nil)
+			}
+
+			s.callWebhooks(ctx, s.webhookPlugins, "Deployment rolled back", interfaces.StateRollback, interfaces.EventComplete, 100, 0, err)
 			e.FSM.Event(interfaces.EventComplete)
 		}()
 	}
@@ -670,17 +1028,19 @@ func (s *StateMachine) doRollback() func(e *fsm.Event) {
 
 func (s *StateMachine) doDestroy() func(e *fsm.Event) {
 	return func(e *fsm.Event) {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		ctx, cancel := s.stateContext(interfaces.StateDestroy)
 		s.logger.Debug("Destroy", "state", e.FSM.Current())
 
+		// the release can be destroyed while a deployment is in progress
+		s.endDeployment(models.DeploymentOutcomeDestroyed, nil)
+
 		go func() {
 			// clean up resources if we finish before timeout
 			defer cancel()
 			// restore the original deployment
 			err := s.runtimePlugin.RestoreOriginal(ctx)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Remove release failed", interfaces.StateDestroy, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Remove release failed", interfaces.StateDestroy, 100, 0, err)
 				return
 			}
 
@@ -689,61 +1049,332 @@ func (s *StateMachine) doDestroy() func(e *fsm.Event) {
 			if err != nil {
 				s.logger.Error("Configure completed with error", "error", err)
 
-				s.callWebhooks(s.webhookPlugins, "Remove release failed", interfaces.StateDestroy, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Remove release failed", interfaces.StateDestroy, 100, 0, err)
 				return
 			}
 
 			// scale all traffic to the candidate
-			err = s.releaserPlugin.Scale(ctx, 100)
+			err = s.scale(ctx, 100)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Remove release failed", interfaces.StateDestroy, interfaces.EventFail, 100, 0, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Remove release failed", interfaces.StateDestroy, 100, 0, err)
 				return
 			}
 
-			// updating consul configuration is an asynchronous process, it is possible
-			// that a deployment can be removed before the data plane has updated its
-			// configuration. this can cause issues where requests are sent to service instances
-			// that do not exist.
-			//
-			// since we can not exactly determine when the state has converged in the data plane
-			// wait an arbitrary period of time.
-			time.Sleep(stepDelay * 4)
-
 			// destroy the primary
 			err = s.runtimePlugin.RemovePrimary(ctx)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Remove release failed", interfaces.StateDestroy, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Remove release failed", interfaces.StateDestroy, 0, 100, err)
 				return
 			}
 
 			// remove the consul config
 			err = s.releaserPlugin.Destroy(ctx)
 			if err != nil {
-				s.callWebhooks(s.webhookPlugins, "Remove release failed", interfaces.StateDestroy, interfaces.EventFail, 0, 100, err)
-				e.FSM.Event(interfaces.EventFail)
+				s.fail(ctx, e, "Remove release failed", interfaces.StateDestroy, 0, 100, err)
 				return
 			}
 
-			s.callWebhooks(s.webhookPlugins, "Remove release succeeded", interfaces.StateDestroy, interfaces.EventComplete, 0, 100, err)
+			s.callWebhooks(ctx, s.webhookPlugins, "Remove release succeeded", interfaces.StateDestroy, interfaces.EventComplete, 0, 100, err)
 			e.FSM.Event(interfaces.EventComplete)
 		}()
 	}
 }
 
+func (s *StateMachine) doPause() func(e *fsm.Event) {
+	return func(e *fsm.Event) {
+		s.logger.Debug("Pause", "state", e.FSM.Current())
+
+		// the strategy can pause the release, the reason is sent with the event
+		var reason error
+		if len(e.Args) == 1 {
+			reason, _ = e.Args[0].(error)
+		}
+
+		// the webhooks are cancelled when the release leaves the paused state
+		ctx, cancel := s.cancelContext()
+
+		go func() {
+			defer cancel()
+
+			s.logger.Info("Release paused", "name", s.release.Name, "traffic", s.strategyPlugin.GetCandidateTraffic(), "reason", reason)
+
+			title := "Release paused"
+			if reason != nil {
+				title = "Release paused, monitoring requires intervention"
+			}
+
+			s.callWebhooks(
+				ctx,
+				s.webhookPlugins,
+				title,
+				interfaces.StatePaused,
+				interfaces.EventPause,
+				s.strategyPlugin.GetPrimaryTraffic(),
+				s.strategyPlugin.GetCandidateTraffic(),
+				reason,
+			)
+		}()
+	}
+}
+
+func (s *StateMachine) doResume() func(e *fsm.Event) {
+	return func(e *fsm.Event) {
+		// a paused release can also be aborted or destroyed
+		if e.Event != interfaces.EventResume {
+			return
+		}
+
+		s.logger.Debug("Resume", "state", e.FSM.Current())
+
+		go func() {
+			s.logger.Info("Release resumed", "name", s.release.Name, "traffic", s.strategyPlugin.GetCandidateTraffic())
+
+			// the release has left the paused state so the webhooks can only be cancelled by
+			// stopping the statemachine
+			s.callWebhooks(
+				s.stopContext,
+				s.webhookPlugins,
+				"Release resumed",
+				interfaces.StateMonitor,
+				interfaces.EventResume,
+				s.strategyPlugin.GetPrimaryTraffic(),
+				s.strategyPlugin.GetCandidateTraffic(),
+				nil,
+			)
+		}()
+	}
+}
+
+// stateContext returns a context for the work in the given state, the context times out after the
+// configured timeout for the state and is cancelled when the release leaves the state
+func (s *StateMachine) stateContext(state string) (context.Context, context.CancelFunc) {
+	ctx, cancel := s.clock.WithTimeout(context.Background(), s.timeout(state))
+
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	s.stateCancel = cancel
+
+	return ctx, cancel
+}
+
+// cancelContext returns a context that is cancelled when the release leaves the current state,
+// unlike stateContext the context does not time out
+func (s *StateMachine) cancelContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	s.stateCancel = cancel
+
+	return ctx, cancel
+}
+
+// cancelState cancels any in-flight work for the current state
+func (s *StateMachine) cancelState() {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	if s.stateCancel != nil {
+		s.stateCancel()
+		s.stateCancel = nil
+	}
+}
+
+// timeout returns the configured timeout for the given state, or the default timeout
+// when the release does not configure a timeout for the state
+func (s *StateMachine) timeout(state string) time.Duration {
+	t := s.release.Timeouts
+	if t == nil {
+		return defaultTimeout
+	}
+
+	value := ""
+
+	switch state {
+	case interfaces.StateConfigure:
+		value = t.Configure
+	case interfaces.StateDeploy:
+		value = t.Deploy
+	case interfaces.StateMonitor:
+		value = t.Monitor
+	case interfaces.StateScale, interfaces.StateAwaitPromotion:
+		value = t.Scale
+	case interfaces.StatePromote:
+		value = t.Promote
+	case interfaces.StateRollback:
+		value = t.Rollback
+	case interfaces.StateDestroy:
+		value = t.Destroy
+	}
+
+	if value == "" {
+		return defaultTimeout
+	}
+
+	// timeouts are validated when the statemachine is created
+	d, _ := time.ParseDuration(value)
+
+	return d
+}
+
+// fail sends the webhooks and fires the fail event when the work for the given state returns an error.
+// When the work for the state timed out the timeout outcome is sent to the webhooks and the timeout event
+// is fired, when the work was cancelled because the release has left the state no event is fired
+func (s *StateMachine) fail(ctx context.Context, e *fsm.Event, title, state string, primaryTraffic, candidateTraffic int, err error) {
+	switch ctx.Err() {
+	case context.Canceled:
+		s.logger.Debug("State cancelled", "state", state, "error", err)
+		return
+
+	case context.DeadlineExceeded:
+		s.logger.Error("State timed out", "state", state, "timeout", s.timeout(state).String(), "error", err)
+		s.endDeployment(models.DeploymentOutcomeTimedOut, err)
+
+		// the state context has expired, send the webhooks with a context that is cancelled
+		// when the release leaves the state
+		wctx, cancel := s.cancelContext()
+		defer cancel()
+
+		s.callWebhooks(wctx, s.webhookPlugins, title+", timed out", state, interfaces.EventTimeout, primaryTraffic, candidateTraffic, err)
+		e.FSM.Event(interfaces.EventTimeout)
+		return
+	}
+
+	s.endDeployment(models.DeploymentOutcomeFailed, err)
+
+	s.callWebhooks(ctx, s.webhookPlugins, title, state, interfaces.EventFail, primaryTraffic, candidateTraffic, err)
+	e.FSM.Event(interfaces.EventFail)
+}
+
+// waitForSchedule blocks until the release schedule allows changes to the candidate traffic,
+// webhooks are called when the release starts waiting. Returns false when the context is
+// cancelled before the schedule allows changes
+func (s *StateMachine) waitForSchedule(ctx context.Context) bool {
+	waiting := false
+
+	for {
+		err := s.release.Schedule.Check(s.clock.Now())
+		if err == nil {
+			if waiting {
+				s.logger.Info("Release schedule allows changes, continuing release", "name", s.release.Name)
+			}
+
+			return true
+		}
+
+		if !waiting {
+			waiting = true
+			s.logger.Info("Release schedule does not allow changes, waiting", "name", s.release.Name, "reason", err)
+
+			s.callWebhooks(
+				ctx,
+				s.webhookPlugins,
+				"Release waiting for the deployment schedule",
+				interfaces.StateMonitor,
+				interfaces.EventWaiting,
+				s.strategyPlugin.GetPrimaryTraffic(),
+				s.strategyPlugin.GetCandidateTraffic(),
+				err,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.clock.After(scheduleInterval):
+		}
+	}
+}
+
+// startDeployment creates the in progress deployment for the current candidate and clears the gate
+// decisions received for a previous deployment, when keepExisting is true an existing in progress
+// deployment and its decisions are not replaced
+func (s *StateMachine) startDeployment(keepExisting bool) {
+	bs := s.runtimePlugin.BaseState()
+
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	if keepExisting && s.release.Deployment != nil {
+		return
+	}
+
+	s.gateDecisions = map[string]interfaces.GateResponse{}
+	s.release.Deployment = models.NewDeployment(bs.CandidateName, bs.CandidateVersion, s.clock.Now())
+}
+
+// recordTraffic adds the traffic sent to the candidate to the in progress deployment
+func (s *StateMachine) recordTraffic(traffic int) {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	if s.release.Deployment == nil {
+		return
+	}
+
+	s.release.Deployment.AddTrafficStep(traffic, s.clock.Now())
+}
+
+// recordFailure sets the reason that the in progress deployment is being rolled back
+func (s *StateMachine) recordFailure(err error) {
+	s.stateLock.Lock()
+	defer s.stateLock.Unlock()
+
+	if s.release.Deployment == nil {
+		return
+	}
+
+	s.release.Deployment.FailureReason = err.Error()
+}
+
+// endDeployment sets the outcome of the in progress deployment and adds it to the deployment
+// history, the release is saved with the next state change
+func (s *StateMachine) endDeployment(outcome string, err error) {
+	s.stateLock.Lock()
+
+	d := s.release.Deployment
+	if d == nil {
+		s.stateLock.Unlock()
+		return
+	}
+
+	d.End(outcome, err, s.clock.Now())
+	s.release.Deployment = nil
+
+	s.stateLock.Unlock()
+
+	s.logger.Info("Deployment finished", "name", s.release.Name, "candidate", d.CandidateName, "version", d.CandidateVersion, "outcome", outcome)
+
+	aerr := s.storage.AppendDeployment(s.release.Name, d)
+	if aerr != nil {
+		s.logger.Error("Unable to append deployment history", "name", s.release.Name, "error", aerr)
+	}
+}
+
+// removeCandidateRoutes removes any routes that send requests to the candidate, routes are only
+// created when the strategy is a RoutingStrategy
+func (s *StateMachine) removeCandidateRoutes(ctx context.Context) error {
+	if _, ok := s.strategyPlugin.(interfaces.RoutingStrategy); !ok {
+		return nil
+	}
+
+	return s.releaserPlugin.Route(ctx, nil)
+}
+
 // callWebhooks calls the defined webhooks, in the event of failure this function will log an error
-// but does not interupt flow
-func (s *StateMachine) callWebhooks(wh []interfaces.Webhook, title, state, result string, primaryTraffic, candidateTraffic int, err error) {
+// but does not interupt flow. Retries made by the webhooks stop when the context is cancelled. The
+// message is also published to the release event subscribers
+func (s *StateMachine) callWebhooks(ctx context.Context, wh []interfaces.Webhook, title, state, result string, primaryTraffic, candidateTraffic int, err error) {
+	errString := ""
+	if err != nil {
+		errString = err.Error()
+	}
+
 	for _, w := range wh {
 		s.logger.Debug("Calling webhook", "title", title)
 
-		errString := ""
-		if err != nil {
-			errString = err.Error()
-		}
-
 		message := interfaces.WebhookMessage{
 			Title:            title,
 			Name:             s.release.Name,
@@ -755,9 +1386,15 @@ func (s *StateMachine) callWebhooks(wh []interfaces.Webhook, title, state, resul
 			Error:            errString,
 		}
 
-		err := w.Send(message)
+		if s.monitors != nil {
+			message.Monitors = s.monitors.Results()
+		}
+
+		err := w.Send(ctx, message)
 		if err != nil {
 			s.logger.Error("Unable to call webhook", "title", title, "error", err)
 		}
 	}
+
+	s.publish(interfaces.ReleaseEvent{Type: interfaces.ReleaseEventWebhook, Result: result, Message: title, Error: errString})
 }
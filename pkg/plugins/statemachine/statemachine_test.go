@@ -2,11 +2,13 @@ package statemachine
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"testing"
 	"time"
 
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
 	"github.com/nicholasjackson/consul-release-controller/pkg/models"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
 	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
@@ -15,9 +17,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func setupTests(t *testing.T) (*models.Release, *StateMachine, *mocks.Mocks) {
+func init() {
+	// set once, goroutines from earlier tests can still be reading the delay
 	stepDelay = 1 * time.Millisecond
+}
 
+func setupTests(t *testing.T) (*models.Release, *StateMachine, *mocks.Mocks) {
 	pp, pm := mocks.BuildMocks(t)
 	r := &models.Release{}
 	data := bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))
@@ -48,13 +53,67 @@ func setupTests(t *testing.T) (*models.Release, *StateMachine, *mocks.Mocks) {
 		if t.Failed() {
 			fmt.Println(pm.LogBuffer.String())
 		}
+
+		// the mock strategy always returns success, stop any running rollout from looping
+		// between monitor and scale once the test has completed
+		sm.SetState(interfaces.StateIdle)
+		sm.Stop()
 	})
 
 	return r, sm, pm
 }
 
-func historyContains(r *models.Release, state string) bool {
-	for _, s := range r.StateHistory() {
+func setupRoutingStrategy(routes []interfaces.RouteMatch) *mocks.RoutingStrategyMock {
+	rs := &mocks.RoutingStrategyMock{}
+	rs.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusFailing, 0, nil)
+	rs.On("GetPrimaryTraffic").Return(100)
+	rs.On("GetCandidateTraffic").Return(0)
+	rs.On("GetCandidateRoutes").Return(routes)
+	rs.On("Reset").Return()
+
+	return rs
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// quietT is used to assert mock calls without failing the test
+type quietT struct{}
+
+func (q quietT) Logf(format string, args ...interface{})   {}
+func (q quietT) Errorf(format string, args ...interface{}) {}
+func (q quietT) FailNow()                                  {}
+
+// calledWith returns true when the method of the mock has been called with the arguments, unlike
+// reading the Calls of the mock directly it is safe while the statemachine is calling the mock
+func calledWith(m *mock.Mock, method string, args ...interface{}) bool {
+	return m.AssertCalled(quietT{}, method, args...)
+}
+
+// appendedDeployment returns the last deployment that was appended to the deployment history
+func appendedDeployment(m *mocks.StoreMock) *models.Deployment {
+	var d *models.Deployment
+	calledWith(&m.Mock, "AppendDeployment", mock.Anything, mock.MatchedBy(func(ad *models.Deployment) bool {
+		d = ad
+		return true
+	}))
+
+	return d
+}
+
+// historyContains returns true when the release has entered the state, the history is read
+// under the state lock as it is changed by the statemachine
+func historyContains(sm *StateMachine, state string) bool {
+	sm.stateLock.Lock()
+	defer sm.stateLock.Unlock()
+
+	for _, s := range sm.release.StateHistory() {
 		if s.State == state {
 			return true
 		}
@@ -64,7 +123,7 @@ func historyContains(r *models.Release, state string) bool {
 }
 
 func TestEventConfigureWithSetupErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Setup")
 	pm.ReleaserMock.On("Setup", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
@@ -72,13 +131,13 @@ func TestEventConfigureWithSetupErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateStart)
 	sm.Event(interfaces.EventConfigure)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Setup", mock.Anything, mock.Anything, mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventConfigureWithInitErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "InitPrimary")
 	pm.RuntimeMock.On("InitPrimary", mock.Anything, mock.Anything).Return(interfaces.RuntimeDeploymentInternalError, fmt.Errorf("boom"))
@@ -86,15 +145,15 @@ func TestEventConfigureWithInitErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateStart)
 	sm.Event(interfaces.EventConfigure)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Setup", mock.Anything, mock.Anything, mock.Anything)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.RuntimeMock.AssertNotCalled(t, "WaitUntilServiceHealthy", mock.Anything, pm.RuntimeMock.PrimarySubsetFilter())
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventConfigureWithHealthCheckErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "WaitUntilServiceHealthy")
 	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
@@ -102,16 +161,16 @@ func TestEventConfigureWithHealthCheckErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateStart)
 	sm.Event(interfaces.EventConfigure)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Setup", mock.Anything, mock.Anything, mock.Anything)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "WaitUntilServiceHealthy", mock.Anything, pm.RuntimeMock.PrimarySubsetFilter())
 	pm.ReleaserMock.AssertNotCalled(t, "Scale", mock.Anything, mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventConfigureWithScaleErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
 	pm.ReleaserMock.On("Scale", mock.Anything, 0).Return(fmt.Errorf("boom"))
@@ -119,15 +178,15 @@ func TestEventConfigureWithScaleErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateStart)
 	sm.Event(interfaces.EventConfigure)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Setup", mock.Anything, mock.Anything, mock.Anything)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventConfigureWithRemoveErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "RemoveCandidate")
 	pm.RuntimeMock.On("RemoveCandidate", mock.Anything).Return(fmt.Errorf("boom"))
@@ -135,31 +194,31 @@ func TestEventConfigureWithRemoveErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateStart)
 	sm.Event(interfaces.EventConfigure)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Setup", mock.Anything, mock.Anything, mock.Anything)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventConfigureWithNoErrorSetsStatusIdle(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	sm.SetState(interfaces.StateStart)
 	sm.Event(interfaces.EventConfigure)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateIdle) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Setup", mock.Anything, mock.Anything, mock.Anything)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
 
 	// ensure webhook dispatched
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployWithInitErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "InitPrimary")
 	pm.RuntimeMock.On("InitPrimary", mock.Anything, mock.Anything).Return(interfaces.RuntimeDeploymentInternalError, fmt.Errorf("boom"))
@@ -167,13 +226,13 @@ func TestEventDeployWithInitErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDeploy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployWithHealthCheckErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "WaitUntilServiceHealthy")
 	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
@@ -181,15 +240,15 @@ func TestEventDeployWithHealthCheckErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDeploy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "WaitUntilServiceHealthy", mock.Anything, pm.RuntimeMock.PrimarySubsetFilter())
 	pm.ReleaserMock.AssertNotCalled(t, "Scale", mock.Anything, mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployWithScaleErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
 	pm.ReleaserMock.On("Scale", mock.Anything, 0).Return(fmt.Errorf("boom"))
@@ -197,14 +256,14 @@ func TestEventDeployWithScaleErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDeploy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployWithRemoveErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "RemoveCandidate")
 	pm.RuntimeMock.On("RemoveCandidate", mock.Anything).Return(fmt.Errorf("boom"))
@@ -212,15 +271,128 @@ func TestEventDeployWithRemoveErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDeploy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
-func TestEventDeployWithNoPrimarySetsStatusMonitor(t *testing.T) {
+func TestNewWithInvalidTimeoutReturnsError(t *testing.T) {
+	pp, _ := mocks.BuildMocks(t)
+
+	r := &models.Release{}
+	data := bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))
+	r.FromJsonBody(ioutil.NopCloser(data))
+	r.Timeouts = &models.Timeouts{Deploy: "abc"}
+
+	_, err := New(r, pp)
+	require.Error(t, err)
+}
+
+func TestEventDeployWithTimeoutSetsStatusFailAndCallsWebhook(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.Timeouts = &models.Timeouts{Deploy: "20ms"}
+
+	// block until the deploy times out
+	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "WaitUntilServiceHealthy")
+	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(context.DeadlineExceeded)
+
+	sm.SetState(interfaces.StateIdle)
+	sm.Event(interfaces.EventDeploy)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, 200*time.Millisecond, 1*time.Millisecond)
+	require.Equal(t, models.DeploymentOutcomeTimedOut, appendedDeployment(pm.StoreMock).Outcome)
+
+	require.Eventually(t, func() bool {
+		return calledWith(&pm.WebhookMock.Mock, "Send", mock.Anything, mock.MatchedBy(func(msg interfaces.WebhookMessage) bool {
+			return msg.State == interfaces.StateDeploy && msg.Outcome == interfaces.EventTimeout
+		}))
+	}, 100*time.Millisecond, 1*time.Millisecond)
+}
+
+func TestEventDeployWithVirtualClockTimesOutWhenClockPassesTimeout(t *testing.T) {
 	r, sm, pm := setupTests(t)
+	r.Timeouts = &models.Timeouts{Deploy: "10m"}
+
+	c := clock.NewVirtual(time.Now())
+	sm.clock = c
+
+	// the deploy waits longer than the timeout on the virtual clock
+	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "WaitUntilServiceHealthy")
+	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		<-c.After(15 * time.Minute)
+		<-args.Get(0).(context.Context).Done()
+	}).Return(context.DeadlineExceeded)
+
+	sm.SetState(interfaces.StateIdle)
+	sm.Event(interfaces.EventDeploy)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
+	require.Equal(t, models.DeploymentOutcomeTimedOut, appendedDeployment(pm.StoreMock).Outcome)
+}
+
+func TestDestroyWhenDeployingCancelsDeploy(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	// block the deploy until it is cancelled
+	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "WaitUntilServiceHealthy")
+	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-args.Get(0).(context.Context).Done()
+		close(cancelled)
+	}).Return(context.Canceled).Once()
+	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Return(nil)
+
+	sm.SetState(interfaces.StateIdle)
+	sm.Event(interfaces.EventDeploy)
+
+	require.Eventually(t, func() bool { return isClosed(started) }, time.Second, time.Millisecond)
+
+	err := sm.Destroy()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return isClosed(cancelled) }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		return historyContains(sm, interfaces.StateIdle) && sm.CurrentState() == interfaces.StateIdle
+	}, 100*time.Millisecond, 1*time.Millisecond)
+
+	require.True(t, historyContains(sm, interfaces.StateDestroy))
+	require.False(t, historyContains(sm, interfaces.StateFail))
+}
+
+func TestStopCancelsWorkWithoutChangingState(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	// block the strategy until it is cancelled
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-args.Get(0).(context.Context).Done()
+		close(cancelled)
+	}).Return(interfaces.StrategyStatusFailed, 0, context.Canceled)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return isClosed(started) }, time.Second, time.Millisecond)
+
+	sm.Stop()
+
+	require.Eventually(t, func() bool { return isClosed(cancelled) }, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return sm.CurrentState() != interfaces.StateMonitor }, 20*time.Millisecond, 1*time.Millisecond)
+}
+
+func TestEventDeployWithNoPrimarySetsStatusMonitor(t *testing.T) {
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "InitPrimary")
 	pm.RuntimeMock.On("InitPrimary", mock.Anything, mock.Anything).Return(interfaces.RuntimeDeploymentNoAction, nil)
@@ -228,28 +400,212 @@ func TestEventDeployWithNoPrimarySetsStatusMonitor(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDeploy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateMonitor) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateMonitor) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertNotCalled(t, "RemoveCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployWithNoErrorSetsStatusIdle(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDeploy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateIdle) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "InitPrimary", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
-func TestEventDeployedWithPostDeploymentTestErrorSetsStatusRollback(t *testing.T) {
+func TestEventDeployRecordsDeploymentHistory(t *testing.T) {
+	r, sm, pm := setupTests(t)
+
+	sm.SetState(interfaces.StateIdle)
+	sm.Event(interfaces.EventDeploy)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
+
+	d := appendedDeployment(pm.StoreMock)
+	require.NotNil(t, d)
+	require.Equal(t, "api-deployment-v1", d.CandidateName)
+	require.Equal(t, models.DeploymentOutcomePromoted, d.Outcome)
+	require.False(t, d.Ended.IsZero())
+	require.Nil(t, r.Deployment)
+}
+
+func webhookSentWithOutcome(m *mocks.WebhookMock, outcome string) bool {
+	return calledWith(&m.Mock, "Send", mock.Anything, mock.MatchedBy(func(msg interfaces.WebhookMessage) bool {
+		return msg.Outcome == outcome
+	}))
+}
+
+func TestEventDeployedOutsideScheduleWaitsAndCallsWebhook(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.Schedule = &models.Schedule{Freezes: []models.ScheduleFreeze{{Name: "forever", Start: "2000-01-01", End: "2100-01-01"}}}
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return webhookSentWithOutcome(pm.WebhookMock, interfaces.EventWaiting) }, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return sm.CurrentState() != interfaces.StateMonitor }, 20*time.Millisecond, 1*time.Millisecond)
+	pm.PostDeploymentMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+	pm.StrategyMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+}
+
+func TestEventDeployedContinuesWhenScheduleAllowsChanges(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.Schedule = &models.Schedule{Freezes: []models.ScheduleFreeze{{Name: "holidays", Start: "2022-12-23", End: "2022-12-26"}}}
+
+	// the freeze ends two minutes after the clock starts
+	start, _ := time.Parse(time.RFC3339, "2022-12-26T23:58:00Z")
+	sm.clock = clock.NewVirtual(start)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateScale) }, time.Second, time.Millisecond)
+	require.True(t, webhookSentWithOutcome(pm.WebhookMock, interfaces.EventWaiting))
+	pm.StrategyMock.AssertCalled(t, "Execute", mock.Anything, mock.Anything)
+}
+
+func TestAbortWhenWaitingForScheduleCancelsMonitor(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.Schedule = &models.Schedule{Freezes: []models.ScheduleFreeze{{Name: "forever", Start: "2000-01-01", End: "2100-01-01"}}}
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return webhookSentWithOutcome(pm.WebhookMock, interfaces.EventWaiting) }, time.Second, time.Millisecond)
+
+	sm.Abort()
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
+	pm.StrategyMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+}
+
+func setupDependency(pm *mocks.Mocks, state, outcome string) {
+	dep := &models.Release{Name: "gateway"}
+	dep.UpdateState(state)
+
+	testutils.ClearMockCall(&pm.StoreMock.Mock, "GetRelease")
+	pm.StoreMock.On("GetRelease", "gateway").Return(dep, nil)
+
+	d := models.NewDeployment("gateway", "1", time.Now())
+	d.End(outcome, nil, time.Now())
+
+	testutils.ClearMockCall(&pm.StoreMock.Mock, "ListDeployments")
+	pm.StoreMock.On("ListDeployments", "gateway").Return([]*models.Deployment{d}, nil)
+}
+
+func TestDeployWithDependencyInProgressWaitsInPending(t *testing.T) {
 	r, sm, pm := setupTests(t)
+	r.DependsOn = []string{"gateway"}
+	setupDependency(pm, interfaces.StateMonitor, models.DeploymentOutcomePromoted)
+
+	sm.SetState(interfaces.StateIdle)
+	sm.Deploy()
+
+	require.Eventually(t, func() bool { return webhookSentWithOutcome(pm.WebhookMock, interfaces.EventWaiting) }, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return sm.CurrentState() != interfaces.StatePending }, 20*time.Millisecond, 1*time.Millisecond)
+	pm.RuntimeMock.AssertNotCalled(t, "InitPrimary", mock.Anything, mock.Anything)
+}
+
+func TestDeployWithDependencyPromotedDeploys(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.DependsOn = []string{"gateway"}
+	setupDependency(pm, interfaces.StateIdle, models.DeploymentOutcomePromoted)
+
+	sm.SetState(interfaces.StateIdle)
+	sm.Deploy()
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateDeploy) }, time.Second, time.Millisecond)
+	require.True(t, historyContains(sm, interfaces.StatePending))
+}
+
+func TestDeployWithDependencyRolledBackSetsStatusFail(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.DependsOn = []string{"gateway"}
+	setupDependency(pm, interfaces.StateIdle, models.DeploymentOutcomeRolledBack)
+
+	sm.SetState(interfaces.StateIdle)
+	sm.Deploy()
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
+	pm.RuntimeMock.AssertNotCalled(t, "InitPrimary", mock.Anything, mock.Anything)
+
+	d := appendedDeployment(pm.StoreMock)
+	require.NotNil(t, d)
+	require.Equal(t, models.DeploymentOutcomeFailed, d.Outcome)
+	require.Contains(t, d.FailureReason, "gateway")
+}
+
+func TestNewWithStrategyWindowLongerThanMonitorTimeoutReturnsError(t *testing.T) {
+	pp, _ := mocks.BuildMocks(t)
+	r := &models.Release{}
+	data := bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))
+	r.FromJsonBody(ioutil.NopCloser(data))
+	r.Timeouts = &models.Timeouts{Monitor: "10m"}
+
+	ws := &mocks.WindowStrategyMock{}
+	ws.On("Configure", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ws.On("GetWindow").Return(15 * time.Minute)
+
+	testutils.ClearMockCall(&pp.Mock, "CreateStrategy")
+	pp.On("CreateStrategy", mock.Anything, mock.Anything).Return(ws, nil)
+
+	_, err := New(r, pp)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must be shorter than the monitor timeout 10m0s")
+}
+
+func TestNewWithDependencyOnItselfReturnsError(t *testing.T) {
+	pp, _ := mocks.BuildMocks(t)
+	r := &models.Release{}
+	data := bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))
+	r.FromJsonBody(ioutil.NopCloser(data))
+	r.DependsOn = []string{r.Name}
+
+	_, err := New(r, pp)
+	require.Error(t, err)
+}
+
+func TestNewWithCircularDependencyReturnsError(t *testing.T) {
+	pp, pm := mocks.BuildMocks(t)
+	r := &models.Release{}
+	data := bytes.NewBuffer(testutils.GetTestData(t, "valid_kubernetes_release.json"))
+	r.FromJsonBody(ioutil.NopCloser(data))
+	r.DependsOn = []string{"gateway"}
+
+	testutils.ClearMockCall(&pm.StoreMock.Mock, "GetRelease")
+	pm.StoreMock.On("GetRelease", "gateway").Return(&models.Release{Name: "gateway", DependsOn: []string{r.Name}}, nil)
+
+	_, err := New(r, pp)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "circular dependency")
+}
+
+func TestEventDeployedWithPostDeploymentTestErrorRecordsFailureReason(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.Deployment = models.NewDeployment("api-deployment-v1", "2", time.Now())
+
+	testutils.ClearMockCall(&pm.PostDeploymentMock.Mock, "Execute")
+	pm.PostDeploymentMock.On("Execute", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return appendedDeployment(pm.StoreMock) != nil }, time.Second, time.Millisecond)
+
+	d := appendedDeployment(pm.StoreMock)
+	require.Equal(t, models.DeploymentOutcomeRolledBack, d.Outcome)
+	require.Equal(t, "boom", d.FailureReason)
+}
+
+func TestEventDeployedWithPostDeploymentTestErrorSetsStatusRollback(t *testing.T) {
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.PostDeploymentMock.Mock, "Execute")
 	pm.PostDeploymentMock.On("Execute", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
@@ -257,14 +613,14 @@ func TestEventDeployedWithPostDeploymentTestErrorSetsStatusRollback(t *testing.T
 	sm.SetState(interfaces.StateDeploy)
 	sm.Event(interfaces.EventDeployed)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateRollback) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateRollback) }, time.Second, time.Millisecond)
 	pm.PostDeploymentMock.AssertCalled(t, "Execute", mock.Anything, mock.Anything)
 	pm.StrategyMock.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployedWithExecuteErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
 	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusFailed, 0, fmt.Errorf("boom"))
@@ -272,13 +628,13 @@ func TestEventDeployedWithExecuteErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateDeploy)
 	sm.Event(interfaces.EventDeployed)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.StrategyMock.AssertCalled(t, "Execute", mock.Anything, mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployedWithExecuteSuccessSetsStatusScale(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
 	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusSuccess, 20, nil)
@@ -286,14 +642,15 @@ func TestEventDeployedWithExecuteSuccessSetsStatusScale(t *testing.T) {
 	sm.SetState(interfaces.StateDeploy)
 	sm.Event(interfaces.EventDeployed)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateScale) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateScale) }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return calledWith(&pm.WebhookMock.Mock, "Send", mock.Anything, mock.Anything) }, time.Second, time.Millisecond)
 	pm.StrategyMock.AssertCalled(t, "Execute", mock.Anything, mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 20)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDeployedWithExecuteCompleteSetsStatusScale(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
 	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusComplete, 100, nil)
@@ -301,22 +658,249 @@ func TestEventDeployedWithExecuteCompleteSetsStatusScale(t *testing.T) {
 	sm.SetState(interfaces.StateDeploy)
 	sm.Event(interfaces.EventDeployed)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StatePromote) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StatePromote) }, time.Second, time.Millisecond)
 	pm.StrategyMock.AssertCalled(t, "Execute", mock.Anything, mock.Anything)
 }
 
-func TestEventHealthyWithNoTrafficSetsStatusFail(t *testing.T) {
+func TestEventDeployedWithExecuteAwaitingPromotionSetsStatusAwaitPromotion(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusAwaitingPromotion, 90, nil)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateAwaitPromotion) }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return calledWith(&pm.WebhookMock.Mock, "Send", mock.Anything, mock.Anything) }, time.Second, time.Millisecond)
+
+	pm.StrategyMock.AssertCalled(t, "Execute", mock.Anything, mock.Anything)
+	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 90)
+	pm.RuntimeMock.AssertNotCalled(t, "PromoteCandidate", mock.Anything)
+	require.Equal(t, interfaces.StateAwaitPromotion, sm.CurrentState())
+}
+
+func TestEventAwaitPromotionWithScaleErrorSetsStatusFail(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
+	pm.ReleaserMock.On("Scale", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	sm.SetState(interfaces.StateMonitor)
+	sm.Event(interfaces.EventAwaitPromotion, 90)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
+	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 90)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestPromoteWhenAwaitingPromotionSetsStatusIdle(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	sm.SetState(interfaces.StateAwaitPromotion)
+	err := sm.Promote()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
+	require.True(t, historyContains(sm, interfaces.StatePromote))
+	pm.RuntimeMock.AssertCalled(t, "PromoteCandidate", mock.Anything)
+}
+
+func TestPromoteRecordsTrafficStepsAndOutcome(t *testing.T) {
+	r, sm, pm := setupTests(t)
+	r.Deployment = models.NewDeployment("api-deployment-v1", "2", time.Now())
+	r.Deployment.AddTrafficStep(50, time.Now())
+
+	sm.SetState(interfaces.StateAwaitPromotion)
+	err := sm.Promote()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return appendedDeployment(pm.StoreMock) != nil }, time.Second, time.Millisecond)
+
+	d := appendedDeployment(pm.StoreMock)
+	require.Equal(t, models.DeploymentOutcomePromoted, d.Outcome)
+	require.Len(t, d.TrafficSteps, 2)
+	require.Equal(t, 100, d.TrafficSteps[1].Traffic)
+}
+
+func TestPromoteWhenNotAwaitingPromotionReturnsError(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	sm.SetState(interfaces.StateMonitor)
+	err := sm.Promote()
+	require.Error(t, err)
+
+	pm.RuntimeMock.AssertNotCalled(t, "PromoteCandidate", mock.Anything)
+}
+
+func TestPauseWhenMonitoringCancelsStrategyAndSetsStatusPaused(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	// block the strategy until it is cancelled
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-args.Get(0).(context.Context).Done()
+		close(cancelled)
+	}).Return(interfaces.StrategyStatusFailed, 0, context.Canceled)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return isClosed(started) }, time.Second, time.Millisecond)
+
+	err := sm.Pause()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return isClosed(cancelled) }, time.Second, time.Millisecond)
+
+	require.True(t, historyContains(sm, interfaces.StatePaused))
+	require.Equal(t, interfaces.StatePaused, sm.CurrentState())
+	pm.ReleaserMock.AssertNotCalled(t, "Scale", mock.Anything, mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestEventDeployedWithExecutePausedSetsStatusPausedAndCallsWebhook(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusPaused, 10, fmt.Errorf("boom"))
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StatePaused) }, time.Second, time.Millisecond)
+	require.Equal(t, interfaces.StatePaused, sm.CurrentState())
+	require.False(t, historyContains(sm, interfaces.StateFail))
+
+	require.Eventually(t, func() bool {
+		return calledWith(&pm.WebhookMock.Mock, "Send", mock.Anything, mock.MatchedBy(func(msg interfaces.WebhookMessage) bool {
+			return msg.State == interfaces.StatePaused && msg.Error == "boom"
+		}))
+	}, 100*time.Millisecond, 1*time.Millisecond)
+}
+
+func TestPauseWhenNotMonitoringReturnsError(t *testing.T) {
+	_, sm, _ := setupTests(t)
+
+	sm.SetState(interfaces.StateIdle)
+
+	err := sm.Pause()
+	require.Error(t, err)
+}
+
+func TestPauseWhenScalingReturnsError(t *testing.T) {
+	_, sm, _ := setupTests(t)
+
+	sm.SetState(interfaces.StateScale)
+
+	err := sm.Pause()
+	require.Error(t, err)
+	require.Equal(t, interfaces.StateScale, sm.CurrentState())
+}
+
+func TestResumeWhenPausedSetsStatusMonitor(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusFailing, 0, nil)
+
+	sm.SetState(interfaces.StatePaused)
+
+	err := sm.Resume()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateMonitor) }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return calledWith(&pm.StrategyMock.Mock, "Execute", mock.Anything, mock.Anything) }, time.Second, time.Millisecond)
+	pm.StrategyMock.AssertCalled(t, "Execute", mock.Anything, mock.Anything)
+}
+
+func TestAbortWhenPausedRollsBackAndResetsStrategy(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	sm.SetState(interfaces.StatePaused)
+
+	err := sm.Abort()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
+	require.True(t, historyContains(sm, interfaces.StateRollback))
+	pm.StrategyMock.AssertCalled(t, "Reset")
+	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
+	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestAbortCancelsWebhooksForTheState(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	testutils.ClearMockCall(&pm.StrategyMock.Mock, "Execute")
+	pm.StrategyMock.On("Execute", mock.Anything, mock.Anything).Return(interfaces.StrategyStatusFailed, 0, nil)
+
+	// block the webhook for the failed monitor until the context is cancelled
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	testutils.ClearMockCall(&pm.WebhookMock.Mock, "Send")
+	pm.WebhookMock.On("Send", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if args.Get(1).(interfaces.WebhookMessage).Title != "Monitor deployment failed" {
+			return
+		}
+
+		close(started)
+		<-args.Get(0).(context.Context).Done()
+		close(cancelled)
+	}).Return(context.Canceled)
+
+	sm.SetState(interfaces.StateDeploy)
+	sm.Event(interfaces.EventDeployed)
+
+	require.Eventually(t, func() bool { return isClosed(started) }, time.Second, time.Millisecond)
+
+	err := sm.Abort()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return isClosed(cancelled) }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateRollback) }, time.Second, time.Millisecond)
+}
+
+func TestAbortRecordsAbortedOutcome(t *testing.T) {
 	r, sm, pm := setupTests(t)
+	r.Deployment = models.NewDeployment("api-deployment-v1", "2", time.Now())
+
+	sm.SetState(interfaces.StatePaused)
+
+	err := sm.Abort()
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return appendedDeployment(pm.StoreMock) != nil }, time.Second, time.Millisecond)
+	require.Equal(t, models.DeploymentOutcomeAborted, appendedDeployment(pm.StoreMock).Outcome)
+}
+
+func TestAbortWhenIdleReturnsError(t *testing.T) {
+	_, sm, _ := setupTests(t)
+
+	sm.SetState(interfaces.StateIdle)
+
+	err := sm.Abort()
+	require.Error(t, err)
+}
+
+func TestEventHealthyWithNoTrafficSetsStatusFail(t *testing.T) {
+	_, sm, pm := setupTests(t)
 
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventHealthy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertNotCalled(t, "Scale", mock.Anything, mock.Anything)
 }
 
 func TestEventHealthyWithScaleErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
 	pm.ReleaserMock.On("Scale", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
@@ -324,24 +908,117 @@ func TestEventHealthyWithScaleErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventHealthy, 20)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 20)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventHealthyWithNoScaleErrorSetsStatusMonitor(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventHealthy, 20)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateMonitor) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateMonitor) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 20)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestEventHealthyWithRoutingStrategyCreatesRoutes(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	routes := []interfaces.RouteMatch{{Type: interfaces.RouteMatchTypeHeader, Name: "x-beta"}}
+	sm.strategyPlugin = setupRoutingStrategy(routes)
+
+	sm.SetState(interfaces.StateMonitor)
+	sm.Event(interfaces.EventHealthy, 0)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateMonitor) }, time.Second, time.Millisecond)
+	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
+	pm.ReleaserMock.AssertCalled(t, "Route", mock.Anything, routes)
+}
+
+func TestEventHealthyWithRouteErrorSetsStatusFail(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	sm.strategyPlugin = setupRoutingStrategy([]interfaces.RouteMatch{{Type: interfaces.RouteMatchTypeHeader, Name: "x-beta"}})
+
+	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Route")
+	pm.ReleaserMock.On("Route", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
+
+	sm.SetState(interfaces.StateMonitor)
+	sm.Event(interfaces.EventHealthy, 0)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestEventHealthyWithoutRoutingStrategyDoesNotCreateRoutes(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	sm.SetState(interfaces.StateMonitor)
+	sm.Event(interfaces.EventHealthy, 20)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateMonitor) }, time.Second, time.Millisecond)
+	pm.ReleaserMock.AssertNotCalled(t, "Route", mock.Anything, mock.Anything)
+}
+
+func receiveEvent(t *testing.T, c <-chan interfaces.ReleaseEvent, eventType string) interfaces.ReleaseEvent {
+	timeout := time.After(100 * time.Millisecond)
+
+	for {
+		select {
+		case e := <-c:
+			if e.Type == eventType {
+				return e
+			}
+		case <-timeout:
+			require.Fail(t, "event not received", eventType)
+			return interfaces.ReleaseEvent{}
+		}
+	}
+}
+
+func TestEventHealthyPublishesStateAndTrafficEvents(t *testing.T) {
+	_, sm, _ := setupTests(t)
+
+	c, cancel := sm.events.Subscribe("api")
+	defer cancel()
+
+	sm.SetState(interfaces.StateMonitor)
+	sm.Event(interfaces.EventHealthy, 20)
+
+	e := receiveEvent(t, c, interfaces.ReleaseEventState)
+	require.Equal(t, interfaces.StateScale, e.State)
+	require.Equal(t, interfaces.EventHealthy, e.Event)
+
+	e = receiveEvent(t, c, interfaces.ReleaseEventTraffic)
+	require.Equal(t, "api", e.Release)
+	require.Equal(t, 20, e.CandidateTraffic)
+
+	e = receiveEvent(t, c, interfaces.ReleaseEventWebhook)
+	require.Equal(t, interfaces.EventScaled, e.Result)
+}
+
+func TestStrategyCheckPublishesCheckEvent(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	c, cancel := sm.events.Subscribe("")
+	defer cancel()
+
+	testutils.ClearMockCall(&pm.MonitorMock.Mock, "Check")
+	pm.MonitorMock.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(interfaces.CheckFailed, fmt.Errorf("boom"))
+
+	m := &monitorEvents{pm.MonitorMock, sm}
+	m.Check(context.Background(), "api", time.Second)
+
+	e := receiveEvent(t, c, interfaces.ReleaseEventCheck)
+	require.Equal(t, "failed", e.Result)
+	require.Equal(t, "boom", e.Error)
 }
 
 func TestEventCompleteWithScaleCandidateErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
 	pm.ReleaserMock.On("Scale", mock.Anything, 100).Return(fmt.Errorf("boom"))
@@ -349,13 +1026,13 @@ func TestEventCompleteWithScaleCandidateErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventComplete)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventCompleteWithPromoteErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "PromoteCandidate")
 	pm.RuntimeMock.On("PromoteCandidate", mock.Anything).Return(interfaces.RuntimeDeploymentInternalError, fmt.Errorf("boom"))
@@ -363,14 +1040,14 @@ func TestEventCompleteWithPromoteErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventComplete)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.RuntimeMock.AssertCalled(t, "PromoteCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventCompleteWithHealthCheckErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "WaitUntilServiceHealthy")
 	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
@@ -378,15 +1055,15 @@ func TestEventCompleteWithHealthCheckErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventComplete)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.ReleaserMock.AssertCalled(t, "WaitUntilServiceHealthy", mock.Anything, pm.RuntimeMock.PrimarySubsetFilter())
 	pm.ReleaserMock.AssertNotCalled(t, "Scale", mock.Anything, 0)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventCompleteWithScalePrimaryErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
 	pm.ReleaserMock.On("Scale", mock.Anything, 100).Return(nil)
@@ -395,15 +1072,15 @@ func TestEventCompleteWithScalePrimaryErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventComplete)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.RuntimeMock.AssertCalled(t, "PromoteCandidate", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventCompleteWithRemoveCandidateErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "RemoveCandidate")
 	pm.RuntimeMock.On("RemoveCandidate", mock.Anything).Return(fmt.Errorf("boom"))
@@ -411,30 +1088,43 @@ func TestEventCompleteWithRemoveCandidateErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventComplete)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.RuntimeMock.AssertCalled(t, "PromoteCandidate", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventCompleteWithNoErrorSetsStatusIdle(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventComplete)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateIdle) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.RuntimeMock.AssertCalled(t, "PromoteCandidate", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestEventCompleteWithRoutingStrategyRemovesRoutes(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	sm.strategyPlugin = setupRoutingStrategy([]interfaces.RouteMatch{{Type: interfaces.RouteMatchTypeHeader, Name: "x-beta"}})
+
+	sm.SetState(interfaces.StateMonitor)
+	sm.Event(interfaces.EventComplete)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
+	pm.ReleaserMock.AssertCalled(t, "Route", mock.Anything, []interfaces.RouteMatch(nil))
+	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
 }
 
 func TestEventUnhealthyWithScaleErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
 	pm.ReleaserMock.On("Scale", mock.Anything, 0).Return(fmt.Errorf("boom"))
@@ -442,13 +1132,13 @@ func TestEventUnhealthyWithScaleErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventUnhealthy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventUnhealthyRemoveCandidateErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "RemoveCandidate")
 	pm.RuntimeMock.On("RemoveCandidate", mock.Anything).Return(fmt.Errorf("boom"))
@@ -456,26 +1146,39 @@ func TestEventUnhealthyRemoveCandidateErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventUnhealthy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventUnhealthyWithNoErrorSetsStatusIdle(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	sm.SetState(interfaces.StateMonitor)
 	sm.Event(interfaces.EventUnhealthy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateIdle) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 0)
 	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestEventUnhealthyWithRoutingStrategyRemovesRoutes(t *testing.T) {
+	_, sm, pm := setupTests(t)
+
+	sm.strategyPlugin = setupRoutingStrategy([]interfaces.RouteMatch{{Type: interfaces.RouteMatchTypeHeader, Name: "x-beta"}})
+
+	sm.SetState(interfaces.StateMonitor)
+	sm.Event(interfaces.EventUnhealthy)
+
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
+	pm.ReleaserMock.AssertCalled(t, "Route", mock.Anything, []interfaces.RouteMatch(nil))
+	pm.RuntimeMock.AssertCalled(t, "RemoveCandidate", mock.Anything)
 }
 
 func TestEventDestroyWithRestoreOriginalErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "RestoreOriginal")
 	pm.RuntimeMock.On("RestoreOriginal", mock.Anything).Return(fmt.Errorf("boom"))
@@ -483,13 +1186,13 @@ func TestEventDestroyWithRestoreOriginalErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDestroy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "RestoreOriginal", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDestroyWithHealthCheckErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "WaitUntilServiceHealthy")
 	pm.ReleaserMock.On("WaitUntilServiceHealthy", mock.Anything, mock.Anything).Return(fmt.Errorf("boom"))
@@ -497,15 +1200,15 @@ func TestEventDestroyWithHealthCheckErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDestroy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "RestoreOriginal", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "WaitUntilServiceHealthy", mock.Anything, pm.RuntimeMock.CandidateSubsetFilter())
 	pm.ReleaserMock.AssertNotCalled(t, "Scale", mock.Anything, 100)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDestroyWithScaleErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Scale")
 	pm.ReleaserMock.On("Scale", mock.Anything, 100).Return(fmt.Errorf("boom"))
@@ -513,14 +1216,14 @@ func TestEventDestroyWithScaleErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDestroy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "RestoreOriginal", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDestroyWithRemovePrimaryErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.RuntimeMock.Mock, "RemovePrimary")
 	pm.RuntimeMock.On("RemovePrimary", mock.Anything).Return(fmt.Errorf("boom"))
@@ -528,15 +1231,15 @@ func TestEventDestroyWithRemovePrimaryErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDestroy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "RestoreOriginal", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.RuntimeMock.AssertCalled(t, "RemovePrimary", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDestroyWithDestroyErrorSetsStatusFail(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	testutils.ClearMockCall(&pm.ReleaserMock.Mock, "Destroy")
 	pm.ReleaserMock.On("Destroy", mock.Anything).Return(fmt.Errorf("boom"))
@@ -544,24 +1247,24 @@ func TestEventDestroyWithDestroyErrorSetsStatusFail(t *testing.T) {
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDestroy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateFail) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateFail) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "RestoreOriginal", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.RuntimeMock.AssertCalled(t, "RemovePrimary", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Destroy", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
 
 func TestEventDestroyWithNoErrorSetsStatusIdle(t *testing.T) {
-	r, sm, pm := setupTests(t)
+	_, sm, pm := setupTests(t)
 
 	sm.SetState(interfaces.StateIdle)
 	sm.Event(interfaces.EventDestroy)
 
-	require.Eventually(t, func() bool { return historyContains(r, interfaces.StateIdle) }, 100*time.Millisecond, 1*time.Millisecond)
+	require.Eventually(t, func() bool { return historyContains(sm, interfaces.StateIdle) }, time.Second, time.Millisecond)
 	pm.RuntimeMock.AssertCalled(t, "RestoreOriginal", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Scale", mock.Anything, 100)
 	pm.RuntimeMock.AssertCalled(t, "RemovePrimary", mock.Anything)
 	pm.ReleaserMock.AssertCalled(t, "Destroy", mock.Anything)
-	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything)
+	pm.WebhookMock.AssertCalled(t, "Send", mock.Anything, mock.Anything)
 }
@@ -0,0 +1,247 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+type teamsClient interface {
+	Send(ctx context.Context, url string, card *Card) error
+}
+
+type teamsImpl struct {
+	client *http.Client
+}
+
+func (t *teamsImpl) Send(ctx context.Context, url string, card *Card) error {
+	payload := Message{
+		Type: "message",
+		Attachments: []Attachment{
+			{ContentType: "application/vnd.microsoft.card.adaptive", Content: card},
+		},
+	}
+
+	d, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to encode Teams message: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d))
+	if err != nil {
+		return fmt.Errorf("unable to create Teams request: %s", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to call Teams webhook: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unable to call Teams webhook, expected status 2xx, got %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Message is the payload for a Teams incoming webhook
+type Message struct {
+	Type        string       `json:"type"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	Content     *Card  `json:"content"`
+}
+
+// Card is an adaptive card, https://adaptivecards.io/explorer/
+type Card struct {
+	Schema  string    `json:"$schema"`
+	Type    string    `json:"type"`
+	Version string    `json:"version"`
+	Body    []Element `json:"body"`
+}
+
+// Element is a TextBlock or FactSet in the body of an adaptive card
+type Element struct {
+	Type   string `json:"type"`
+	Text   string `json:"text,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Color  string `json:"color,omitempty"`
+	Wrap   bool   `json:"wrap,omitempty"`
+	Facts  []Fact `json:"facts,omitempty"`
+}
+
+type Fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type Plugin struct {
+	log    hclog.Logger
+	store  interfaces.PluginStateStore
+	config *PluginConfig
+	client teamsClient
+}
+
+type PluginConfig struct {
+	// URL of the Teams incoming webhook
+	URL string `json:"url" validate:"required,url"`
+	// Optional template to use instead of default messages
+	Template string `json:"template,omitempty"`
+	// List of status to which the webhook applies, if empty all status are used
+	Status []string `json:"status,omitempty"`
+}
+
+func New() (*Plugin, error) {
+	return &Plugin{}, nil
+}
+
+var ErrMissingURL = fmt.Errorf(`URL is a required field when configuring Teams webhooks,
+	you can obtain this value when adding an Incoming Webhook connector to a Teams channel`)
+
+func (p *Plugin) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	p.log = log
+	p.store = store
+	p.config = &PluginConfig{}
+
+	err := json.Unmarshal(data, p.config)
+	if err != nil {
+		return fmt.Errorf("unable to decode Webhook config: %s", err)
+	}
+
+	validate := validator.New()
+	err = validate.Struct(p.config)
+
+	if err != nil {
+		errorMessage := ""
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Namespace() {
+			case "PluginConfig.URL":
+				errorMessage += ErrMissingURL.Error() + "\n"
+			}
+		}
+
+		return fmt.Errorf(errorMessage)
+	}
+
+	p.client = &teamsImpl{client: &http.Client{Timeout: 10 * time.Second}}
+
+	return nil
+}
+
+func (p *Plugin) Send(ctx context.Context, message interfaces.WebhookMessage) error {
+	// only send if current status is in our list of status
+	if len(p.config.Status) > 0 {
+		progress := false
+		for _, s := range p.config.Status {
+			if s == message.State {
+				progress = true
+			}
+		}
+
+		// status not in our list
+		if !progress {
+			p.log.Debug("Ignoring Teams message", "url", p.config.URL, "message", message, "status", message.State, "statuses", p.config.Status)
+			return nil
+		}
+	}
+
+	p.log.Debug("Sending message to Teams", "url", p.config.URL, "message", message)
+
+	templateContent := defaultContent
+	if p.config.Template != "" {
+		templateContent = p.config.Template
+	}
+
+	tmpl, err := template.New("teams").Parse(templateContent)
+	if err != nil {
+		return fmt.Errorf("unable to process message template for Webhook plugin: %s", err)
+	}
+
+	out := bytes.NewBufferString("")
+	err = tmpl.Execute(out, message)
+	if err != nil {
+		return fmt.Errorf("unable to execute template for Webhook plugin: %s", err)
+	}
+
+	return p.client.Send(ctx, p.config.URL, buildCard(message, strings.TrimSpace(out.String())))
+}
+
+// buildCard returns an adaptive card for the message with the title colour coded by
+// the outcome, the content of the template, the traffic split, and any error
+func buildCard(message interfaces.WebhookMessage, content string) *Card {
+	body := []Element{
+		{Type: "TextBlock", Text: message.Title, Size: "Medium", Weight: "Bolder", Color: outcomeColor(message), Wrap: true},
+		{Type: "TextBlock", Text: content, Wrap: true},
+		{Type: "FactSet", Facts: []Fact{
+			{Title: "Release", Value: fmt.Sprintf("%s/%s", message.Namespace, message.Name)},
+			{Title: "State", Value: message.State},
+			{Title: "Outcome", Value: message.Outcome},
+			{Title: "Primary", Value: trafficBar(message.PrimaryTraffic)},
+			{Title: "Candidate", Value: trafficBar(message.CandidateTraffic)},
+		}},
+	}
+
+	if message.Error != "" {
+		body = append(body, Element{Type: "TextBlock", Text: message.Error, Color: "Attention", Wrap: true})
+	}
+
+	return &Card{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body:    body,
+	}
+}
+
+// outcomeColor returns the adaptive card colour for the outcome of the message
+func outcomeColor(message interfaces.WebhookMessage) string {
+	if message.Error != "" {
+		return "Attention"
+	}
+
+	switch message.Outcome {
+	case interfaces.EventFail, interfaces.EventTimeout, interfaces.EventUnhealthy, interfaces.EventAbort:
+		return "Attention"
+	case interfaces.EventWaiting, interfaces.EventPause, interfaces.EventAwaitPromotion:
+		return "Warning"
+	}
+
+	return "Good"
+}
+
+// trafficBar renders the traffic percentage as a bar of ten blocks e.g. ███░░░░░░░ 30%
+func trafficBar(traffic int) string {
+	if traffic < 0 {
+		traffic = 0
+	}
+
+	if traffic > 100 {
+		traffic = 100
+	}
+
+	filled := (traffic + 5) / 10
+
+	return fmt.Sprintf("%s%s %d%%", strings.Repeat("█", filled), strings.Repeat("░", 10-filled), traffic)
+}
+
+var defaultContent = `
+Consul Release Controller state has changed to "{{ .State }}" for
+the release "{{ .Name }}" in the namespace "{{ .Namespace }}".
+`
@@ -0,0 +1,185 @@
+package teams
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockClient struct {
+	mock.Mock
+}
+
+func (t *mockClient) Send(ctx context.Context, url string, card *Card) error {
+	args := t.Called(ctx, url, card)
+
+	return args.Error(0)
+}
+
+func setupTests(t *testing.T, config string) (*Plugin, *mockClient) {
+	p, _ := New()
+
+	err := p.Configure([]byte(config), hclog.NewNullLogger(), &mocks.StoreMock{})
+	assert.NoError(t, err)
+
+	mc := &mockClient{}
+	mc.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	p.client = mc
+
+	return p, mc
+}
+
+func sentCard(mc *mockClient) *Card {
+	return mc.Calls[0].Arguments.Get(2).(*Card)
+}
+
+func TestValidatesURL(t *testing.T) {
+	p, _ := New()
+	err := p.Configure([]byte(configWithMissingURL), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	assert.Error(t, err)
+}
+
+func TestConfiguresWithoutError(t *testing.T) {
+	p, _ := New()
+	err := p.Configure([]byte(validConfig), hclog.NewNullLogger(), &mocks.StoreMock{})
+
+	assert.NoError(t, err)
+}
+
+func TestSendsCardWithDefaultContent(t *testing.T) {
+	p, mc := setupTests(t, validConfig)
+
+	p.Send(context.Background(), interfaces.WebhookMessage{
+		Name:             "testname",
+		Namespace:        "testnamespace",
+		Title:            "testtitle",
+		Outcome:          interfaces.EventHealthy,
+		State:            "teststate",
+		PrimaryTraffic:   70,
+		CandidateTraffic: 30,
+	})
+
+	mc.AssertCalled(t, "Send", mock.Anything, "https://example.webhook.office.com/webhookb2/abc", mock.Anything)
+
+	card := sentCard(mc)
+	assert.Equal(t, "testtitle", card.Body[0].Text)
+	assert.Equal(t, "Good", card.Body[0].Color)
+	assert.Contains(t, card.Body[1].Text, `has changed to "teststate"`)
+	assert.Equal(t, "███████░░░ 70%", card.Body[2].Facts[3].Value)
+	assert.Equal(t, "███░░░░░░░ 30%", card.Body[2].Facts[4].Value)
+	assert.Len(t, card.Body, 3)
+}
+
+func TestSendsCardWithError(t *testing.T) {
+	p, mc := setupTests(t, validConfig)
+
+	p.Send(context.Background(), interfaces.WebhookMessage{
+		Name:      "testname",
+		Namespace: "testnamespace",
+		Title:     "testtitle",
+		Outcome:   interfaces.EventFail,
+		State:     "teststate",
+		Error:     "It went boom",
+	})
+
+	card := sentCard(mc)
+	assert.Equal(t, "Attention", card.Body[0].Color)
+	assert.Equal(t, "It went boom", card.Body[3].Text)
+}
+
+func TestSendsCardWithWarningWhenWaiting(t *testing.T) {
+	p, mc := setupTests(t, validConfig)
+
+	p.Send(context.Background(), interfaces.WebhookMessage{
+		Title:   "testtitle",
+		Outcome: interfaces.EventWaiting,
+		State:   "teststate",
+	})
+
+	assert.Equal(t, "Warning", sentCard(mc).Body[0].Color)
+}
+
+func TestSendsCardWithCustomContent(t *testing.T) {
+	p, mc := setupTests(t, validConfigWithTemplate)
+
+	p.Send(context.Background(), interfaces.WebhookMessage{
+		Name:      "testname",
+		Namespace: "testnamespace",
+		Title:     "testtitle",
+		Outcome:   "testoutcome",
+		State:     "teststate",
+	})
+
+	assert.Equal(t, "my template teststate", sentCard(mc).Body[1].Text)
+}
+
+func TestDoesNotSendMessageWhenStatusNotInList(t *testing.T) {
+	p, mc := setupTests(t, validConfigWithStatus)
+
+	p.Send(context.Background(), interfaces.WebhookMessage{
+		Name:      "testname",
+		Namespace: "testnamespace",
+		Title:     "testtitle",
+		Outcome:   "testoutcome",
+		State:     "teststate",
+	})
+
+	mc.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSendReturnsErrorWhenContextCancelled(t *testing.T) {
+	// the server does not respond until the test completes
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c := &teamsImpl{client: &http.Client{Timeout: 10 * time.Second}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	st := time.Now()
+	err := c.Send(ctx, ts.URL, buildCard(interfaces.WebhookMessage{Title: "testtitle"}, "content"))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), context.Canceled.Error())
+	assert.Less(t, time.Since(st), time.Second)
+}
+
+var configWithMissingURL = `
+{
+	"template": "abcdef"
+}
+`
+
+var validConfig = `
+{
+	"url": "https://example.webhook.office.com/webhookb2/abc"
+}
+`
+
+var validConfigWithTemplate = `
+{
+	"url": "https://example.webhook.office.com/webhookb2/abc",
+	"template": "my template {{ .State }}"
+}
+`
+
+var validConfigWithStatus = `
+{
+	"url": "https://example.webhook.office.com/webhookb2/abc",
+	"status": ["state_destroy"]
+}
+`
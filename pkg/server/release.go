@@ -20,6 +20,7 @@ type Release struct {
 	kubernetesController *kubernetes.Kubernetes
 	nomadController      *nomad.Nomad
 	apiServer            *api.Server
+	provider             interfaces.Provider
 	enableKubernetes     bool
 	enableNomad          bool
 	tlsBindAddress       string
@@ -65,6 +66,7 @@ func (r *Release) Start() error {
 	}
 
 	provider := plugins.GetProvider(r.log, r.metrics, store)
+	r.provider = provider
 
 	apiError := make(chan error)
 	kubernetesError := make(chan error)
@@ -187,6 +189,12 @@ func (r *Release) Shutdown() error {
 		r.log.Debug("Nomad controller stopped")
 	}
 
+	if r.provider != nil {
+		r.log.Info("Stopping releases")
+		stopReleases(r.provider, r.log)
+		r.log.Debug("Releases stopped")
+	}
+
 	r.log.Debug("Shutdown complete")
 	r.shutdown <- struct{}{}
 
@@ -203,9 +211,19 @@ func rehydrateReleases(p interfaces.Provider, logger hclog.Logger) error {
 
 	for _, r := range rels {
 		logger.Info("Rehydrating release", "name", r.Name, "state", r.CurrentState())
+
+		// a release that can not be created, for example because its monitor config is no longer
+		// valid, must not stop the controller from managing the other releases
 		sm, err := p.GetStateMachine(r)
 		if err != nil {
-			return fmt.Errorf("unable to get statemachine for release: %s, %s", r.Name, err)
+			logger.Error("Unable to get statemachine for release, the release will not be resumed", "name", r.Name, "error", err)
+			continue
+		}
+
+		// paused releases remain paused until they are resumed using the API
+		if r.CurrentState() == interfaces.StatePaused {
+			logger.Info("Release is paused", "name", r.Name)
+			continue
 		}
 
 		go sm.Resume()
@@ -215,3 +233,24 @@ func rehydrateReleases(p interfaces.Provider, logger hclog.Logger) error {
 
 	//panic("exit")
 }
+
+// stopReleases cancels any in-flight work for the releases, the state of the releases is not
+// changed so that they can be rehydrated when the controller restarts
+func stopReleases(p interfaces.Provider, logger hclog.Logger) {
+	rels, err := p.GetDataStore().ListReleases(&interfaces.ListOptions{})
+	if err != nil {
+		logger.Error("Unable to list releases", "error", err)
+		return
+	}
+
+	for _, r := range rels {
+		sm, err := p.GetStateMachine(r)
+		if err != nil {
+			logger.Error("Unable to get statemachine for release", "name", r.Name, "error", err)
+			continue
+		}
+
+		logger.Debug("Stopping release", "name", r.Name, "state", sm.CurrentState())
+		sm.Stop()
+	}
+}
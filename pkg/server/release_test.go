@@ -0,0 +1,45 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/mocks"
+	"github.com/nicholasjackson/consul-release-controller/pkg/testutils"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRehydrateReleasesSkipsReleasesThatCanNotBeCreated(t *testing.T) {
+	pp, pm := mocks.BuildMocks(t)
+
+	invalid := &models.Release{Name: "invalid"}
+	valid := &models.Release{Name: "valid"}
+	valid.UpdateState(interfaces.StateMonitor)
+
+	testutils.ClearMockCall(&pm.StoreMock.Mock, "ListReleases")
+	pm.StoreMock.On("ListReleases", mock.Anything).Return([]*models.Release{invalid, valid}, nil)
+
+	testutils.ClearMockCall(&pp.Mock, "GetStateMachine")
+	pp.On("GetStateMachine", invalid).Return(nil, fmt.Errorf("boom"))
+	pp.On("GetStateMachine", valid).Return(pm.StateMachineMock, nil)
+
+	resumed := make(chan struct{})
+	testutils.ClearMockCall(&pm.StateMachineMock.Mock, "Resume")
+	pm.StateMachineMock.On("Resume").Run(func(args mock.Arguments) { close(resumed) }).Return(nil)
+
+	err := rehydrateReleases(pp, hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Fatal("expected the valid release to be resumed")
+	}
+
+	pp.AssertCalled(t, "GetStateMachine", invalid)
+}
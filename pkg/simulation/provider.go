@@ -0,0 +1,234 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/abtest"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/bluegreen"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/canary"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+// provider creates the simulated plugins for a release, only the strategy plugin is
+// the real implementation
+type provider struct {
+	log      hclog.Logger
+	clock    interfaces.Clock
+	store    interfaces.Store
+	events   interfaces.Events
+	recorder *recorder
+	script   *Script
+}
+
+func (p *provider) CreateReleaser(pluginName string) (interfaces.Releaser, error) {
+	return &releaser{recorder: p.recorder}, nil
+}
+
+func (p *provider) CreateRuntime(pluginName string) (interfaces.Runtime, error) {
+	return &runtime{}, nil
+}
+
+func (p *provider) CreateMonitor(pluginName, name, namespace, runtime string) (interfaces.Monitor, error) {
+	return &monitor{recorder: p.recorder, script: p.script}, nil
+}
+
+func (p *provider) CreateStrategy(pluginName string, mp interfaces.Monitor) (interfaces.Strategy, error) {
+	switch pluginName {
+	case plugins.PluginStrategyTypeCanary:
+		return canary.New(mp, p.clock)
+	case plugins.PluginStrategyTypeBlueGreen:
+		return bluegreen.New(mp, p.clock)
+	case plugins.PluginStrategyTypeABTest:
+		return abtest.New(mp, p.clock)
+	}
+
+	return nil, fmt.Errorf("invalid Strategy plugin type: %s", pluginName)
+}
+
+func (p *provider) CreateWebhook(pluginName string) (interfaces.Webhook, error) {
+	return &webhook{}, nil
+}
+
+func (p *provider) CreateGate(pluginName string) (interfaces.Gate, error) {
+	return &gate{}, nil
+}
+
+func (p *provider) CreatePostDeploymentTest(pluginName, name, namespace, runtime string, mp interfaces.Monitor) (interfaces.PostDeploymentTest, error) {
+	return &postDeploymentTest{}, nil
+}
+
+func (p *provider) GetRuntimeClient(runtime string) (interfaces.RuntimeClient, error) {
+	return nil, fmt.Errorf("runtime clients are not available when simulating a release")
+}
+
+func (p *provider) GetLogger() hclog.Logger {
+	return p.log
+}
+
+func (p *provider) GetMetrics() interfaces.Metrics {
+	return p.recorder
+}
+
+func (p *provider) GetDataStore() interfaces.Store {
+	return p.store
+}
+
+func (p *provider) GetClock() interfaces.Clock {
+	return p.clock
+}
+
+func (p *provider) GetEvents() interfaces.Events {
+	return p.events
+}
+
+func (p *provider) GetStateMachine(release *models.Release) (interfaces.StateMachine, error) {
+	return nil, fmt.Errorf("statemachines are not available when simulating a release")
+}
+
+func (p *provider) DeleteStateMachine(release *models.Release) error {
+	return nil
+}
+
+// releaser records the traffic sent to the candidate
+type releaser struct {
+	recorder *recorder
+}
+
+func (r *releaser) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return nil
+}
+
+func (r *releaser) BaseConfig() interfaces.ReleaserBaseConfig {
+	return interfaces.ReleaserBaseConfig{}
+}
+
+func (r *releaser) Setup(ctx context.Context, primarySubsetFilter, candidateSubsetFilter string) error {
+	return nil
+}
+
+func (r *releaser) Scale(ctx context.Context, value int) error {
+	r.recorder.scaled(value)
+	return nil
+}
+
+func (r *releaser) Route(ctx context.Context, matches []interfaces.RouteMatch) error {
+	return nil
+}
+
+func (r *releaser) Destroy(ctx context.Context) error {
+	return nil
+}
+
+func (r *releaser) WaitUntilServiceHealthy(ctx context.Context, filter string) error {
+	return nil
+}
+
+// runtime simulates a runtime where the primary already exists so that every deployment
+// executes the strategy
+type runtime struct {
+	config interfaces.RuntimeBaseConfig
+}
+
+func (r *runtime) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return json.Unmarshal(data, &r.config)
+}
+
+func (r *runtime) BaseConfig() interfaces.RuntimeBaseConfig {
+	return r.config
+}
+
+func (r *runtime) BaseState() interfaces.RuntimeBaseState {
+	return interfaces.RuntimeBaseState{CandidateName: "candidate", PrimaryName: "primary"}
+}
+
+func (r *runtime) InitPrimary(ctx context.Context, releaseName string) (interfaces.RuntimeDeploymentStatus, error) {
+	return interfaces.RuntimeDeploymentNoAction, nil
+}
+
+func (r *runtime) PromoteCandidate(ctx context.Context) (interfaces.RuntimeDeploymentStatus, error) {
+	return interfaces.RuntimeDeploymentUpdate, nil
+}
+
+func (r *runtime) RemoveCandidate(ctx context.Context) error {
+	return nil
+}
+
+func (r *runtime) RestoreOriginal(ctx context.Context) error {
+	return nil
+}
+
+func (r *runtime) RemovePrimary(ctx context.Context) error {
+	return nil
+}
+
+func (r *runtime) CandidateSubsetFilter() string {
+	return ""
+}
+
+func (r *runtime) PrimarySubsetFilter() string {
+	return ""
+}
+
+// monitor returns the scripted result for each check
+type monitor struct {
+	recorder *recorder
+	script   *Script
+}
+
+func (m *monitor) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return nil
+}
+
+func (m *monitor) Check(ctx context.Context, candidateName string, interval time.Duration) (interfaces.CheckResult, error) {
+	check, traffic := m.recorder.check()
+
+	name := m.script.result(check, traffic)
+	m.recorder.checked(name)
+
+	result := checkResults[name]
+	if result != interfaces.CheckSuccess {
+		return result, fmt.Errorf("scripted check result: %s", name)
+	}
+
+	return result, nil
+}
+
+// webhook discards all messages
+type webhook struct{}
+
+func (w *webhook) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return nil
+}
+
+func (w *webhook) Send(ctx context.Context, message interfaces.WebhookMessage) error {
+	return nil
+}
+
+// postDeploymentTest always passes, the scripted monitor results are used to simulate
+// the health of the candidate
+type postDeploymentTest struct{}
+
+func (t *postDeploymentTest) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return nil
+}
+
+func (t *postDeploymentTest) Execute(ctx context.Context, candidateName string) error {
+	return nil
+}
+
+// gate approves all releases, the decisions of external approval services are not simulated
+type gate struct{}
+
+func (g *gate) Configure(data json.RawMessage, log hclog.Logger, store interfaces.PluginStateStore) error {
+	return nil
+}
+
+func (g *gate) Check(ctx context.Context, request interfaces.GateRequest) (interfaces.GateResponse, error) {
+	return interfaces.GateResponse{Result: interfaces.GateResultApproved}, nil
+}
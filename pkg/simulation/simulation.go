@@ -0,0 +1,231 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/clock"
+	"github.com/nicholasjackson/consul-release-controller/pkg/events"
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/memory"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/statemachine"
+)
+
+const (
+	// CheckResultSuccess scripts a successful monitor check
+	CheckResultSuccess = "success"
+	// CheckResultFailed scripts a monitor check where the candidate did not meet the configured criteria
+	CheckResultFailed = "failed"
+	// CheckResultNoMetrics scripts a monitor check that did not return any metrics
+	CheckResultNoMetrics = "no_metrics"
+	// CheckResultError scripts a monitor check that could not be executed
+	CheckResultError = "error"
+)
+
+var checkResults = map[string]interfaces.CheckResult{
+	CheckResultSuccess:   interfaces.CheckSuccess,
+	CheckResultFailed:    interfaces.CheckFailed,
+	CheckResultNoMetrics: interfaces.CheckNoMetrics,
+	CheckResultError:     interfaces.CheckError,
+}
+
+// Timeout is the maximum real time that a simulation can run before it is stopped,
+// the simulated release uses a virtual clock so a simulation normally completes in
+// a fraction of a second
+var Timeout = 30 * time.Second
+
+// Script defines the monitor check results that are returned during a simulation
+type Script struct {
+	// Checks are evaluated in order for every monitor check, the result of the first
+	// matching check is returned. When no checks match the monitor check succeeds.
+	Checks []ScriptedCheck `json:"checks"`
+}
+
+// ScriptedCheck returns Result for monitor checks that match all of the set criteria
+type ScriptedCheck struct {
+	// MinTraffic matches checks where the candidate receives at least this percentage of traffic
+	MinTraffic int `json:"min_traffic,omitempty"`
+
+	// After matches checks after this number of checks have been executed
+	After int `json:"after,omitempty"`
+
+	// Count limits the number of checks that return Result, once the limit is reached
+	// this check no longer matches, 0 matches all checks
+	Count int `json:"count,omitempty"`
+
+	// Result of the check, one of success, failed, no_metrics, or error
+	Result string `json:"result"`
+
+	matched int
+}
+
+// Validate returns an error when the script contains an unknown result
+func (s *Script) Validate() error {
+	for i, c := range s.Checks {
+		if _, ok := checkResults[c.Result]; !ok {
+			return fmt.Errorf("check %d has an invalid result %q, valid results are success, failed, no_metrics, error", i, c.Result)
+		}
+	}
+
+	return nil
+}
+
+// result returns the scripted result for the given check number and candidate traffic
+func (s *Script) result(check, traffic int) string {
+	for i := range s.Checks {
+		c := &s.Checks[i]
+
+		if traffic < c.MinTraffic || check <= c.After {
+			continue
+		}
+
+		if c.Count > 0 && c.matched >= c.Count {
+			continue
+		}
+
+		c.matched++
+		return c.Result
+	}
+
+	return CheckResultSuccess
+}
+
+// Result is the outcome of a simulated release
+type Result struct {
+	// Timeline of states, traffic, and checks for the simulated release
+	Timeline Timeline `json:"timeline"`
+
+	// FinalState is the state of the release when the simulation finished
+	FinalState string `json:"final_state"`
+
+	// Deployment is the record of the simulated deployment
+	Deployment *models.Deployment `json:"deployment"`
+
+	// Duration is the simulated time that the release took to complete
+	Duration time.Duration `json:"duration"`
+}
+
+// Run simulates a new deployment for the given release, the monitor plugin for the release
+// is replaced with one that returns the results defined in the script, all other plugins,
+// with the exception of the strategy, are replaced with simulated plugins that always
+// succeed. The release is not modified.
+//
+// The simulation runs until the release is promoted, rolled back, fails, awaits manual
+// promotion, or is paused.
+func Run(r *models.Release, s *Script, log hclog.Logger) (*Result, error) {
+	err := s.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	// copy the release so that state changes do not modify the original
+	d, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy release: %s", err)
+	}
+
+	rel := &models.Release{}
+	err = json.Unmarshal(d, rel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy release: %s", err)
+	}
+
+	// simulated releases always start from an idle release and do not wait for the
+	// releases they depend on
+	rel.Statehistory = nil
+	rel.Deployment = nil
+	rel.DependsOn = nil
+
+	if rel.Strategy == nil {
+		return nil, fmt.Errorf("release does not define a strategy")
+	}
+
+	// the scripted checks are the combined result of the monitors, a single simulated
+	// monitor is used
+	rel.Monitors = nil
+
+	// the simulated plugins ignore their config, only the plugin names are required
+	for _, pc := range []**models.PluginConfig{&rel.Releaser, &rel.Runtime, &rel.Monitor} {
+		if *pc == nil {
+			*pc = &models.PluginConfig{}
+		}
+	}
+
+	c := clock.NewVirtual(time.Now())
+	rec := newRecorder(c)
+	store := memory.NewStore()
+
+	// copy the script as the number of times each check matched is recorded
+	script := &Script{Checks: append([]ScriptedCheck{}, s.Checks...)}
+
+	p := &provider{log: log, clock: c, store: store, events: events.NewBroker(), recorder: rec, script: script}
+
+	// the statemachine does not return strategy configuration errors, validate the
+	// strategy config before starting the simulation
+	strat, err := p.CreateStrategy(rel.Strategy.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = strat.Configure(rel.Strategy.Config, log, store.CreatePluginStateStore(rel, "validate"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid strategy config: %s", err)
+	}
+
+	sm, err := statemachine.New(rel, p)
+	if err != nil {
+		return nil, err
+	}
+
+	defer sm.Stop()
+
+	sm.SetState(interfaces.StateIdle)
+
+	err = sm.Deploy()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.After(Timeout)
+
+	state := ""
+	for !finished(state) {
+		select {
+		case state = <-rec.states:
+		case <-timeout:
+			return nil, fmt.Errorf("simulation did not complete within %s, last state: %s", Timeout, state)
+		}
+	}
+
+	res := &Result{
+		Timeline:   rec.timeline(),
+		FinalState: state,
+		Duration:   c.Now().Sub(rec.start),
+	}
+
+	deps, err := store.ListDeployments(rel.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Deployment = rel.Deployment
+	if len(deps) > 0 {
+		res.Deployment = deps[len(deps)-1]
+	}
+
+	return res, nil
+}
+
+// finished returns true when a release in the given state will not progress without
+// intervention
+func finished(state string) bool {
+	switch state {
+	case interfaces.StateIdle, interfaces.StateFail, interfaces.StateAwaitPromotion, interfaces.StatePaused:
+		return true
+	}
+
+	return false
+}
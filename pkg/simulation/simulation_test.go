@@ -0,0 +1,99 @@
+package simulation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/nicholasjackson/consul-release-controller/pkg/models"
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRelease(t *testing.T, strategy string) *models.Release {
+	return &models.Release{
+		Name:     "api",
+		Releaser: &models.PluginConfig{Name: "consul"},
+		Runtime:  &models.PluginConfig{Name: "kubernetes"},
+		Monitor:  &models.PluginConfig{Name: "prometheus"},
+		Strategy: &models.PluginConfig{Name: "canary", Config: json.RawMessage(strategy)},
+	}
+}
+
+func trafficSteps(d *models.Deployment) []int {
+	steps := []int{}
+	for _, s := range d.TrafficSteps {
+		steps = append(steps, s.Traffic)
+	}
+
+	return steps
+}
+
+func TestRunWithInvalidScriptReturnsError(t *testing.T) {
+	r := setupRelease(t, canaryStrategy)
+
+	_, err := Run(r, &Script{Checks: []ScriptedCheck{{Result: "boom"}}}, hclog.NewNullLogger())
+	require.Error(t, err)
+}
+
+func TestRunWithInvalidStrategyConfigReturnsError(t *testing.T) {
+	r := setupRelease(t, `{"interval": "30s", "initial_traffic": 200}`)
+
+	_, err := Run(r, &Script{}, hclog.NewNullLogger())
+	require.Error(t, err)
+}
+
+func TestRunWithSuccessfulChecksPromotesCandidate(t *testing.T) {
+	r := setupRelease(t, canaryStrategy)
+
+	res, err := Run(r, &Script{}, hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StateIdle, res.FinalState)
+	require.Equal(t, models.DeploymentOutcomePromoted, res.Deployment.Outcome)
+	require.Equal(t, []int{10, 30, 50, 70, 100}, trafficSteps(res.Deployment))
+
+	// the initial delay and each interval are simulated
+	require.GreaterOrEqual(t, res.Duration, 5*time.Minute)
+	require.Contains(t, res.Timeline.String(), "candidate traffic 50%")
+
+	// the original release is not modified
+	require.Empty(t, r.CurrentState())
+}
+
+func TestRunWithFailedChecksAtTrafficRollsBackCandidate(t *testing.T) {
+	r := setupRelease(t, canaryStrategy)
+
+	s := &Script{Checks: []ScriptedCheck{{MinTraffic: 25, Result: CheckResultFailed}}}
+
+	res, err := Run(r, s, hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	require.Equal(t, interfaces.StateIdle, res.FinalState)
+	require.Equal(t, models.DeploymentOutcomeRolledBack, res.Deployment.Outcome)
+	require.Equal(t, []int{10, 30}, trafficSteps(res.Deployment))
+	require.Contains(t, res.Timeline.String(), "check 2 failed")
+}
+
+func TestRunWithLimitedFailedChecksPromotesCandidate(t *testing.T) {
+	r := setupRelease(t, canaryStrategy)
+
+	s := &Script{Checks: []ScriptedCheck{{MinTraffic: 25, Count: 2, Result: CheckResultFailed}}}
+
+	res, err := Run(r, s, hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	require.Equal(t, models.DeploymentOutcomePromoted, res.Deployment.Outcome)
+}
+
+const canaryStrategy = `
+{
+  "interval": "1m",
+  "initial_traffic": 10,
+  "initial_delay": "1m",
+  "traffic_step": 20,
+  "max_traffic": 90,
+  "error_threshold": 3
+}
+`
@@ -0,0 +1,133 @@
+package simulation
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nicholasjackson/consul-release-controller/pkg/plugins/interfaces"
+)
+
+// Entry is a single event in the timeline of a simulated release
+type Entry struct {
+	// Elapsed is the simulated time since the start of the release
+	Elapsed time.Duration `json:"elapsed"`
+
+	// State of the release when the event occurred
+	State string `json:"state"`
+
+	// Traffic is the percentage of traffic sent to the candidate when the event occurred
+	Traffic int `json:"traffic"`
+
+	Message string `json:"message"`
+}
+
+// Timeline is the ordered list of events for a simulated release
+type Timeline []Entry
+
+// String returns the timeline formatted as a table
+func (t Timeline) String() string {
+	b := &bytes.Buffer{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "ELAPSED\tSTATE\tTRAFFIC\tEVENT")
+	for _, e := range t {
+		fmt.Fprintf(w, "%s\t%s\t%d%%\t%s\n", e.Elapsed, e.State, e.Traffic, e.Message)
+	}
+
+	w.Flush()
+
+	return b.String()
+}
+
+// recorder builds the timeline for a simulation, it implements the Metrics interface so
+// that it is notified of every state change
+type recorder struct {
+	m       sync.Mutex
+	clock   interfaces.Clock
+	start   time.Time
+	state   string
+	traffic int
+	checks  int
+	entries Timeline
+	states  chan string
+}
+
+func newRecorder(c interfaces.Clock) *recorder {
+	return &recorder{
+		clock:   c,
+		start:   c.Now(),
+		entries: Timeline{},
+		states:  make(chan string, 100),
+	}
+}
+
+func (r *recorder) ServiceStarting() {}
+
+func (r *recorder) HandleRequest(handler string, args map[string]string) func(status int) {
+	return func(status int) {}
+}
+
+func (r *recorder) StateChanged(release, state string, args map[string]string) func(status int) {
+	r.m.Lock()
+	r.state = state
+	r.add(fmt.Sprintf("entered %s", state))
+	r.m.Unlock()
+
+	r.states <- state
+
+	return func(status int) {}
+}
+
+// scaled records that the traffic sent to the candidate has changed
+func (r *recorder) scaled(traffic int) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if traffic == r.traffic {
+		return
+	}
+
+	r.traffic = traffic
+	r.add(fmt.Sprintf("candidate traffic %d%%", traffic))
+}
+
+// check returns the number of the next monitor check and the current candidate traffic
+func (r *recorder) check() (int, int) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.checks++
+
+	return r.checks, r.traffic
+}
+
+// checked records the result of a monitor check
+func (r *recorder) checked(result string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.add(fmt.Sprintf("check %d %s", r.checks, result))
+}
+
+func (r *recorder) timeline() Timeline {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	t := make(Timeline, len(r.entries))
+	copy(t, r.entries)
+
+	return t
+}
+
+// add appends an entry to the timeline, the caller must hold the lock
+func (r *recorder) add(message string) {
+	r.entries = append(r.entries, Entry{
+		Elapsed: r.clock.Now().Sub(r.start),
+		State:   r.state,
+		Traffic: r.traffic,
+		Message: message,
+	})
+}